@@ -0,0 +1,415 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+type createCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+type updateCommentRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+type commentResponse struct {
+	ID        int32         `json:"id"`
+	PostID    sql.NullInt32 `json:"post_id"`
+	UserID    sql.NullInt32 `json:"user_id"`
+	ParentID  sql.NullInt32 `json:"parent_id"`
+	Content   string        `json:"content"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	UpdatedAt sql.NullTime  `json:"updated_at"`
+}
+
+func newCommentResponse(comment db.Comment) commentResponse {
+	return commentResponse{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		UserID:    comment.UserID,
+		ParentID:  comment.ParentID,
+		Content:   comment.Content,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
+
+// commentNode is a comment together with the replies nested below it
+type commentNode struct {
+	ID         int32          `json:"id"`
+	PostID     int32          `json:"post_id"`
+	UserID     int32          `json:"user_id"`
+	ParentID   sql.NullInt32  `json:"parent_id"`
+	Content    string         `json:"content"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
+	UpdatedAt  sql.NullTime   `json:"updated_at"`
+	Username   string         `json:"username"`
+	FirstName  sql.NullString `json:"first_name"`
+	LastName   sql.NullString `json:"last_name"`
+	Depth      int32          `json:"depth"`
+	ReplyCount int64          `json:"reply_count"`
+	Replies    []*commentNode `json:"replies"`
+}
+
+// buildCommentTree nests a parent-ordered list of comments. Rows whose parent
+// is not part of the list become roots of the returned forest.
+func buildCommentTree(rows []db.CommentThreadRow) []*commentNode {
+	roots := []*commentNode{}
+	nodes := make(map[int32]*commentNode, len(rows))
+
+	for _, row := range rows {
+		node := &commentNode{
+			ID:         row.ID,
+			PostID:     row.PostID.Int32,
+			UserID:     row.UserID.Int32,
+			ParentID:   row.ParentID,
+			Content:    row.Content,
+			CreatedAt:  row.CreatedAt,
+			UpdatedAt:  row.UpdatedAt,
+			Username:   row.Username.String,
+			FirstName:  row.FirstName,
+			LastName:   row.LastName,
+			Depth:      row.Depth,
+			ReplyCount: row.ReplyCount,
+			Replies:    []*commentNode{},
+		}
+		nodes[row.ID] = node
+
+		parent, ok := nodes[row.ParentID.Int32]
+		if row.Depth > 0 && row.ParentID.Valid && ok {
+			parent.Replies = append(parent.Replies, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+// parseThreadQuery reads the limit, offset and max_depth query parameters.
+// max_depth can only lower the configured maximum reply depth.
+func (server *Server) parseThreadQuery(ctx *gin.Context) (limit, offset, maxDepth int32) {
+	limit = 10 // default limit
+	offset = 0 // default offset
+	maxDepth = server.config.CommentMaxDepth
+
+	// Parse limit from query parameter
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limitInt, err := strconv.ParseInt(limitStr, 10, 32)
+		if err == nil && limitInt > 0 {
+			limit = int32(limitInt)
+		}
+	}
+
+	// Parse offset from query parameter
+	if offsetStr := ctx.Query("offset"); offsetStr != "" {
+		offsetInt, err := strconv.ParseInt(offsetStr, 10, 32)
+		if err == nil && offsetInt >= 0 {
+			offset = int32(offsetInt)
+		}
+	}
+
+	// Parse max_depth from query parameter
+	if depthStr := ctx.Query("max_depth"); depthStr != "" {
+		depthInt, err := strconv.ParseInt(depthStr, 10, 32)
+		if err == nil && depthInt >= 0 && int32(depthInt) < maxDepth {
+			maxDepth = int32(depthInt)
+		}
+	}
+
+	return limit, offset, maxDepth
+}
+
+func (server *Server) createComment(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || postID <= 0 {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	var req createCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	// Get authenticated user
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	// Readers may only comment on posts they can see
+	if _, err := server.getReadablePost(ctx, int32(postID), user, true); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	comment, err := server.store.CreateComment(ctx, db.CreateCommentParams{
+		PostID: sql.NullInt32{
+			Int32: int32(postID),
+			Valid: true,
+		},
+		UserID: sql.NullInt32{
+			Int32: user.ID,
+			Valid: true,
+		},
+		Content: req.Content,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+// listPostComments handles retrieving the comment threads of a post, newest thread first
+func (server *Server) listPostComments(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || postID <= 0 {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	limit, offset, maxDepth := server.parseThreadQuery(ctx)
+
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if _, err := server.getReadablePost(ctx, int32(postID), user, authenticated); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	comments, err := server.store.ListCommentThreads(ctx, db.ListCommentThreadsParams{
+		PostID:   int32(postID),
+		MaxDepth: maxDepth,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, buildCommentTree(comments))
+}
+
+// listCommentReplies handles retrieving the reply threads below a comment, so
+// clients can expand threads cut off by the maximum depth
+func (server *Server) listCommentReplies(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || id <= 0 {
+		ctx.Error(invalidParamError("invalid comment id"))
+		return
+	}
+
+	limit, offset, maxDepth := server.parseThreadQuery(ctx)
+
+	parent, err := server.store.GetComment(ctx, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errCommentNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if _, err := server.getReadablePost(ctx, parent.PostID.Int32, user, authenticated); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	comments, err := server.store.ListCommentThreads(ctx, db.ListCommentThreadsParams{
+		PostID:   parent.PostID.Int32,
+		ParentID: &parent.ID,
+		MaxDepth: maxDepth,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, buildCommentTree(comments))
+}
+
+func (server *Server) createReply(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || id <= 0 {
+		ctx.Error(invalidParamError("invalid comment id"))
+		return
+	}
+
+	var req createCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	// Get authenticated user
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	parent, err := server.store.GetComment(ctx, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errCommentNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if _, err := server.getReadablePost(ctx, parent.PostID.Int32, user, true); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Replies always live on the same post as the comment they answer
+	comment, err := server.store.CreateComment(ctx, db.CreateCommentParams{
+		PostID: parent.PostID,
+		UserID: sql.NullInt32{
+			Int32: user.ID,
+			Valid: true,
+		},
+		Content: req.Content,
+		ParentID: sql.NullInt32{
+			Int32: parent.ID,
+			Valid: true,
+		},
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+func (server *Server) updateComment(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid comment id"))
+		return
+	}
+
+	var req updateCommentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	comment, ok := server.getOwnedComment(ctx, int32(id), authPayload.Username, "you can only update your own comments")
+	if !ok {
+		return
+	}
+
+	updated, err := server.store.UpdateComment(ctx, db.UpdateCommentParams{
+		ID:      comment.ID,
+		Content: req.Content,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newCommentResponse(updated))
+}
+
+func (server *Server) deleteComment(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid comment id"))
+		return
+	}
+
+	comment, ok := server.getOwnedComment(ctx, int32(id), authPayload.Username, "you can only delete your own comments")
+	if !ok {
+		return
+	}
+
+	err = server.store.DeleteComment(ctx, comment.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "comment deleted successfully"})
+}
+
+// getOwnedComment loads a comment and verifies it was written by the given user.
+// It writes the error response itself and reports whether the caller may continue.
+func (server *Server) getOwnedComment(ctx *gin.Context, id int32, username string, forbiddenMsg string) (db.Comment, bool) {
+	comment, err := server.store.GetComment(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errCommentNotFound)
+			return comment, false
+		}
+		ctx.Error(err)
+		return comment, false
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return comment, false
+		}
+		ctx.Error(err)
+		return comment, false
+	}
+
+	// Verify ownership
+	if !comment.UserID.Valid || comment.UserID.Int32 != user.ID {
+		ctx.Error(notOwnerError(forbiddenMsg))
+		return comment, false
+	}
+
+	return comment, true
+}
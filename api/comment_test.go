@@ -0,0 +1,508 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateComment(t *testing.T) {
+	user := db.User{ID: 1, Username: "testuser1"}
+	comment := db.Comment{
+		ID:      1,
+		PostID:  sql.NullInt32{Int32: 1, Valid: true},
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Content: "Nice write-up",
+	}
+
+	testCases := []struct {
+		name          string
+		postID        int32
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			postID: 1,
+			body:   gin.H{"content": comment.Content},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Eq(db.CreateCommentParams{
+						PostID:  comment.PostID,
+						UserID:  comment.UserID,
+						Content: comment.Content,
+					})).
+					Times(1).
+					Return(comment, nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got commentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, newCommentResponse(comment), got)
+			},
+		},
+		{
+			name:   "PostNotFound",
+			postID: 1,
+			body:   gin.H{"content": comment.Content},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "EmptyContent",
+			postID: 1,
+			body:   gin.H{"content": ""},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "NoAuthentication",
+			postID: 1,
+			body:   gin.H{"content": comment.Content},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/posts/%d/comments", tc.postID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListPostComments(t *testing.T) {
+	// One thread: a root comment, a reply to it and a reply to the reply
+	comments := []db.CommentThreadRow{
+		{ID: 1, PostID: sql.NullInt32{Int32: 1, Valid: true}, Content: "root", Depth: 0, ReplyCount: 1},
+		{ID: 2, PostID: sql.NullInt32{Int32: 1, Valid: true}, ParentID: sql.NullInt32{Int32: 1, Valid: true}, Content: "reply", Depth: 1, ReplyCount: 1},
+		{ID: 3, PostID: sql.NullInt32{Int32: 1, Valid: true}, ParentID: sql.NullInt32{Int32: 2, Valid: true}, Content: "nested reply", Depth: 2},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?limit=3&offset=6",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					ListCommentThreads(gomock.Any(), gomock.Eq(db.ListCommentThreadsParams{
+						PostID:   1,
+						MaxDepth: 5,
+						Limit:    3,
+						Offset:   6,
+					})).
+					Times(1).
+					Return(comments, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []commentNode
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, 1)
+				require.Equal(t, "root", got[0].Content)
+				require.Len(t, got[0].Replies, 1)
+				require.Equal(t, "reply", got[0].Replies[0].Content)
+				require.Len(t, got[0].Replies[0].Replies, 1)
+				require.Equal(t, "nested reply", got[0].Replies[0].Replies[0].Content)
+			},
+		},
+		{
+			name:  "MaxDepthCappedByConfig",
+			query: "?max_depth=50",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					ListCommentThreads(gomock.Any(), gomock.Eq(db.ListCommentThreadsParams{
+						PostID:   1,
+						MaxDepth: 5,
+						Limit:    10,
+						Offset:   0,
+					})).
+					Times(1).
+					Return([]db.CommentThreadRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "PostNotFound",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListCommentThreads(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					ListCommentThreads(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+				CommentMaxDepth:   5,
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			url := "/api/v1/posts/1/comments" + tc.query
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreateReply(t *testing.T) {
+	parent := db.Comment{
+		ID:      7,
+		PostID:  sql.NullInt32{Int32: 3, Valid: true},
+		UserID:  sql.NullInt32{Int32: 2, Valid: true},
+		Content: "Which board is that?",
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 1, Username: "testuser1"}, nil)
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(parent.ID)).
+					Times(1).
+					Return(parent, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(3))).
+					Times(1).
+					Return(db.GetPostRow{ID: 3, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Eq(db.CreateCommentParams{
+						PostID:   parent.PostID,
+						UserID:   sql.NullInt32{Int32: 1, Valid: true},
+						Content:  "An ESP32",
+						ParentID: sql.NullInt32{Int32: parent.ID, Valid: true},
+					})).
+					Times(1).
+					Return(db.Comment{ID: 8, PostID: parent.PostID, ParentID: sql.NullInt32{Int32: parent.ID, Valid: true}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got commentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, parent.ID, got.ParentID.Int32)
+			},
+		},
+		{
+			name: "ParentNotFound",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 1, Username: "testuser1"}, nil)
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(parent.ID)).
+					Times(1).
+					Return(db.Comment{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"content": "An ESP32"})
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/comments/%d/replies", parent.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, "testuser1"))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDeleteComment(t *testing.T) {
+	comment := db.Comment{
+		ID:      1,
+		PostID:  sql.NullInt32{Int32: 1, Valid: true},
+		UserID:  sql.NullInt32{Int32: 1, Valid: true},
+		Content: "Nice write-up",
+	}
+
+	testCases := []struct {
+		name          string
+		commentID     int32
+		buildStubs    func(store *mockdb.MockStore)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			commentID: comment.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 1, Username: "testuser1"}, nil)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, "testuser1")
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:      "NotAuthor",
+			commentID: comment.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(comment, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser2")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser2"}, nil)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, "testuser2")
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "CommentNotFound",
+			commentID: comment.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetComment(gomock.Any(), gomock.Eq(comment.ID)).
+					Times(1).
+					Return(db.Comment{}, sql.ErrNoRows)
+				store.EXPECT().
+					DeleteComment(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, "testuser1")
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/v1/comments/%d", tc.commentID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+type verifyEmailRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// sendVerificationEmail creates a verification token for the user and emails
+// them the link to confirm their address.
+func (server *Server) sendVerificationEmail(ctx *gin.Context, user db.User) error {
+	token, tokenHash, err := util.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreateEmailVerificationToken(ctx, db.CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(server.config.EmailVerificationTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	verifyURL := fmt.Sprintf("%s/verify-email?token=%s",
+		strings.TrimRight(server.config.AppBaseURL, "/"), url.QueryEscape(token))
+
+	return server.mailer.SendEmail(util.Email{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. "+
+			"It expires in %s.\n\n%s\n\n"+
+			"If you did not create an account, you can ignore this email.\n",
+			user.Username, server.config.EmailVerificationTokenDuration, verifyURL),
+	})
+}
+
+// verifyEmail handles confirming an email address with a verification token
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	verificationToken, err := server.store.GetEmailVerificationTokenByHash(ctx, util.HashOpaqueToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(newAPIError(http.StatusBadRequest, "auth.invalid_verification_token", "invalid or expired verification token"))
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if verificationToken.UsedAt.Valid || time.Now().After(verificationToken.ExpiresAt) {
+		ctx.Error(newAPIError(http.StatusBadRequest, "auth.invalid_verification_token", "invalid or expired verification token"))
+		return
+	}
+
+	err = server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		TokenID: verificationToken.ID,
+		UserID:  verificationToken.UserID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(newAPIError(http.StatusBadRequest, "auth.invalid_verification_token", "invalid or expired verification token"))
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "email verified successfully"})
+}
+
+// resendVerificationEmail handles sending a new verification link to the
+// authenticated user, for when the first one expired or got lost.
+func (server *Server) resendVerificationEmail(ctx *gin.Context) {
+	authPayload, err := server.getAuthPayload(ctx)
+	if err != nil {
+		ctx.Error(newAPIError(http.StatusUnauthorized, "auth.required", err.Error()))
+		return
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		ctx.Error(newAPIError(http.StatusConflict, "user.email_already_verified", "email is already verified"))
+		return
+	}
+
+	if err := server.sendVerificationEmail(ctx, user); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "verification email sent successfully"})
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateUserSendsVerificationEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user := db.User{ID: 1, Username: "testuser1", Email: "testuser1@example.com", Role: util.RoleAuthor}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		CreateUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(user, nil)
+
+	var tokenHash string
+	store.EXPECT().
+		CreateEmailVerificationToken(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateEmailVerificationTokenParams) (db.EmailVerificationToken, error) {
+			require.Equal(t, user.ID, arg.UserID)
+			require.WithinDuration(t, time.Now().Add(24*time.Hour), arg.ExpiresAt, time.Second)
+			require.Equal(t, time.UTC, arg.ExpiresAt.Location())
+			tokenHash = arg.TokenHash
+			return db.EmailVerificationToken{ID: 1, UserID: arg.UserID, TokenHash: arg.TokenHash}, nil
+		})
+
+	config := util.Config{
+		TokenSymmetricKey:              "12345678901234567890123456789012",
+		EmailVerificationTokenDuration: 24 * time.Hour,
+		AppBaseURL:                     "https://example.com",
+	}
+
+	server, err := NewServer(store, config)
+	require.NoError(t, err)
+	mailer := &recordingMailer{}
+	server.mailer = mailer
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"username": user.Username,
+		"email":    user.Email,
+		"password": "secret",
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got userResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.False(t, got.EmailVerifiedAt.Valid)
+
+	// The emailed token is the one whose hash was stored
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, user.Email, mailer.sent[0].To)
+	link := regexp.MustCompile(`https://example.com/verify-email\?token=\S+`).FindString(mailer.sent[0].Body)
+	require.NotEmpty(t, link)
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, tokenHash, util.HashOpaqueToken(parsed.Query().Get("token")))
+}
+
+func TestVerifyEmail(t *testing.T) {
+	token, tokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+
+	verificationToken := db.EmailVerificationToken{
+		ID:        1,
+		UserID:    1,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?token=" + url.QueryEscape(token),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetEmailVerificationTokenByHash(gomock.Any(), gomock.Eq(tokenHash)).
+					Times(1).
+					Return(verificationToken, nil)
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Eq(db.VerifyEmailTxParams{
+						TokenID: verificationToken.ID,
+						UserID:  verificationToken.UserID,
+					})).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingToken",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetEmailVerificationTokenByHash(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "UnknownToken",
+			query: "?token=unknown",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetEmailVerificationTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EmailVerificationToken{}, sql.ErrNoRows)
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "ExpiredToken",
+			query: "?token=" + url.QueryEscape(token),
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := verificationToken
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+				store.EXPECT().
+					GetEmailVerificationTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(expired, nil)
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "AlreadyUsed",
+			query: "?token=" + url.QueryEscape(token),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetEmailVerificationTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(verificationToken, nil)
+				store.EXPECT().
+					VerifyEmailTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/v1/verify-email"+tc.query, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCreatePostRequiresVerifiedEmail(t *testing.T) {
+	body := gin.H{
+		"title":   "Test Post",
+		"content": "Test Content",
+		"user_id": 1,
+		"type":    "blog",
+		"status":  "draft",
+	}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Verified",
+			user: db.User{ID: 1, EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Post{ID: 1}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "Unverified",
+			user: db.User{ID: 1},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+				Times(1).
+				Return(tc.user, nil)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:        "12345678901234567890123456789012",
+				RequireEmailVerification: true,
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/posts", bytes.NewReader(data))
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, "testuser1"))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
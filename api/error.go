@@ -0,0 +1,250 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+)
+
+// apiError is an error a handler reports to the client. Code is a stable,
+// machine-readable identifier clients can switch on, Message is for humans.
+type apiError struct {
+	Status  int          `json:"-"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Details []fieldError `json:"details,omitempty"`
+}
+
+// fieldError explains why one field of a request was rejected
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// errorEnvelope is the body of every error response
+type errorEnvelope struct {
+	Error *apiError `json:"error"`
+}
+
+func (err *apiError) Error() string {
+	return err.Message
+}
+
+func newAPIError(status int, code, message string) *apiError {
+	return &apiError{Status: status, Code: code, Message: message}
+}
+
+var (
+	errInternal = newAPIError(http.StatusInternalServerError, "internal", "internal server error")
+
+	errAuthRequired    = newAPIError(http.StatusUnauthorized, "auth.required", "authorization header is required")
+	errInvalidToken    = newAPIError(http.StatusUnauthorized, "auth.invalid_token", "invalid or expired token")
+	errTokenUserGone   = newAPIError(http.StatusUnauthorized, "auth.user_not_found", "user not found")
+	errRoleNotAllowed  = newAPIError(http.StatusForbidden, "auth.forbidden", "your role is not allowed to perform this action")
+	errEmailUnverified = newAPIError(http.StatusForbidden, "user.email_not_verified", "verify your email address before creating posts")
+
+	errUserNotFound     = newAPIError(http.StatusNotFound, "user.not_found", "user not found")
+	errPostNotFound     = newAPIError(http.StatusNotFound, "post.not_found", "post not found")
+	errCommentNotFound  = newAPIError(http.StatusNotFound, "comment.not_found", "comment not found")
+	errTagNotFound      = newAPIError(http.StatusNotFound, "tag.not_found", "tag not found")
+	errImageNotFound    = newAPIError(http.StatusNotFound, "image.not_found", "image not found")
+	errRevisionNotFound = newAPIError(http.StatusNotFound, "revision.not_found", "revision not found")
+	errSitemapNotFound  = newAPIError(http.StatusNotFound, "sitemap.not_found", "sitemap not found")
+
+	errUsernameTaken = newAPIError(http.StatusConflict, "user.username_taken", "username already exists")
+	errEmailTaken    = newAPIError(http.StatusConflict, "user.email_taken", "email already exists")
+)
+
+// invalidParamError reports a malformed path or query parameter
+func invalidParamError(message string) *apiError {
+	return newAPIError(http.StatusBadRequest, "request.invalid_parameter", message)
+}
+
+// notOwnerError reports a change to something that belongs to another user
+func notOwnerError(message string) *apiError {
+	return newAPIError(http.StatusUnauthorized, "auth.not_owner", message)
+}
+
+// constraintErrors are the errors reported when a request breaks a named
+// database constraint
+var constraintErrors = map[string]*apiError{
+	"users_username_key": errUsernameTaken,
+	"users_email_key":    errEmailTaken,
+	"posts_slug_key":     newAPIError(http.StatusConflict, "post.slug_taken", "slug already exists"),
+	"tags_name_key":      newAPIError(http.StatusConflict, "tag.name_taken", "tag already exists"),
+}
+
+// errorMiddleware renders the last error a handler recorded with ctx.Error as
+// an error envelope. Errors the client cannot act on are only logged by gin,
+// so driver messages never reach the response.
+func errorMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if len(ctx.Errors) == 0 || ctx.Writer.Written() {
+			return
+		}
+
+		apiErr := toAPIError(ctx.Errors.Last())
+		ctx.JSON(apiErr.Status, errorEnvelope{Error: apiErr})
+	}
+}
+
+func toAPIError(err *gin.Error) *apiError {
+	var apiErr *apiError
+	if errors.As(err.Err, &apiErr) {
+		return apiErr
+	}
+
+	if err.IsType(gin.ErrorTypeBind) {
+		return bindingError(err.Err)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err.Err, &pqErr) {
+		if apiErr := databaseError(pqErr); apiErr != nil {
+			return apiErr
+		}
+	}
+
+	return errInternal
+}
+
+// databaseError maps constraint violations to the request that caused them,
+// or returns nil when the error is the server's fault
+func databaseError(err *pq.Error) *apiError {
+	if apiErr, ok := constraintErrors[err.Constraint]; ok {
+		return apiErr
+	}
+
+	switch err.Code.Name() {
+	case "unique_violation":
+		return newAPIError(http.StatusConflict, "resource.conflict", "resource already exists")
+	case "foreign_key_violation":
+		return newAPIError(http.StatusUnprocessableEntity, "resource.invalid_reference", "a referenced resource does not exist")
+	case "check_violation", "not_null_violation", "string_data_right_truncation":
+		apiErr := newAPIError(http.StatusUnprocessableEntity, "validation.failed", "request violates a data constraint")
+		if err.Column != "" {
+			apiErr.Details = []fieldError{{Field: err.Column, Rule: err.Code.Name(), Message: err.Message}}
+		}
+		return apiErr
+	}
+	return nil
+}
+
+// bindingError describes why gin could not bind a request
+func bindingError(err error) *apiError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		apiErr := newAPIError(http.StatusBadRequest, "validation.failed", "request validation failed")
+		for _, fe := range validationErrs {
+			apiErr.Details = append(apiErr.Details, fieldError{
+				Field:   fe.Field(),
+				Rule:    fe.Tag(),
+				Message: validationMessage(fe),
+			})
+		}
+		return apiErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		apiErr := newAPIError(http.StatusBadRequest, "validation.failed", "request validation failed")
+		apiErr.Details = []fieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be of type %s", jsonTypeName(typeErr.Type)),
+		}}
+		return apiErr
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return newAPIError(http.StatusBadRequest, "request.malformed", "request body is not valid JSON")
+	}
+	if errors.Is(err, io.EOF) {
+		return newAPIError(http.StatusBadRequest, "request.malformed", "request body is required")
+	}
+
+	return newAPIError(http.StatusBadRequest, "request.invalid", err.Error())
+}
+
+// validationMessage explains a failed binding rule in words
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min", "max", "gte", "lte":
+		bound := "at least"
+		if fe.Tag() == "max" || fe.Tag() == "lte" {
+			bound = "at most"
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return fmt.Sprintf("must be %s %s characters long", bound, fe.Param())
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return fmt.Sprintf("must contain %s %s items", bound, fe.Param())
+		default:
+			return fmt.Sprintf("must be %s %s", bound, fe.Param())
+		}
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+// jsonTypeName is the JSON name of the Go type a value was decoded into
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// useRequestFieldNames makes validation errors name fields the way clients
+// send them, by their json or form tag instead of the Go field name. The
+// validator is shared by every server, so it is only set up once.
+var useRequestFieldNames = sync.OnceFunc(func() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+})
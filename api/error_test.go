@@ -0,0 +1,279 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func requireErrorEnvelope(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) *apiError {
+	require.Equal(t, status, recorder.Code)
+
+	var got errorEnvelope
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.NotNil(t, got.Error)
+	require.Equal(t, code, got.Error.Code)
+	require.NotEmpty(t, got.Error.Message)
+	return got.Error
+}
+
+func TestCreateUserErrors(t *testing.T) {
+	validBody := gin.H{
+		"username": "testuser1",
+		"email":    "testuser1@example.com",
+		"password": "secret",
+	}
+
+	testCases := []struct {
+		name          string
+		body          interface{}
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ValidationFailed",
+			body: gin.H{
+				"username": "testuser1",
+				"email":    "not-an-email",
+				"password": "123",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				apiErr := requireErrorEnvelope(t, recorder, http.StatusBadRequest, "validation.failed")
+				require.ElementsMatch(t, []fieldError{
+					{Field: "email", Rule: "email", Message: "must be a valid email address"},
+					{Field: "password", Rule: "min", Message: "must be at least 6 characters long"},
+				}, apiErr.Details)
+			},
+		},
+		{
+			name: "WrongType",
+			body: gin.H{
+				"username": 12,
+				"email":    "testuser1@example.com",
+				"password": "secret",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				apiErr := requireErrorEnvelope(t, recorder, http.StatusBadRequest, "validation.failed")
+				require.Equal(t, []fieldError{{Field: "username", Rule: "type", Message: "must be of type string"}}, apiErr.Details)
+			},
+		},
+		{
+			name: "MalformedJSON",
+			body: "{",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusBadRequest, "request.malformed")
+			},
+		},
+		{
+			name: "UsernameTaken",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: "users_username_key", Message: `duplicate key value violates unique constraint "users_username_key"`})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				apiErr := requireErrorEnvelope(t, recorder, http.StatusConflict, "user.username_taken")
+				require.Equal(t, "username already exists", apiErr.Message)
+			},
+		},
+		{
+			name: "EmailTaken",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23505", Constraint: "users_email_key"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusConflict, "user.email_taken")
+			},
+		},
+		{
+			name: "CheckViolation",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, &pq.Error{Code: "23514", Constraint: "users_role_check"})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusUnprocessableEntity, "validation.failed")
+			},
+		},
+		{
+			name: "InternalError",
+			body: validBody,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateUser(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				apiErr := requireErrorEnvelope(t, recorder, http.StatusInternalServerError, "internal")
+				// The driver message stays in the logs
+				require.NotContains(t, recorder.Body.String(), sql.ErrConnDone.Error())
+				require.Equal(t, "internal server error", apiErr.Message)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			var data []byte
+			if raw, ok := tc.body.(string); ok {
+				data = []byte(raw)
+			} else {
+				data, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestErrorEnvelopeFromMiddleware(t *testing.T) {
+	testCases := []struct {
+		name    string
+		url     string
+		header  string
+		status  int
+		code    string
+		message string
+	}{
+		{
+			name:    "MissingAuthorization",
+			url:     "/api/v1/users/1/usage",
+			status:  http.StatusUnauthorized,
+			code:    "auth.required",
+			message: "authorization header is required",
+		},
+		{
+			name:    "InvalidToken",
+			url:     "/api/v1/users/1/usage",
+			header:  "Bearer invalid",
+			status:  http.StatusUnauthorized,
+			code:    "auth.invalid_token",
+			message: "invalid or expired token",
+		},
+		{
+			name:    "InvalidID",
+			url:     "/api/v1/users/abc",
+			status:  http.StatusBadRequest,
+			code:    "request.invalid_parameter",
+			message: "invalid id",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server, err := NewServer(mockdb.NewMockStore(ctrl), util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			if tc.header != "" {
+				request.Header.Set("Authorization", tc.header)
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			apiErr := requireErrorEnvelope(t, recorder, tc.status, tc.code)
+			require.Equal(t, tc.message, apiErr.Message)
+		})
+	}
+}
+
+func TestDatabaseError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    *pq.Error
+		status int
+		code   string
+	}{
+		{
+			name:   "SlugTaken",
+			err:    &pq.Error{Code: "23505", Constraint: "posts_slug_key"},
+			status: http.StatusConflict,
+			code:   "post.slug_taken",
+		},
+		{
+			name:   "OtherUniqueViolation",
+			err:    &pq.Error{Code: "23505", Constraint: "sessions_refresh_token_hash_key"},
+			status: http.StatusConflict,
+			code:   "resource.conflict",
+		},
+		{
+			name:   "ForeignKeyViolation",
+			err:    &pq.Error{Code: "23503", Constraint: "posts_user_id_fkey"},
+			status: http.StatusUnprocessableEntity,
+			code:   "resource.invalid_reference",
+		},
+		{
+			name:   "NotNullViolation",
+			err:    &pq.Error{Code: "23502", Column: "title"},
+			status: http.StatusUnprocessableEntity,
+			code:   "validation.failed",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			apiErr := databaseError(tc.err)
+			require.NotNil(t, apiErr)
+			require.Equal(t, tc.status, apiErr.Status)
+			require.Equal(t, tc.code, apiErr.Code)
+		})
+	}
+
+	// Errors that are not the client's fault are not mapped
+	require.Nil(t, databaseError(&pq.Error{Code: "57014"}))
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/feeds"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+// feedFormats are the extensions feeds are served under
+var feedFormats = []string{"rss", "atom", "json"}
+
+// feedContentTypes maps each feed format to the content type it is served with
+var feedContentTypes = map[string]string{
+	"rss":  "application/rss+xml; charset=utf-8",
+	"atom": "application/atom+xml; charset=utf-8",
+	"json": "application/feed+json; charset=utf-8",
+}
+
+// feedSize is how many of the latest posts a feed lists
+const feedSize = 20
+
+// postFeed describes a feed and which published posts it lists
+type postFeed struct {
+	title       string
+	description string
+	authorID    sql.NullInt32
+	tagID       sql.NullInt32
+}
+
+// siteFeed serves the latest published posts of the whole site
+func (server *Server) siteFeed(format string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		server.serveFeed(ctx, format, postFeed{
+			title:       server.config.SiteTitle,
+			description: fmt.Sprintf("Latest posts on %s", server.config.SiteTitle),
+		})
+	}
+}
+
+// userFeed serves the latest published posts of one author
+func (server *Server) userFeed(format string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+		if err != nil {
+			ctx.Error(invalidParamError("invalid id"))
+			return
+		}
+
+		user, err := server.store.GetUser(ctx, int32(id))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.Error(errUserNotFound)
+				return
+			}
+			ctx.Error(err)
+			return
+		}
+
+		server.serveFeed(ctx, format, postFeed{
+			title:       fmt.Sprintf("%s: %s", server.config.SiteTitle, user.Username),
+			description: fmt.Sprintf("Latest posts by %s", user.Username),
+			authorID:    sql.NullInt32{Int32: user.ID, Valid: true},
+		})
+	}
+}
+
+// tagFeed serves the latest published posts with one tag
+func (server *Server) tagFeed(format string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+		if err != nil {
+			ctx.Error(invalidParamError("invalid id"))
+			return
+		}
+
+		tag, err := server.store.GetTag(ctx, int32(id))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.Error(errTagNotFound)
+				return
+			}
+			ctx.Error(err)
+			return
+		}
+
+		server.serveFeed(ctx, format, postFeed{
+			title:       fmt.Sprintf("%s: %s", server.config.SiteTitle, tag.Name),
+			description: fmt.Sprintf("Latest posts tagged %s", tag.Name),
+			tagID:       sql.NullInt32{Int32: tag.ID, Valid: true},
+		})
+	}
+}
+
+// serveFeed writes feed in format. The ETag is a hash of the feed and
+// Last-Modified the time its newest post changed, so readers polling with
+// If-None-Match or If-Modified-Since get 304 Not Modified until it changes.
+func (server *Server) serveFeed(ctx *gin.Context, format string, feed postFeed) {
+	posts, err := server.store.ListPosts(ctx, db.ListPostsParams{
+		Status:   util.PostStatusPublished,
+		AuthorID: feed.authorID,
+		TagID:    feed.tagID,
+		Limit:    feedSize,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	siteURL := server.config.SiteURL
+	out := &feeds.Feed{
+		Title:       feed.title,
+		Link:        &feeds.Link{Href: siteURL + "/"},
+		Description: feed.description,
+	}
+	for _, post := range posts {
+		// Posts not read since their content changed have no rendering yet
+		contentHTML := post.ContentHtml
+		if !contentHTML.Valid {
+			contentHTML, _, err = renderPost(post.Format, post.Content)
+			if err != nil {
+				ctx.Error(err)
+				return
+			}
+		}
+
+		published := post.CreatedAt.Time
+		if post.PublishAt.Valid {
+			published = post.PublishAt.Time
+		}
+		item := &feeds.Item{
+			Id:          postTagURI(siteURL, post.ID, post.CreatedAt.Time),
+			IsPermaLink: "false",
+			Title:       post.Title,
+			Link:        &feeds.Link{Href: siteURL + "/posts/" + post.Slug},
+			Content:     contentHTML.String,
+			Created:     published,
+			Updated:     post.UpdatedAt.Time,
+		}
+		if post.Username.Valid {
+			item.Author = &feeds.Author{Name: post.Username.String}
+		}
+		out.Add(item)
+
+		for _, t := range []time.Time{item.Created, item.Updated} {
+			if t.After(out.Updated) {
+				out.Updated = t
+			}
+		}
+	}
+
+	// Feeds are told apart by their own URL, the site link is the same for all
+	feedURL := siteURL + ctx.Request.URL.Path
+	var body string
+	switch format {
+	case "rss":
+		body, err = out.ToRss()
+	case "atom":
+		atomFeed := (&feeds.Atom{Feed: out}).AtomFeed()
+		atomFeed.Id = feedURL
+		body, err = feeds.ToXML(atomFeed)
+	case "json":
+		jsonFeed := (&feeds.JSON{Feed: out}).JSONFeed()
+		jsonFeed.FeedUrl = feedURL
+		body, err = jsonFeed.ToJSON()
+	default:
+		err = fmt.Errorf("unknown feed format %s", format)
+	}
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	sum := sha256.Sum256([]byte(body))
+	ctx.Header("Content-Type", feedContentTypes[format])
+	ctx.Header("ETag", fmt.Sprintf(`"%x"`, sum[:16]))
+	http.ServeContent(ctx.Writer, ctx.Request, "", out.Updated, strings.NewReader(body))
+}
+
+// postTagURI identifies a post in feeds with a tag URI (RFC 4151), which
+// stays the same when the post is renamed and its link changes
+func postTagURI(siteURL string, id int32, created time.Time) string {
+	host := "localhost"
+	if u, err := url.Parse(siteURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("tag:%s,%s:posts/%d", host, created.UTC().Format("2006-01-02"), id)
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeeds(t *testing.T) {
+	updatedAt := time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC)
+	posts := []db.ListPostsRow{
+		{
+			ID:          2,
+			Title:       "Second post",
+			Slug:        "second-post",
+			Content:     "# Hello",
+			Format:      "markdown",
+			ContentHtml: sql.NullString{String: `<h1 id="hello">Hello</h1>`, Valid: true},
+			Status:      sql.NullString{String: util.PostStatusPublished, Valid: true},
+			CreatedAt:   sql.NullTime{Time: time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), Valid: true},
+			UpdatedAt:   sql.NullTime{Time: updatedAt, Valid: true},
+			Username:    sql.NullString{String: "testuser1", Valid: true},
+		},
+		{
+			ID:        1,
+			Title:     "First post",
+			Slug:      "first-post",
+			Content:   "*not rendered yet*",
+			Format:    "markdown",
+			Status:    sql.NullString{String: util.PostStatusPublished, Valid: true},
+			CreatedAt: sql.NullTime{Time: time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC), Valid: true},
+			UpdatedAt: sql.NullTime{Time: time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC), Valid: true},
+			Username:  sql.NullString{String: "testuser1", Valid: true},
+		},
+	}
+	siteArg := db.ListPostsParams{Status: util.PostStatusPublished, Limit: feedSize}
+
+	testCases := []struct {
+		name          string
+		url           string
+		header        http.Header
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "RSS",
+			url:  "/api/v1/feed.rss",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/rss+xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+				require.NotEmpty(t, recorder.Header().Get("ETag"))
+				require.Equal(t, updatedAt.Format(http.TimeFormat), recorder.Header().Get("Last-Modified"))
+
+				var got struct {
+					Channel struct {
+						Title string `xml:"title"`
+						Items []struct {
+							Title   string `xml:"title"`
+							Link    string `xml:"link"`
+							GUID    string `xml:"guid"`
+							Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+						} `xml:"item"`
+					} `xml:"channel"`
+				}
+				require.NoError(t, xml.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "My Site", got.Channel.Title)
+				require.Len(t, got.Channel.Items, 2)
+				require.Equal(t, "Second post", got.Channel.Items[0].Title)
+				require.Equal(t, "https://example.com/posts/second-post", got.Channel.Items[0].Link)
+				require.Equal(t, "tag:example.com,2024-03-01:posts/2", got.Channel.Items[0].GUID)
+				require.Equal(t, `<h1 id="hello">Hello</h1>`, got.Channel.Items[0].Content)
+				require.Equal(t, "<p><em>not rendered yet</em></p>\n", got.Channel.Items[1].Content)
+			},
+		},
+		{
+			name: "Atom",
+			url:  "/api/v1/feed.atom",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/atom+xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+				var got struct {
+					ID      string `xml:"id"`
+					Entries []struct {
+						ID string `xml:"id"`
+					} `xml:"entry"`
+				}
+				require.NoError(t, xml.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "https://example.com/api/v1/feed.atom", got.ID)
+				require.Len(t, got.Entries, 2)
+				require.Equal(t, "tag:example.com,2024-02-01:posts/1", got.Entries[1].ID)
+			},
+		},
+		{
+			name: "JSON",
+			url:  "/api/v1/feed.json",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/feed+json; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+				var got struct {
+					Title   string `json:"title"`
+					FeedURL string `json:"feed_url"`
+					Items   []struct {
+						URL         string `json:"url"`
+						ContentHTML string `json:"content_html"`
+					} `json:"items"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "My Site", got.Title)
+				require.Equal(t, "https://example.com/api/v1/feed.json", got.FeedURL)
+				require.Len(t, got.Items, 2)
+				require.Equal(t, "https://example.com/posts/first-post", got.Items[1].URL)
+			},
+		},
+		{
+			name:   "NotModifiedSince",
+			url:    "/api/v1/feed.rss",
+			header: http.Header{"If-Modified-Since": {updatedAt.Format(http.TimeFormat)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotModified, recorder.Code)
+				require.Empty(t, recorder.Body.String())
+			},
+		},
+		{
+			name:   "ModifiedSince",
+			url:    "/api/v1/feed.rss",
+			header: http.Header{"If-Modified-Since": {updatedAt.Add(-time.Hour).Format(http.TimeFormat)}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ETagChanged",
+			url:    "/api/v1/feed.rss",
+			header: http.Header{"If-None-Match": {`"stale"`}},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "EmptyFeed",
+			url:  "/api/v1/feed.json",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Eq(siteArg)).Times(1).Return([]db.ListPostsRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Last-Modified"))
+				require.NotEmpty(t, recorder.Header().Get("ETag"))
+			},
+		},
+		{
+			name: "UserFeed",
+			url:  "/api/v1/users/7/feed.rss",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(int32(7))).Times(1).Return(db.User{ID: 7, Username: "testuser1"}, nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(db.ListPostsParams{
+						Status:   util.PostStatusPublished,
+						AuthorID: sql.NullInt32{Int32: 7, Valid: true},
+						Limit:    feedSize,
+					})).
+					Times(1).
+					Return(posts, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "<title>My Site: testuser1</title>")
+			},
+		},
+		{
+			name: "UserNotFound",
+			url:  "/api/v1/users/7/feed.atom",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "TagFeed",
+			url:  "/api/v1/tags/3/feed.json",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTag(gomock.Any(), gomock.Eq(int32(3))).Times(1).Return(db.Tag{ID: 3, Name: "golang"}, nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(db.ListPostsParams{
+						Status: util.PostStatusPublished,
+						TagID:  sql.NullInt32{Int32: 3, Valid: true},
+						Limit:  feedSize,
+					})).
+					Times(1).
+					Return(posts[:1], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"title": "My Site: golang"`)
+			},
+		},
+		{
+			name: "TagNotFound",
+			url:  "/api/v1/tags/3/feed.rss",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTag(gomock.Any(), gomock.Any()).Times(1).Return(db.Tag{}, sql.ErrNoRows)
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			url:  "/api/v1/tags/abc/feed.rss",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetTag(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			url:  "/api/v1/feed.rss",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPosts(gomock.Any(), gomock.Any()).Times(1).Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+				SiteTitle:         "My Site",
+				SiteURL:           "https://example.com",
+			})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+			for key, values := range tc.header {
+				request.Header[key] = values
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestFeedETag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	posts := []db.ListPostsRow{
+		{ID: 1, Title: "First post", Slug: "first-post", ContentHtml: sql.NullString{String: "<p>hi</p>", Valid: true}},
+	}
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().ListPosts(gomock.Any(), gomock.Any()).Times(2).Return(posts, nil)
+
+	server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/api/v1/feed.atom", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	etag := recorder.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// The same feed has the same tag, so polling with it is answered with 304
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/api/v1/feed.atom", nil)
+	require.NoError(t, err)
+	request.Header.Set("If-None-Match", etag)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+}
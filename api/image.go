@@ -0,0 +1,730 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/imaging"
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+// multipartOverhead is allowed on top of the file size for the form
+// boundaries and the other fields of an upload
+const multipartOverhead = 1 << 20
+
+// allowedImageTypes maps the sniffed content types we accept to the file
+// extension used for the stored blob
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type imageVariantResponse struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type"`
+	Width    int32  `json:"width"`
+	Height   int32  `json:"height"`
+}
+
+type imageResponse struct {
+	ID          int32                  `json:"id"`
+	UserID      sql.NullInt32          `json:"user_id"`
+	FilePath    string                 `json:"file_path"`
+	URL         string                 `json:"url"`
+	AltText     sql.NullString         `json:"alt_text"`
+	SizeBytes   int64                  `json:"size_bytes"`
+	MimeType    string                 `json:"mime_type"`
+	Checksum    string                 `json:"checksum"`
+	Width       int32                  `json:"width"`
+	Height      int32                  `json:"height"`
+	Srcset      string                 `json:"srcset"`
+	Variants    []imageVariantResponse `json:"variants"`
+	SanitizedAt sql.NullTime           `json:"sanitized_at"`
+	UploadedAt  sql.NullTime           `json:"uploaded_at"`
+}
+
+// imageURL returns the public URL of an image's blob. Images recorded before
+// uploads existed only have the path the client sent, which is used as is.
+func (server *Server) imageURL(image db.Image) string {
+	if !image.IsUploaded() {
+		return image.FilePath
+	}
+	return server.blobStore.URL(image.FilePath)
+}
+
+// newImageResponse builds the response of an image and its variants, which
+// must be sorted by width. The srcset lists the variants and the original so
+// browsers can pick the smallest file that fits.
+func (server *Server) newImageResponse(image db.Image, variants []db.ImageVariant) imageResponse {
+	response := imageResponse{
+		ID:          image.ID,
+		UserID:      image.UserID,
+		FilePath:    image.FilePath,
+		URL:         server.imageURL(image),
+		AltText:     image.AltText,
+		SizeBytes:   image.SizeBytes,
+		MimeType:    image.MimeType,
+		Checksum:    image.Checksum,
+		Width:       image.Width,
+		Height:      image.Height,
+		Variants:    make([]imageVariantResponse, len(variants)),
+		SanitizedAt: image.SanitizedAt,
+		UploadedAt:  image.UploadedAt,
+	}
+
+	candidates := make([]string, 0, len(variants)+1)
+	for i, variant := range variants {
+		url := server.blobStore.URL(variant.FilePath)
+		response.Variants[i] = imageVariantResponse{
+			Name:     variant.Name,
+			URL:      url,
+			MimeType: variant.MimeType,
+			Width:    variant.Width,
+			Height:   variant.Height,
+		}
+		candidates = append(candidates, fmt.Sprintf("%s %dw", url, variant.Width))
+	}
+	if image.Width > 0 {
+		candidates = append(candidates, fmt.Sprintf("%s %dw", response.URL, image.Width))
+	}
+	response.Srcset = strings.Join(candidates, ", ")
+
+	return response
+}
+
+// listPostImageResponses returns the images of a post in display order
+func (server *Server) listPostImageResponses(ctx *gin.Context, postID int32) ([]imageResponse, error) {
+	images, err := server.store.ListPostImages(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	return server.postImageResponses(ctx, postID, images)
+}
+
+// postImageResponses builds the responses of images of a post, keeping their
+// order and loading their variants
+func (server *Server) postImageResponses(ctx *gin.Context, postID int32, images []db.Image) ([]imageResponse, error) {
+	variants, err := server.store.ListImageVariantsByPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	variantsByImage := make(map[int32][]db.ImageVariant)
+	for _, variant := range variants {
+		variantsByImage[variant.ImageID] = append(variantsByImage[variant.ImageID], variant)
+	}
+
+	responses := make([]imageResponse, len(images))
+	for i, image := range images {
+		responses[i] = server.newImageResponse(image, variantsByImage[image.ID])
+	}
+	return responses, nil
+}
+
+// addImage handles a multipart upload of an image to a post. The file goes in
+// the "file" field and an optional description in "alt_text".
+func (server *Server) addImage(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	_, ok := server.getEditablePost(ctx, int32(postID), authPayload, "you can only add images to your own posts")
+	if !ok {
+		return
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	data, err := server.readImageUpload(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Trust the bytes, not the client's Content-Type or file name
+	mimeType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[mimeType]
+	if !ok {
+		ctx.Error(newAPIError(http.StatusUnsupportedMediaType, "image.unsupported_type", fmt.Sprintf("unsupported image type %s", mimeType)))
+		return
+	}
+
+	// Refuse images too large to decode from their header, before their
+	// pixels are allocated
+	if err := imaging.CheckDimensions(data, server.config.MaxImagePixels); err != nil {
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			ctx.Error(newAPIError(http.StatusUnprocessableEntity, "image.too_many_pixels",
+				fmt.Sprintf("image must not have more than %d pixels", server.config.MaxImagePixels)))
+			return
+		}
+		ctx.Error(newAPIError(http.StatusUnsupportedMediaType, "image.undecodable", "cannot decode image"))
+		return
+	}
+
+	// Drop EXIF, GPS and other metadata before anything is stored, keeping
+	// the orientation it described
+	data, err = imaging.Sanitize(data)
+	if err != nil {
+		ctx.Error(newAPIError(http.StatusUnsupportedMediaType, "image.undecodable", "cannot decode image"))
+		return
+	}
+	sanitizedAt := time.Now()
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	// Content the user uploaded before is linked again instead of stored twice
+	_, err = server.store.GetUserImageByChecksum(ctx, db.GetUserImageByChecksumParams{
+		UserID:   sql.NullInt32{Int32: user.ID, Valid: true},
+		Checksum: checksum,
+	})
+	if err == nil {
+		result, err := server.store.UploadPostImageTx(ctx, db.UploadPostImageTxParams{
+			PostID:   int32(postID),
+			UserID:   user.ID,
+			AltText:  ctx.PostForm("alt_text"),
+			Checksum: checksum,
+		})
+		if err == nil {
+			ctx.JSON(http.StatusOK, server.newImageResponse(result.Image, result.Variants))
+			return
+		}
+		// An orphaned image collected since the lookup is stored again
+		if err != sql.ErrNoRows {
+			ctx.Error(err)
+			return
+		}
+	} else if err != sql.ErrNoRows {
+		ctx.Error(err)
+		return
+	}
+
+	img, format, err := imaging.Decode(data, server.config.MaxImagePixels)
+	if err != nil {
+		ctx.Error(newAPIError(http.StatusUnsupportedMediaType, "image.undecodable", "cannot decode image"))
+		return
+	}
+
+	variants, err := imaging.GenerateVariants(img, format, imaging.DefaultVariants)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	size := int64(len(data))
+	for _, variant := range variants {
+		size += int64(len(variant.Data))
+	}
+	if !server.checkStorageQuota(ctx, user, size) {
+		return
+	}
+
+	baseKey := fmt.Sprintf("images/%d/%d-%s", user.ID, time.Now().UnixNano(), checksum[:16])
+
+	// Store the original and its variants, removing them all again if
+	// anything fails
+	var storedKeys []string
+	cleanup := func() {
+		for _, key := range storedKeys {
+			if err := server.blobStore.Delete(ctx, key); err != nil {
+				log.Printf("cannot delete blob %s of failed upload: %v", key, err)
+			}
+		}
+	}
+
+	key := baseKey + ext
+	err = server.blobStore.Put(ctx, key, bytes.NewReader(data), int64(len(data)), mimeType)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	storedKeys = append(storedKeys, key)
+
+	uploadVariants := make([]db.UploadImageVariant, len(variants))
+	for i, variant := range variants {
+		variantKey := fmt.Sprintf("%s-%s%s", baseKey, variant.Name, allowedImageTypes[variant.MimeType])
+		err = server.blobStore.Put(ctx, variantKey, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.MimeType)
+		if err != nil {
+			cleanup()
+			ctx.Error(err)
+			return
+		}
+		storedKeys = append(storedKeys, variantKey)
+
+		uploadVariants[i] = db.UploadImageVariant{
+			Name:      variant.Name,
+			FilePath:  variantKey,
+			MimeType:  variant.MimeType,
+			Width:     int32(variant.Width),
+			Height:    int32(variant.Height),
+			SizeBytes: int64(len(variant.Data)),
+		}
+	}
+
+	bounds := img.Bounds()
+	result, err := server.store.UploadPostImageTx(ctx, db.UploadPostImageTxParams{
+		PostID:      int32(postID),
+		UserID:      user.ID,
+		FilePath:    key,
+		AltText:     ctx.PostForm("alt_text"),
+		SizeBytes:   int64(len(data)),
+		MimeType:    mimeType,
+		Checksum:    checksum,
+		Width:       int32(bounds.Dx()),
+		Height:      int32(bounds.Dy()),
+		SanitizedAt: sanitizedAt,
+		Variants:    uploadVariants,
+		Quota:       server.config.StorageQuotaFor(user.Role),
+	})
+	if err != nil {
+		cleanup()
+
+		// A concurrent upload of the user used up the room checked above
+		var quotaErr *db.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			if apiErr := storageQuotaError(quotaErr.Usage, server.config.StorageQuotaFor(user.Role), quotaErr.Size); apiErr != nil {
+				ctx.Error(apiErr)
+				return
+			}
+		}
+		ctx.Error(err)
+		return
+	}
+
+	// An identical upload of the user committed first, its blobs are used
+	// instead
+	if result.Reused {
+		cleanup()
+	}
+
+	ctx.JSON(http.StatusOK, server.newImageResponse(result.Image, result.Variants))
+}
+
+// readImageUpload reads the uploaded file, enforcing the size limit
+func (server *Server) readImageUpload(ctx *gin.Context) ([]byte, error) {
+	maxSize := server.config.MaxUploadSize
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+multipartOverhead)
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, imageTooLargeError(maxSize)
+		}
+		return nil, newAPIError(http.StatusBadRequest, "image.missing", "an image file is required in the file field")
+	}
+
+	if fileHeader.Size > maxSize {
+		return nil, imageTooLargeError(maxSize)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, imageTooLargeError(maxSize)
+	}
+	if len(data) == 0 {
+		return nil, newAPIError(http.StatusBadRequest, "image.empty", "image file is empty")
+	}
+
+	return data, nil
+}
+
+func imageTooLargeError(maxSize int64) *apiError {
+	return newAPIError(http.StatusRequestEntityTooLarge, "image.too_large", fmt.Sprintf("image must not be larger than %d bytes", maxSize))
+}
+
+func (server *Server) deleteImage(ctx *gin.Context) {
+	// Get auth payload
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid image id"))
+		return
+	}
+
+	// Get the image to verify ownership
+	image, err := server.store.GetImage(ctx, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errImageNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	// Get user by username
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Verify ownership, admins can delete any image
+	if image.UserID.Int32 != user.ID && !util.CanDeleteAnyContent(authPayload.Role) {
+		ctx.Error(notOwnerError("you can only delete your own images"))
+		return
+	}
+
+	// The variant rows go with the image, so look up their blobs first
+	variants, err := server.store.ListImageVariants(ctx, image.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = server.store.DeleteImage(ctx, int32(id))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Only uploaded images have blobs, older rows recorded a caller-supplied path
+	keys := make([]string, 0, len(variants)+1)
+	if image.IsUploaded() {
+		keys = append(keys, image.FilePath)
+	}
+	for _, variant := range variants {
+		keys = append(keys, variant.FilePath)
+	}
+	for _, key := range keys {
+		if err := server.blobStore.Delete(ctx, key); err != nil {
+			log.Printf("cannot delete blob %s of image %d: %v", key, image.ID, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "image deleted successfully"})
+}
+
+type reorderPostImagesRequest struct {
+	ImageIDs []int32 `json:"image_ids" binding:"required,min=1"`
+}
+
+type updateImageRequest struct {
+	// A pointer so that an empty string can clear the alt text
+	AltText *string `json:"alt_text" binding:"required"`
+}
+
+// listPostImages returns the gallery of a post in display order
+func (server *Server) listPostImages(ctx *gin.Context) {
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || postID <= 0 {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if _, err := server.getReadablePost(ctx, int32(postID), user, authenticated); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	images, err := server.listPostImageResponses(ctx, int32(postID))
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, images)
+}
+
+// reorderPostImages sets the display order of a post's images. The request
+// must list every image of the post exactly once, first image first.
+func (server *Server) reorderPostImages(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	var req reorderPostImagesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	_, ok := server.getEditablePost(ctx, int32(postID), authPayload, "you can only reorder images of your own posts")
+	if !ok {
+		return
+	}
+
+	images, err := server.store.ReorderPostImagesTx(ctx, db.ReorderPostImagesTxParams{
+		PostID:   int32(postID),
+		ImageIDs: req.ImageIDs,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrImageOrderMismatch) {
+			ctx.Error(newAPIError(http.StatusBadRequest, "image.order_mismatch", err.Error()))
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	responses, err := server.postImageResponses(ctx, int32(postID), images)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, responses)
+}
+
+// updateImage edits the alt text of an image
+func (server *Server) updateImage(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid image id"))
+		return
+	}
+
+	var req updateImageRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	image, err := server.store.GetImage(ctx, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errImageNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Verify ownership, editors can edit any image
+	if image.UserID.Int32 != user.ID && !util.CanEditAnyPost(authPayload.Role) {
+		ctx.Error(notOwnerError("you can only edit your own images"))
+		return
+	}
+
+	updated, err := server.store.UpdateImageAltText(ctx, db.UpdateImageAltTextParams{
+		ID: image.ID,
+		AltText: sql.NullString{
+			String: *req.AltText,
+			Valid:  *req.AltText != "",
+		},
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	variants, err := server.store.ListImageVariants(ctx, updated.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, server.newImageResponse(updated, variants))
+}
+
+// detachImage removes an image from a post's gallery. The image itself and
+// its blobs are kept, use deleteImage to remove those.
+func (server *Server) detachImage(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	postID, err := strconv.ParseInt(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	imageID, err := strconv.ParseInt(ctx.Param("imageId"), 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid image id"))
+		return
+	}
+
+	_, ok := server.getEditablePost(ctx, int32(postID), authPayload, "you can only remove images from your own posts")
+	if !ok {
+		return
+	}
+
+	removed, err := server.store.RemovePostImage(ctx, db.RemovePostImageParams{
+		PostID:  int32(postID),
+		ImageID: int32(imageID),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if removed == 0 {
+		ctx.Error(newAPIError(http.StatusNotFound, "image.not_attached", "image is not attached to this post"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "image removed from post successfully"})
+}
+
+type storageUsageResponse struct {
+	UserID       int32 `json:"user_id"`
+	ImageCount   int32 `json:"image_count"`
+	ImageBytes   int64 `json:"image_bytes"`
+	VariantBytes int64 `json:"variant_bytes"`
+	TotalBytes   int64 `json:"total_bytes"`
+	// Limits of the user's role, zero when unlimited
+	MaxImages int32 `json:"max_images"`
+	MaxBytes  int64 `json:"max_bytes"`
+}
+
+// getUserStorageUsage reports how much storage the images of a user take,
+// counting each deduplicated image once. Users can see their own usage,
+// admins anyone's.
+func (server *Server) getUserStorageUsage(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || id <= 0 {
+		ctx.Error(invalidParamError("invalid id"))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errUserNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if user.Username != authPayload.Username && authPayload.Role != util.RoleAdmin {
+		ctx.Error(notOwnerError("you can only see your own storage usage"))
+		return
+	}
+
+	usage, err := server.store.GetUserStorageUsage(ctx, sql.NullInt32{Int32: user.ID, Valid: true})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	quota := server.config.StorageQuotaFor(user.Role)
+	ctx.JSON(http.StatusOK, storageUsageResponse{
+		UserID:       user.ID,
+		ImageCount:   usage.ImageCount,
+		ImageBytes:   usage.ImageBytes,
+		VariantBytes: usage.VariantBytes,
+		TotalBytes:   usage.ImageBytes + usage.VariantBytes,
+		MaxImages:    quota.MaxImages,
+		MaxBytes:     quota.MaxBytes,
+	})
+}
+
+// checkStorageQuota verifies that storing one more image taking size bytes
+// with its variants keeps the user within the quota of their role. It records
+// the error itself and reports whether the upload may continue.
+func (server *Server) checkStorageQuota(ctx *gin.Context, user db.User, size int64) bool {
+	quota := server.config.StorageQuotaFor(user.Role)
+	if quota.MaxBytes == 0 && quota.MaxImages == 0 {
+		return true
+	}
+
+	usage, err := server.store.GetUserStorageUsage(ctx, sql.NullInt32{Int32: user.ID, Valid: true})
+	if err != nil {
+		ctx.Error(err)
+		return false
+	}
+
+	if apiErr := storageQuotaError(usage, quota, size); apiErr != nil {
+		ctx.Error(apiErr)
+		return false
+	}
+	return true
+}
+
+// storageQuotaError explains why usage leaves no room in quota for size more
+// bytes, or returns nil when it does
+func storageQuotaError(usage db.GetUserStorageUsageRow, quota util.StorageQuota, size int64) *apiError {
+	if quota.MaxImages > 0 && usage.ImageCount >= quota.MaxImages {
+		return newAPIError(http.StatusUnprocessableEntity, "image.quota_exceeded",
+			fmt.Sprintf("image quota exceeded: you already have %d of %d images", usage.ImageCount, quota.MaxImages))
+	}
+
+	used := usage.ImageBytes + usage.VariantBytes
+	if quota.MaxBytes > 0 && used+size > quota.MaxBytes {
+		return newAPIError(http.StatusRequestEntityTooLarge, "storage.quota_exceeded",
+			fmt.Sprintf("storage quota exceeded: %d of %d bytes used and the image needs %d", used, quota.MaxBytes, size))
+	}
+	return nil
+}
+
+// getEditablePost loads a post and verifies the user may change it, which
+// authors may for their own posts and editors for any. It writes the error
+// response itself and reports whether the caller may continue.
+func (server *Server) getEditablePost(ctx *gin.Context, id int32, authPayload *util.Payload, forbiddenMsg string) (db.GetPostRow, bool) {
+	post, err := server.store.GetPost(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errPostNotFound)
+			return post, false
+		}
+		ctx.Error(err)
+		return post, false
+	}
+
+	if post.Username.String != authPayload.Username && !util.CanEditAnyPost(authPayload.Role) {
+		ctx.Error(notOwnerError(forbiddenMsg))
+		return post, false
+	}
+
+	return post, true
+}
@@ -0,0 +1,935 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/storage"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestPNG encodes a small solid image as PNG
+func createTestPNG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// withPNGSize rewrites the dimensions a PNG declares in its IHDR chunk, which
+// follows the 8 byte signature, and the CRC that covers them
+func withPNGSize(data []byte, width, height uint32) []byte {
+	patched := bytes.Clone(data)
+	binary.BigEndian.PutUint32(patched[16:], width)
+	binary.BigEndian.PutUint32(patched[20:], height)
+	binary.BigEndian.PutUint32(patched[29:], crc32.ChecksumIEEE(patched[12:29]))
+	return patched
+}
+
+// newUploadRequest builds a multipart request with data in the file field
+func newUploadRequest(t *testing.T, url string, data []byte, altText string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if data != nil {
+		part, err := writer.CreateFormFile("file", "upload.bin")
+		require.NoError(t, err)
+		_, err = part.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.WriteField("alt_text", altText))
+	require.NoError(t, writer.Close())
+
+	request, err := http.NewRequest(http.MethodPost, url, &body)
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+// countFiles counts the regular files below dir
+func countFiles(t *testing.T, dir string) int {
+	count := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func TestAddImage(t *testing.T) {
+	pngData := createTestPNG(t, 400, 200)
+	sum := sha256.Sum256(pngData)
+	checksum := hex.EncodeToString(sum[:])
+
+	post := db.GetPostRow{
+		ID:       1,
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		data          []byte
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, dir string)
+	}{
+		{
+			name:     "OK",
+			data:     pngData,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					GetUserImageByChecksum(gomock.Any(), gomock.Eq(db.GetUserImageByChecksumParams{
+						UserID:   sql.NullInt32{Int32: 1, Valid: true},
+						Checksum: checksum,
+					})).
+					Times(1).
+					Return(db.Image{}, sql.ErrNoRows)
+				store.EXPECT().
+					UploadPostImageTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.UploadPostImageTxParams) (db.UploadPostImageTxResult, error) {
+						require.Equal(t, int32(1), arg.PostID)
+						require.Equal(t, int32(1), arg.UserID)
+						require.Equal(t, "a red square", arg.AltText)
+						require.Equal(t, int64(len(pngData)), arg.SizeBytes)
+						require.Equal(t, "image/png", arg.MimeType)
+						require.Equal(t, checksum, arg.Checksum)
+						require.Equal(t, int32(400), arg.Width)
+						require.Equal(t, int32(200), arg.Height)
+						require.False(t, arg.SanitizedAt.IsZero())
+						require.Regexp(t, `^images/1/\d+-[0-9a-f]{16}\.png$`, arg.FilePath)
+
+						// Only the thumbnail is narrower than the upload
+						require.Len(t, arg.Variants, 1)
+						require.Equal(t, "thumbnail", arg.Variants[0].Name)
+						require.Equal(t, int32(320), arg.Variants[0].Width)
+						require.Equal(t, int32(160), arg.Variants[0].Height)
+						require.Equal(t, strings.TrimSuffix(arg.FilePath, ".png")+"-thumbnail.png", arg.Variants[0].FilePath)
+
+						return db.UploadPostImageTxResult{
+							Image: db.Image{
+								ID:        1,
+								UserID:    sql.NullInt32{Int32: arg.UserID, Valid: true},
+								FilePath:  arg.FilePath,
+								SizeBytes: arg.SizeBytes,
+								MimeType:  arg.MimeType,
+								Checksum:  arg.Checksum,
+								Width:     arg.Width,
+								Height:    arg.Height,
+								SanitizedAt: sql.NullTime{
+									Time:  arg.SanitizedAt,
+									Valid: true,
+								},
+							},
+							Variants: []db.ImageVariant{{
+								ImageID:  1,
+								Name:     arg.Variants[0].Name,
+								FilePath: arg.Variants[0].FilePath,
+								MimeType: arg.Variants[0].MimeType,
+								Width:    arg.Variants[0].Width,
+								Height:   arg.Variants[0].Height,
+							}},
+						}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got imageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "image/png", got.MimeType)
+				require.Equal(t, checksum, got.Checksum)
+				require.True(t, got.SanitizedAt.Valid)
+				require.Equal(t, "/uploads/"+got.FilePath, got.URL)
+				require.Len(t, got.Variants, 1)
+				require.Equal(t, got.Variants[0].URL+" 320w, "+got.URL+" 400w", got.Srcset)
+
+				stored, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(got.FilePath)))
+				require.NoError(t, err)
+				require.Equal(t, pngData, stored)
+
+				thumbnail, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(got.Variants[0].URL, "/uploads/"))))
+				require.NoError(t, err)
+				decoded, err := png.Decode(bytes.NewReader(thumbnail))
+				require.NoError(t, err)
+				require.Equal(t, 320, decoded.Bounds().Dx())
+			},
+		},
+		{
+			name:     "Deduplicated",
+			data:     pngData,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				existing := db.Image{
+					ID:       7,
+					UserID:   sql.NullInt32{Int32: 1, Valid: true},
+					FilePath: "images/1/earlier.png",
+					Checksum: checksum,
+				}
+
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().GetUserImageByChecksum(gomock.Any(), gomock.Any()).Times(1).Return(existing, nil)
+				store.EXPECT().
+					UploadPostImageTx(gomock.Any(), gomock.Eq(db.UploadPostImageTxParams{
+						PostID:   1,
+						UserID:   1,
+						AltText:  "a red square",
+						Checksum: checksum,
+					})).
+					Times(1).
+					Return(db.UploadPostImageTxResult{Image: existing, Reused: true}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got imageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, int32(7), got.ID)
+				require.Equal(t, "images/1/earlier.png", got.FilePath)
+
+				// Nothing new was stored
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			name:     "DeduplicatedImageCollected",
+			data:     pngData,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				existing := db.Image{ID: 7, FilePath: "images/1/earlier.png", Checksum: checksum}
+
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().GetUserImageByChecksum(gomock.Any(), gomock.Any()).Times(1).Return(existing, nil)
+				gomock.InOrder(
+					// The orphaned image was collected before it could be linked
+					store.EXPECT().
+						UploadPostImageTx(gomock.Any(), gomock.Eq(db.UploadPostImageTxParams{
+							PostID:   1,
+							UserID:   1,
+							AltText:  "a red square",
+							Checksum: checksum,
+						})).
+						Return(db.UploadPostImageTxResult{}, sql.ErrNoRows),
+					store.EXPECT().
+						UploadPostImageTx(gomock.Any(), gomock.Any()).
+						DoAndReturn(func(_ interface{}, arg db.UploadPostImageTxParams) (db.UploadPostImageTxResult, error) {
+							require.NotEmpty(t, arg.FilePath)
+							return db.UploadPostImageTxResult{
+								Image: db.Image{ID: 9, FilePath: arg.FilePath, MimeType: arg.MimeType, Checksum: arg.Checksum},
+							}, nil
+						}),
+				)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got imageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, int32(9), got.ID)
+
+				// The upload was stored again with its thumbnail
+				require.Equal(t, 2, countFiles(t, dir))
+			},
+		},
+		{
+			name:     "ReusedByConcurrentUpload",
+			data:     pngData,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().GetUserImageByChecksum(gomock.Any(), gomock.Any()).Times(1).Return(db.Image{}, sql.ErrNoRows)
+				store.EXPECT().
+					UploadPostImageTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UploadPostImageTxResult{
+						Image:  db.Image{ID: 8, FilePath: "images/1/other.png", Checksum: checksum},
+						Reused: true,
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			name:     "UnsupportedType",
+			data:     []byte("#!/bin/sh\necho this is not an image\n"),
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().UploadPostImageTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			// A 1x1 PNG whose header claims 100000x100000 pixels
+			name:     "TooManyPixels",
+			data:     withPNGSize(createTestPNG(t, 1, 1), 100000, 100000),
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().GetUserImageByChecksum(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UploadPostImageTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				requireErrorEnvelope(t, recorder, http.StatusUnprocessableEntity, "image.too_many_pixels")
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			name:     "TooLarge",
+			data:     append(pngData, make([]byte, 2048)...),
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().UploadPostImageTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+			},
+		},
+		{
+			name:     "MissingFile",
+			data:     nil,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().UploadPostImageTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			data:     pngData,
+			username: "testuser2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().UploadPostImageTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InternalErrorRemovesBlob",
+			data:     pngData,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().GetUserImageByChecksum(gomock.Any(), gomock.Any()).Times(1).Return(db.Image{}, sql.ErrNoRows)
+				store.EXPECT().
+					UploadPostImageTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UploadPostImageTxResult{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+				MaxUploadSize:     int64(len(pngData)) + 1024,
+				MaxImagePixels:    1 << 20,
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			dir := t.TempDir()
+			server.blobStore = storage.NewLocalBlobStore(dir, "/uploads")
+			recorder := httptest.NewRecorder()
+
+			request := newUploadRequest(t, fmt.Sprintf("/api/v1/posts/%d", post.ID)+"/images", tc.data, "a red square")
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, tc.username))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, dir)
+		})
+	}
+}
+
+func TestListPostImages(t *testing.T) {
+	images := []db.Image{
+		{ID: 2, FilePath: "images/1/b.png", Checksum: "b", AltText: sql.NullString{String: "second upload", Valid: true}},
+		{ID: 1, FilePath: "images/1/a.png", Checksum: "a"},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+	store.EXPECT().ListPostImages(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(images, nil)
+	store.EXPECT().ListImageVariantsByPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return([]db.ImageVariant{}, nil)
+
+	server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/api/v1/posts/1/images", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got []imageResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Len(t, got, 2)
+	require.Equal(t, int32(2), got[0].ID)
+	require.Equal(t, "second upload", got[0].AltText.String)
+	require.Equal(t, int32(1), got[1].ID)
+}
+
+func TestReorderPostImages(t *testing.T) {
+	post := db.GetPostRow{
+		ID:       1,
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		body          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			body:     `{"image_ids": [3, 1, 2]}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().
+					ReorderPostImagesTx(gomock.Any(), gomock.Eq(db.ReorderPostImagesTxParams{
+						PostID:   1,
+						ImageIDs: []int32{3, 1, 2},
+					})).
+					Times(1).
+					Return([]db.Image{{ID: 3}, {ID: 1}, {ID: 2}}, nil)
+				store.EXPECT().ListImageVariantsByPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return([]db.ImageVariant{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []imageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 3)
+				require.Equal(t, []int32{3, 1, 2}, []int32{got[0].ID, got[1].ID, got[2].ID})
+			},
+		},
+		{
+			name:     "Mismatch",
+			body:     `{"image_ids": [1, 1]}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().
+					ReorderPostImagesTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, db.ErrImageOrderMismatch)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "EmptyOrder",
+			body:     `{"image_ids": []}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ReorderPostImagesTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			body:     `{"image_ids": [1]}`,
+			username: "testuser2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().ReorderPostImagesTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPut, "/api/v1/posts/1/images/order", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, tc.username))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateImage(t *testing.T) {
+	image := db.Image{
+		ID:      1,
+		UserID:  sql.NullInt32{Int32: 1, Valid: true},
+		AltText: sql.NullString{String: "old", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		body          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			body:     `{"alt_text": "a red square"}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImage(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(image, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).Times(1).Return(db.User{ID: 1}, nil)
+
+				updated := image
+				updated.AltText = sql.NullString{String: "a red square", Valid: true}
+				store.EXPECT().
+					UpdateImageAltText(gomock.Any(), gomock.Eq(db.UpdateImageAltTextParams{
+						ID:      1,
+						AltText: updated.AltText,
+					})).
+					Times(1).
+					Return(updated, nil)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return([]db.ImageVariant{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got imageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "a red square", got.AltText.String)
+			},
+		},
+		{
+			name:     "ClearAltText",
+			body:     `{"alt_text": ""}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImage(gomock.Any(), gomock.Any()).Times(1).Return(image, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					UpdateImageAltText(gomock.Any(), gomock.Eq(db.UpdateImageAltTextParams{ID: 1})).
+					Times(1).
+					Return(db.Image{ID: 1}, nil)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Any()).Times(1).Return([]db.ImageVariant{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "MissingAltText",
+			body:     `{}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().UpdateImageAltText(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			body:     `{"alt_text": "mine now"}`,
+			username: "testuser2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImage(gomock.Any(), gomock.Any()).Times(1).Return(image, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 2}, nil)
+				store.EXPECT().UpdateImageAltText(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			body:     `{"alt_text": "gone"}`,
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetImage(gomock.Any(), gomock.Any()).Times(1).Return(db.Image{}, sql.ErrNoRows)
+				store.EXPECT().UpdateImageAltText(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPatch, "/api/v1/images/1", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, tc.username))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDetachImage(t *testing.T) {
+	post := db.GetPostRow{
+		ID:       1,
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().
+					RemovePostImage(gomock.Any(), gomock.Eq(db.RemovePostImageParams{PostID: 1, ImageID: 2})).
+					Times(1).
+					Return(int64(1), nil)
+				// The image row and its blobs stay
+				store.EXPECT().DeleteImage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "NotAttached",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().RemovePostImage(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: "testuser2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().RemovePostImage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodDelete, "/api/v1/posts/1/images/2", nil)
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, tc.username))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGetUserStorageUsage(t *testing.T) {
+	user := db.User{ID: 1, Username: "testuser1"}
+
+	testCases := []struct {
+		name          string
+		username      string
+		role          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: "testuser1",
+			role:     util.RoleAuthor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(user, nil)
+				store.EXPECT().
+					GetUserStorageUsage(gomock.Any(), gomock.Eq(sql.NullInt32{Int32: 1, Valid: true})).
+					Times(1).
+					Return(db.GetUserStorageUsageRow{ImageCount: 2, ImageBytes: 3000, VariantBytes: 500}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got storageUsageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, storageUsageResponse{
+					UserID:       1,
+					ImageCount:   2,
+					ImageBytes:   3000,
+					VariantBytes: 500,
+					TotalBytes:   3500,
+				}, got)
+			},
+		},
+		{
+			name:     "Admin",
+			username: "admin",
+			role:     util.RoleAdmin,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().GetUserStorageUsage(gomock.Any(), gomock.Any()).Times(1).Return(db.GetUserStorageUsageRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: "testuser2",
+			role:     util.RoleEditor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().GetUserStorageUsage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: "testuser1",
+			role:     util.RoleAuthor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().GetUserStorageUsage(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/v1/users/1/usage", nil)
+			require.NoError(t, err)
+			addAuthHeader(request, createTestTokenWithRole(t, server.tokenMaker, tc.username, tc.role))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestAddImageQuota(t *testing.T) {
+	pngData := createTestPNG(t, 100, 100)
+	post := db.GetPostRow{
+		ID:       1,
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+	author := db.User{ID: 1, Username: "testuser1", Role: util.RoleAuthor}
+
+	testCases := []struct {
+		name          string
+		user          db.User
+		usage         db.GetUserStorageUsageRow
+		txErr         error
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, dir string)
+	}{
+		{
+			name:  "WithinQuota",
+			user:  author,
+			usage: db.GetUserStorageUsageRow{ImageCount: 1, ImageBytes: 1000},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "TooManyImages",
+			user:  author,
+			usage: db.GetUserStorageUsageRow{ImageCount: 2, ImageBytes: 1000},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "image quota exceeded")
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			name: "TooManyBytes",
+			user: author,
+			// One byte short of room for the upload
+			usage: db.GetUserStorageUsageRow{ImageCount: 1, ImageBytes: 9000, VariantBytes: 1001 - int64(len(pngData))},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "storage quota exceeded")
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			// Another upload of the user was recorded between the check before
+			// storing the blobs and the transaction
+			name:  "ExceededByConcurrentUpload",
+			user:  author,
+			usage: db.GetUserStorageUsageRow{ImageCount: 1, ImageBytes: 1000},
+			txErr: &db.QuotaExceededError{Usage: db.GetUserStorageUsageRow{ImageCount: 2, ImageBytes: 2000}},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				requireErrorEnvelope(t, recorder, http.StatusUnprocessableEntity, "image.quota_exceeded")
+				require.Zero(t, countFiles(t, dir))
+			},
+		},
+		{
+			name: "Unlimited",
+			user: db.User{ID: 1, Username: "testuser1", Role: util.RoleAdmin},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, dir string) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+			store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(tc.user, nil)
+			store.EXPECT().GetUserImageByChecksum(gomock.Any(), gomock.Any()).Times(1).Return(db.Image{}, sql.ErrNoRows)
+			store.EXPECT().GetUserStorageUsage(gomock.Any(), gomock.Any()).AnyTimes().Return(tc.usage, nil)
+			store.EXPECT().UploadPostImageTx(gomock.Any(), gomock.Any()).AnyTimes().Return(db.UploadPostImageTxResult{}, tc.txErr)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+				MaxUploadSize:     1 << 20,
+				MaxImagePixels:    1 << 20,
+				StorageQuotas: map[string]util.StorageQuota{
+					util.RoleAuthor: {MaxBytes: 10000, MaxImages: 2},
+				},
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			dir := t.TempDir()
+			server.blobStore = storage.NewLocalBlobStore(dir, "/uploads")
+			recorder := httptest.NewRecorder()
+
+			request := newUploadRequest(t, "/api/v1/posts/1/images", pngData, "")
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, "testuser1"))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, dir)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLikePost(t *testing.T) {
+	user := db.User{ID: 1, Username: "testuser1"}
+	arg := db.PostLikeTxParams{UserID: user.ID, PostID: 1}
+
+	testCases := []struct {
+		name          string
+		action        string
+		buildStubs    func(store *mockdb.MockStore)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Like",
+			action: "like",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					LikePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.PostLikeTxResult{Post: db.Post{ID: 1, Likes: 3}, LikedByMe: true}, nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, float64(3), got["likes"])
+				require.Equal(t, true, got["liked_by_me"])
+			},
+		},
+		{
+			name:   "Unlike",
+			action: "unlike",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					UnlikePostTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.PostLikeTxResult{Post: db.Post{ID: 1, Likes: 2}}, nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, float64(2), got["likes"])
+				require.Equal(t, false, got["liked_by_me"])
+			},
+		},
+		{
+			name:   "PostNotFound",
+			action: "like",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.GetPostRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					LikePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "DraftOfAnotherUser",
+			action: "like",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{
+						ID:     1,
+						UserID: sql.NullInt32{Int32: 2, Valid: true},
+						Status: sql.NullString{String: util.PostStatusDraft, Valid: true},
+					}, nil)
+				store.EXPECT().
+					LikePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, user.Username)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "NoAuthentication",
+			action: "like",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					LikePostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/api/v1/posts/%d/%s", arg.PostID, tc.action)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListPostLikers(t *testing.T) {
+	likers := []db.ListPostLikersRow{
+		{ID: 2, Username: "reader2"},
+		{ID: 3, Username: "reader3"},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?limit=2&offset=4",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{ID: 1, Status: sql.NullString{String: util.PostStatusPublished, Valid: true}}, nil)
+				store.EXPECT().
+					ListPostLikers(gomock.Any(), gomock.Eq(db.ListPostLikersParams{
+						PostID: 1,
+						Limit:  2,
+						Offset: 4,
+					})).
+					Times(1).
+					Return(likers, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Len(t, got, 2)
+				require.Equal(t, "reader2", got[0]["username"])
+				require.Equal(t, "reader3", got[1]["username"])
+			},
+		},
+		{
+			name:  "PostNotFound",
+			query: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListPostLikers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			url := "/api/v1/posts/1/likers" + tc.query
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
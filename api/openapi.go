@@ -0,0 +1,449 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3gen"
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+)
+
+// authLevel is what an operation asks of the caller's access token
+type authLevel int
+
+const (
+	authNone     authLevel = iota
+	authOptional           // a token lets authors see their own unpublished posts
+	authRequired
+	authAuthor // the author, editor or admin role is needed too
+	authAdmin
+)
+
+// apiOperation documents one route for the OpenAPI specification. Request and
+// response schemas are generated from the Go types, so their binding tags are
+// documented as they are enforced.
+type apiOperation struct {
+	method   string
+	path     string // gin route path, like /api/v1/posts/:id or /uploads/*filepath
+	tag      string
+	summary  string
+	auth     authLevel
+	query    interface{} // struct whose form tags are the query parameters
+	body     interface{} // JSON request body
+	upload   bool        // an image sent as multipart/form-data
+	response interface{} // JSON response body, nil for contentType responses
+	// contentType is the type of responses that are not JSON
+	contentType string
+}
+
+// pageQuery documents the limit and offset query parameters of list endpoints
+type pageQuery struct {
+	Limit  int32 `form:"limit,default=10" binding:"min=1"`
+	Offset int32 `form:"offset,default=0" binding:"min=0"`
+}
+
+// postListQuery documents the query parameters of the post lists
+type postListQuery struct {
+	cursorPageRequest
+	Status string `form:"status,default=published" binding:"oneof=draft scheduled published unlisted archived"`
+}
+
+type searchPostsQuery struct {
+	Q string `form:"q" binding:"required"`
+	cursorPageRequest
+}
+
+type commentThreadQuery struct {
+	pageQuery
+	MaxDepth int32 `form:"max_depth" binding:"min=0"` // can only lower the configured maximum depth
+}
+
+type revisionDiffQuery struct {
+	From int32 `form:"from" binding:"required,min=1"`
+	To   int32 `form:"to" binding:"min=0"` // 0 or left out compares with the current post
+}
+
+// postPageResponse documents the pageResponse of the post lists
+type postPageResponse struct {
+	Data       []gin.H `json:"data"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	Total      *int64  `json:"total,omitempty"`
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// apiOperations lists every route the router registers
+func apiOperations() []apiOperation {
+	operations := []apiOperation{
+		{method: http.MethodGet, path: "/uploads/*filepath", tag: "images", summary: "Uploaded file, when files are stored on local disk", contentType: "application/octet-stream"},
+		{method: http.MethodHead, path: "/uploads/*filepath", tag: "images", summary: "Uploaded file headers, when files are stored on local disk", contentType: "application/octet-stream"},
+		{method: http.MethodGet, path: "/robots.txt", tag: "seo", summary: "Crawler rules and the sitemap location", contentType: "text/plain"},
+		{method: http.MethodGet, path: "/sitemap.xml", tag: "seo", summary: "Sitemap of published posts, their authors and tags, or a sitemap index", contentType: "application/xml"},
+		{method: http.MethodGet, path: "/sitemaps/:file", tag: "seo", summary: "Numbered sitemap listed by the sitemap index", contentType: "application/xml"},
+
+		{method: http.MethodGet, path: "/api/v1/openapi.json", tag: "docs", summary: "This OpenAPI specification", response: gin.H{}},
+		{method: http.MethodGet, path: "/api/v1/docs", tag: "docs", summary: "Interactive API documentation", contentType: "text/html"},
+
+		{method: http.MethodPost, path: "/api/v1/users", tag: "users", summary: "Register a user", body: createUserRequest{}, response: userResponse{}},
+		{method: http.MethodPost, path: "/api/v1/login", tag: "auth", summary: "Log in", body: loginUserRequest{}, response: loginUserResponse{}},
+		{method: http.MethodPost, path: "/api/v1/logout", tag: "auth", summary: "Revoke a refresh token", body: refreshTokenRequest{}, response: messageResponse{}},
+		{method: http.MethodPost, path: "/api/v1/tokens/renew", tag: "auth", summary: "Renew an access token", body: refreshTokenRequest{}, response: renewAccessTokenResponse{}},
+		{method: http.MethodPost, path: "/api/v1/password/forgot", tag: "auth", summary: "Email a password reset link", body: forgotPasswordRequest{}, response: messageResponse{}},
+		{method: http.MethodPost, path: "/api/v1/password/reset", tag: "auth", summary: "Reset a password", body: resetPasswordRequest{}, response: messageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/verify-email", tag: "auth", summary: "Verify an email address", query: verifyEmailRequest{}, response: messageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/users/:id", tag: "users", summary: "Get a user", response: userResponse{}},
+		{method: http.MethodGet, path: "/api/v1/users", tag: "users", summary: "List users", query: listUsersRequest{}, response: []userResponse{}},
+		{method: http.MethodGet, path: "/api/v1/posts/:id", tag: "posts", summary: "Get a post", auth: authOptional, response: gin.H{}},
+		{method: http.MethodGet, path: "/api/v1/posts/by-slug/:slug", tag: "posts", summary: "Get a post by slug, old slugs redirect", auth: authOptional, response: gin.H{}},
+		{method: http.MethodGet, path: "/api/v1/posts", tag: "posts", summary: "List posts", auth: authOptional, query: postListQuery{}, response: postPageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/posts/filter", tag: "posts", summary: "Filter posts", auth: authOptional, query: PostFilter{}, response: postPageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/posts/search", tag: "posts", summary: "Search published posts", query: searchPostsQuery{}, response: postPageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/users/:id/posts", tag: "posts", summary: "List the posts of a user", auth: authOptional, query: postListQuery{}, response: postPageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/posts/by-likes", tag: "posts", summary: "List posts by likes", auth: authOptional, query: postListQuery{}, response: postPageResponse{}},
+		{method: http.MethodGet, path: "/api/v1/users/by-likes", tag: "users", summary: "List users by the likes of their posts", query: pageQuery{}, response: []gin.H{}},
+		{method: http.MethodGet, path: "/api/v1/posts/:id/comments", tag: "comments", summary: "List the comment threads of a post", query: commentThreadQuery{}, response: []*commentNode{}},
+		{method: http.MethodGet, path: "/api/v1/comments/:id/replies", tag: "comments", summary: "List the replies to a comment", query: commentThreadQuery{}, response: []*commentNode{}},
+		{method: http.MethodGet, path: "/api/v1/posts/:id/likers", tag: "likes", summary: "List the users who liked a post", query: pageQuery{}, response: []gin.H{}},
+		{method: http.MethodGet, path: "/api/v1/posts/:id/images", tag: "images", summary: "List the images of a post", response: []imageResponse{}},
+	}
+
+	for _, format := range feedFormats {
+		contentType := strings.TrimSuffix(feedContentTypes[format], "; charset=utf-8")
+		operations = append(operations,
+			apiOperation{method: http.MethodGet, path: "/api/v1/feed." + format, tag: "feeds", summary: "Feed of the latest posts", contentType: contentType},
+			apiOperation{method: http.MethodGet, path: "/api/v1/users/:id/feed." + format, tag: "feeds", summary: "Feed of the latest posts of a user", contentType: contentType},
+			apiOperation{method: http.MethodGet, path: "/api/v1/tags/:id/feed." + format, tag: "feeds", summary: "Feed of the latest posts with a tag", contentType: contentType},
+		)
+	}
+
+	return append(operations, []apiOperation{
+		{method: http.MethodPut, path: "/api/v1/users/:id", tag: "users", summary: "Update your profile", auth: authRequired, body: updateUserRequest{}, response: userResponse{}},
+		{method: http.MethodPut, path: "/api/v1/users/:id/password", tag: "users", summary: "Change your password", auth: authRequired, body: updateUserPasswordRequest{}, response: gin.H{}},
+		{method: http.MethodGet, path: "/api/v1/users/:id/usage", tag: "images", summary: "Get the storage usage of a user", auth: authRequired, response: storageUsageResponse{}},
+		{method: http.MethodPost, path: "/api/v1/verify-email/resend", tag: "auth", summary: "Resend the verification email", auth: authRequired, response: messageResponse{}},
+
+		{method: http.MethodPost, path: "/api/v1/posts", tag: "posts", summary: "Create a post", auth: authAuthor, body: createPostRequest{}, response: postResponse{}},
+		{method: http.MethodPut, path: "/api/v1/posts/:id", tag: "posts", summary: "Update a post", auth: authAuthor, body: updatePostRequest{}, response: db.Post{}},
+		{method: http.MethodDelete, path: "/api/v1/posts/:id", tag: "posts", summary: "Delete a post", auth: authAuthor, response: messageResponse{}},
+
+		{method: http.MethodGet, path: "/api/v1/posts/:id/revisions", tag: "revisions", summary: "List the revisions of a post", auth: authAuthor, query: pageQuery{}, response: []postRevisionResponse{}},
+		{method: http.MethodGet, path: "/api/v1/posts/:id/revisions/diff", tag: "revisions", summary: "Diff two revisions of a post", auth: authAuthor, query: revisionDiffQuery{}, response: postRevisionDiffResponse{}},
+		{method: http.MethodPost, path: "/api/v1/posts/:id/revisions/:rev/restore", tag: "revisions", summary: "Restore a revision of a post", auth: authAuthor, response: db.Post{}},
+
+		{method: http.MethodPost, path: "/api/v1/posts/:id/images", tag: "images", summary: "Upload an image to a post", auth: authAuthor, upload: true, response: imageResponse{}},
+		{method: http.MethodPut, path: "/api/v1/posts/:id/images/order", tag: "images", summary: "Reorder the images of a post", auth: authAuthor, body: reorderPostImagesRequest{}, response: []imageResponse{}},
+		{method: http.MethodDelete, path: "/api/v1/posts/:id/images/:imageId", tag: "images", summary: "Remove an image from a post", auth: authAuthor, response: messageResponse{}},
+		{method: http.MethodPatch, path: "/api/v1/images/:id", tag: "images", summary: "Update the alt text of an image", auth: authAuthor, body: updateImageRequest{}, response: imageResponse{}},
+		{method: http.MethodDelete, path: "/api/v1/images/:id", tag: "images", summary: "Delete an image", auth: authAuthor, response: messageResponse{}},
+
+		{method: http.MethodPost, path: "/api/v1/posts/:id/tags", tag: "tags", summary: "Tag a post", auth: authAuthor, body: addTagRequest{}, response: db.Tag{}},
+		{method: http.MethodDelete, path: "/api/v1/tags/:id", tag: "tags", summary: "Delete a tag", auth: authAuthor, response: messageResponse{}},
+		{method: http.MethodDelete, path: "/api/v1/posts/:id/tags/:tagId", tag: "tags", summary: "Remove a tag from a post", auth: authAuthor, response: messageResponse{}},
+
+		{method: http.MethodPut, path: "/api/v1/admin/users/:id/role", tag: "users", summary: "Change the role of a user", auth: authAdmin, body: updateUserRoleRequest{}, response: userResponse{}},
+
+		{method: http.MethodPost, path: "/api/v1/posts/:id/like", tag: "likes", summary: "Like a post", auth: authRequired, response: gin.H{}},
+		{method: http.MethodPost, path: "/api/v1/posts/:id/unlike", tag: "likes", summary: "Unlike a post", auth: authRequired, response: gin.H{}},
+
+		{method: http.MethodPost, path: "/api/v1/posts/:id/comments", tag: "comments", summary: "Comment on a post", auth: authRequired, body: createCommentRequest{}, response: commentResponse{}},
+		{method: http.MethodPost, path: "/api/v1/comments/:id/replies", tag: "comments", summary: "Reply to a comment", auth: authRequired, body: createCommentRequest{}, response: commentResponse{}},
+		{method: http.MethodPut, path: "/api/v1/comments/:id", tag: "comments", summary: "Edit your comment", auth: authRequired, body: updateCommentRequest{}, response: commentResponse{}},
+		{method: http.MethodDelete, path: "/api/v1/comments/:id", tag: "comments", summary: "Delete your comment", auth: authRequired, response: messageResponse{}},
+	}...)
+}
+
+// openAPISpec is the OpenAPI document of the API as JSON. It only depends on
+// the code, so it is built once.
+var openAPISpec = sync.OnceValues(func() ([]byte, error) {
+	spec, err := buildOpenAPISpec(apiOperations())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(spec)
+})
+
+func (server *Server) getOpenAPISpec(ctx *gin.Context) {
+	spec, err := openAPISpec()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
+
+// apiDocsPage loads Swagger UI from a CDN and points it at the specification
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>API documentation</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+func (server *Server) getAPIDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(apiDocsPage))
+}
+
+// buildOpenAPISpec documents operations as an OpenAPI 3 document
+func buildOpenAPISpec(operations []apiOperation) (*openapi3.T, error) {
+	spec := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:   "Portfolio API",
+			Version: "1.0.0",
+		},
+		Paths: openapi3.NewPaths(),
+		Components: &openapi3.Components{
+			Schemas: openapi3.Schemas{},
+			SecuritySchemes: openapi3.SecuritySchemes{
+				"bearerAuth": &openapi3.SecuritySchemeRef{
+					Value: openapi3.NewSecurityScheme().WithType("http").WithScheme("bearer").WithBearerFormat("PASETO"),
+				},
+			},
+		},
+	}
+
+	errorSchema, err := generateSchema(errorEnvelope{}, spec.Components.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	errorResp := openapi3.NewResponse().WithDescription("Error").WithJSONSchemaRef(errorSchema)
+
+	for _, o := range operations {
+		op := openapi3.NewOperation()
+		op.Tags = []string{o.tag}
+		op.Summary = o.summary
+
+		switch o.auth {
+		case authOptional:
+			op.Security = &openapi3.SecurityRequirements{openapi3.NewSecurityRequirement().Authenticate("bearerAuth"), {}}
+		case authRequired, authAuthor, authAdmin:
+			op.Security = openapi3.NewSecurityRequirements().With(openapi3.NewSecurityRequirement().Authenticate("bearerAuth"))
+		}
+		switch o.auth {
+		case authAuthor:
+			op.Description = "Requires the author, editor or admin role."
+		case authAdmin:
+			op.Description = "Requires the admin role."
+		}
+
+		path := o.path
+		for _, segment := range strings.Split(o.path, "/") {
+			if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+				continue
+			}
+			name := segment[1:]
+			path = strings.Replace(path, segment, "{"+name+"}", 1)
+			op.AddParameter(openapi3.NewPathParameter(name).WithSchema(pathParamSchema(name)))
+		}
+
+		if o.query != nil {
+			params, err := queryParameters(reflect.TypeOf(o.query))
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", o.method, o.path, err)
+			}
+			for _, param := range params {
+				op.AddParameter(param)
+			}
+		}
+
+		if o.body != nil {
+			schema, err := generateSchema(o.body, spec.Components.Schemas)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", o.method, o.path, err)
+			}
+			op.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(schema)}
+		}
+		if o.upload {
+			schema := openapi3.NewObjectSchema().
+				WithProperty("file", openapi3.NewStringSchema().WithFormat("binary")).
+				WithProperty("alt_text", openapi3.NewStringSchema())
+			schema.Required = []string{"file"}
+			op.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithRequired(true).WithFormDataSchema(schema)}
+		}
+
+		resp := openapi3.NewResponse().WithDescription("OK")
+		if o.response != nil {
+			schema, err := generateSchema(o.response, spec.Components.Schemas)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", o.method, o.path, err)
+			}
+			resp.WithJSONSchemaRef(schema)
+		} else {
+			resp.WithContent(openapi3.NewContentWithSchema(openapi3.NewStringSchema(), []string{o.contentType}))
+		}
+		op.Responses = openapi3.NewResponses(
+			openapi3.WithStatus(http.StatusOK, &openapi3.ResponseRef{Value: resp}),
+			openapi3.WithName("default", errorResp),
+		)
+
+		spec.AddOperation(path, o.method, op)
+	}
+
+	return spec, nil
+}
+
+// pathParamSchema describes a path parameter. Ids are numbers, the rest text.
+func pathParamSchema(name string) *openapi3.Schema {
+	if name == "id" || name == "rev" || strings.HasSuffix(name, "Id") {
+		return openapi3.NewInt32Schema()
+	}
+	return openapi3.NewStringSchema()
+}
+
+// generateSchema describes the JSON encoding of v, adding the structs it is
+// made of to schemas
+func generateSchema(v interface{}, schemas openapi3.Schemas) (*openapi3.SchemaRef, error) {
+	return openapi3gen.NewSchemaRefForValue(v, schemas,
+		openapi3gen.UseAllExportedFields(),
+		openapi3gen.CreateComponentSchemas(openapi3gen.ExportComponentSchemasOptions{
+			ExportComponentSchemas: true,
+			ExportTopLevelSchema:   true,
+		}),
+		openapi3gen.SchemaCustomizer(bindingCustomizer),
+	)
+}
+
+// bindingCustomizer documents the gin binding rules of struct fields. Required
+// fields are listed on the struct, the other rules on the field.
+func bindingCustomizer(name string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() && hasBindingRule(field.Tag, "required") {
+				schema.Required = append(schema.Required, jsonFieldName(field))
+			}
+		}
+	}
+	return applyBindingRules(t, tag.Get("binding"), schema)
+}
+
+// queryParameters documents the fields of struct type t that gin binds from
+// the query string by their form tags
+func queryParameters(t reflect.Type) ([]*openapi3.Parameter, error) {
+	var params []*openapi3.Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			embedded, err := queryParameters(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			params = append(params, embedded...)
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		schemaRef, err := openapi3gen.NewSchemaRefForValue(reflect.Zero(field.Type).Interface(), nil)
+		if err != nil {
+			return nil, err
+		}
+		schema := schemaRef.Value
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if err := applyBindingRules(fieldType, field.Tag.Get("binding"), schema); err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if value, ok := strings.CutPrefix(options, "default="); ok {
+			schema.Default = value
+			if n, err := strconv.Atoi(value); err == nil {
+				schema.Default = n
+			}
+		}
+
+		param := openapi3.NewQueryParameter(name).WithSchema(schema)
+		param.Required = hasBindingRule(field.Tag, "required")
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// applyBindingRules carries the binding rules of a field of type t over to its
+// schema. Rules after dive apply to the elements and are left out.
+func applyBindingRules(t reflect.Type, rules string, schema *openapi3.Schema) error {
+	if rules == "" {
+		return nil
+	}
+	for _, rule := range strings.Split(rules, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			return nil
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			for _, v := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, v)
+			}
+		case "min", "max":
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid binding rule %s", rule)
+			}
+			switch {
+			case t.Kind() == reflect.String && key == "min":
+				schema.MinLength = n
+			case t.Kind() == reflect.String:
+				schema.MaxLength = &n
+			case t.Kind() == reflect.Slice && key == "min":
+				schema.MinItems = n
+			case t.Kind() == reflect.Slice:
+				schema.MaxItems = &n
+			case key == "min":
+				f := float64(n)
+				schema.Min = &f
+			default:
+				f := float64(n)
+				schema.Max = &f
+			}
+		}
+	}
+	return nil
+}
+
+func hasBindingRule(tag reflect.StructTag, rule string) bool {
+	for _, r := range strings.Split(tag.Get("binding"), ",") {
+		if r == "dive" {
+			return false
+		}
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonFieldName is the name encoding/json gives field
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+// routeParam matches the :name and *name parameters of gin route paths
+var routeParam = regexp.MustCompile(`[:*]([^/]+)`)
+
+func loadOpenAPISpec(t *testing.T) *openapi3.T {
+	data, err := openAPISpec()
+	require.NoError(t, err)
+
+	spec, err := openapi3.NewLoader().LoadFromData(data)
+	require.NoError(t, err)
+	return spec
+}
+
+func TestOpenAPISpecIsValid(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+	require.NoError(t, spec.Validate(context.Background()))
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// A storage directory registers the uploads route too
+	server, err := NewServer(mockdb.NewMockStore(ctrl), util.Config{
+		TokenSymmetricKey: "12345678901234567890123456789012",
+		StorageDir:        t.TempDir(),
+	})
+	require.NoError(t, err)
+	spec := loadOpenAPISpec(t)
+
+	registered := map[string]bool{}
+	for _, route := range server.router.Routes() {
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		registered[route.Method+" "+path] = true
+
+		item := spec.Paths.Value(path)
+		require.NotNil(t, item, "route %s %s is missing from the OpenAPI spec", route.Method, route.Path)
+		require.NotNil(t, item.GetOperation(route.Method), "route %s %s is missing from the OpenAPI spec", route.Method, route.Path)
+	}
+
+	for path, item := range spec.Paths.Map() {
+		for method := range item.Operations() {
+			require.True(t, registered[method+" "+path], "%s %s is in the OpenAPI spec but not registered", method, path)
+		}
+	}
+}
+
+func TestOpenAPISpecFollowsBindingTags(t *testing.T) {
+	spec := loadOpenAPISpec(t)
+
+	createUser := spec.Components.Schemas["createUserRequest"].Value
+	require.ElementsMatch(t, []string{"username", "email", "password"}, createUser.Required)
+	require.Equal(t, "email", createUser.Properties["email"].Value.Format)
+	require.Equal(t, uint64(6), createUser.Properties["password"].Value.MinLength)
+
+	updateRole := spec.Components.Schemas["updateUserRoleRequest"].Value
+	require.Equal(t, []interface{}{"admin", "editor", "author", "reader"}, updateRole.Properties["role"].Value.Enum)
+
+	reorder := spec.Components.Schemas["reorderPostImagesRequest"].Value
+	require.Equal(t, uint64(1), reorder.Properties["image_ids"].Value.MinItems)
+
+	listUsers := spec.Paths.Value("/api/v1/users").Get
+	pageSize := listUsers.Parameters.GetByInAndName(openapi3.ParameterInQuery, "page_size")
+	require.NotNil(t, pageSize)
+	require.True(t, pageSize.Required)
+	require.Equal(t, 5.0, *pageSize.Schema.Value.Min)
+	require.Equal(t, 10.0, *pageSize.Schema.Value.Max)
+
+	filter := spec.Paths.Value("/api/v1/posts/filter").Get
+	limit := filter.Parameters.GetByInAndName(openapi3.ParameterInQuery, "limit")
+	require.NotNil(t, limit)
+	require.False(t, limit.Required)
+	require.Equal(t, 10.0, limit.Schema.Value.Default)
+
+	getPost := spec.Paths.Value("/api/v1/posts/{id}").Get
+	require.NotNil(t, getPost.Parameters.GetByInAndName(openapi3.ParameterInPath, "id"))
+	require.Len(t, *getPost.Security, 2)
+}
+
+func TestServeOpenAPIDocs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		url         string
+		contentType string
+	}{
+		{
+			name:        "Spec",
+			url:         "/api/v1/openapi.json",
+			contentType: "application/json; charset=utf-8",
+		},
+		{
+			name:        "Docs",
+			url:         "/api/v1/docs",
+			contentType: "text/html; charset=utf-8",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server, err := NewServer(mockdb.NewMockStore(ctrl), util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, tc.contentType, recorder.Header().Get("Content-Type"))
+			require.NotEmpty(t, recorder.Body.String())
+		})
+	}
+}
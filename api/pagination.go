@@ -0,0 +1,114 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Orders of the post lists their cursors belong to
+const (
+	sortNewest    = "created_at:desc"
+	sortMostLiked = "likes:desc"
+	sortRelevance = "rank:desc" // search results, ties broken newest first
+)
+
+var errInvalidCursor = newAPIError(http.StatusBadRequest, "request.invalid_cursor", "invalid cursor")
+
+// cursorPageRequest holds the query parameters of lists paginated by cursor.
+// Cursor is the next_cursor of the previous page, left out for the first.
+type cursorPageRequest struct {
+	Limit        int32  `form:"limit,default=10" binding:"min=1,max=100"`
+	Cursor       string `form:"cursor"`
+	IncludeTotal bool   `form:"include_total"` // counting costs a query, so it is opt-in
+}
+
+// pageResponse is the envelope of lists paginated by cursor. NextCursor is
+// left out on the last page, Total unless the client asked for it.
+type pageResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	Total      *int64      `json:"total,omitempty"`
+}
+
+// postCursor marks the last post of a page, the next page starts after it.
+// Posts are ordered by one of the sort fields and then by id, Sort names that
+// order so a cursor is not reused with another one.
+type postCursor struct {
+	Sort      string     `json:"s"`
+	ID        int32      `json:"id"`
+	CreatedAt *time.Time `json:"c,omitempty"`
+	Likes     int32      `json:"l,omitempty"`
+	Title     string     `json:"t,omitempty"`
+	Rank      float32    `json:"r,omitempty"`
+}
+
+// encode returns the cursor in the opaque form handed to clients
+func (cursor postCursor) encode() string {
+	// A struct of strings, numbers and a time always marshals
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePostCursor parses a cursor sent back by a client for a list in the
+// sort order. It returns nil for the first page.
+func decodePostCursor(value, sort string) (*postCursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var cursor postCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Sort != sort || cursor.ID <= 0 {
+		return nil, errInvalidCursor
+	}
+	if (strings.HasPrefix(sort, "created_at:") || sort == sortRelevance) && cursor.CreatedAt == nil {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// writePage responds with a page of a list. The Link header (RFC 8288) points
+// to the first page and, unless this is the last one, to the next page.
+func writePage(ctx *gin.Context, data interface{}, nextCursor string, total *int64) {
+	links := []string{pageLink(ctx, "", "first")}
+	if nextCursor != "" {
+		links = append(links, pageLink(ctx, nextCursor, "next"))
+	}
+	ctx.Header("Link", strings.Join(links, ", "))
+
+	ctx.JSON(http.StatusOK, pageResponse{
+		Data:       data,
+		NextCursor: nextCursor,
+		Total:      total,
+	})
+}
+
+// pageLink is a link to the page of the current list starting after cursor,
+// keeping the other query parameters of the request
+func pageLink(ctx *gin.Context, cursor, rel string) string {
+	query := ctx.Request.URL.Query()
+	query.Del("cursor")
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+
+	target := url.URL{Path: ctx.Request.URL.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+}
+
+// newestPostCursor marks a post of a list ordered newest first
+func newestPostCursor(id int32, createdAt sql.NullTime) postCursor {
+	return postCursor{Sort: sortNewest, ID: id, CreatedAt: &createdAt.Time}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// forgotPassword handles emailing a password reset link. It answers the same
+// way whether or not the email is registered, so it cannot be used to find
+// out who has an account.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	response := gin.H{"message": "if the email is registered, a password reset link has been sent"}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusOK, response)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	token, tokenHash, err := util.NewOpaqueToken()
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	_, err = server.store.CreatePasswordResetToken(ctx, db.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().UTC().Add(server.config.PasswordResetTokenDuration),
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	resetURL := fmt.Sprintf("%s/reset-password?token=%s",
+		strings.TrimRight(server.config.AppBaseURL, "/"), url.QueryEscape(token))
+
+	err = server.mailer.SendEmail(util.Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. "+
+			"Open the link below to choose a new one. It expires in %s and can only be used once.\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.Username, server.config.PasswordResetTokenDuration, resetURL),
+	})
+	if err != nil {
+		// Failing here would tell the caller the email is registered
+		log.Printf("cannot send password reset email to user %d: %v", user.ID, err)
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// resetPassword handles setting a new password with a reset token. All of the
+// user's sessions are revoked, so they have to log in again everywhere.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	resetToken, err := server.store.GetPasswordResetTokenByHash(ctx, util.HashOpaqueToken(req.Token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(newAPIError(http.StatusBadRequest, "auth.invalid_reset_token", "invalid or expired reset token"))
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	if resetToken.UsedAt.Valid || time.Now().After(resetToken.ExpiresAt) {
+		ctx.Error(newAPIError(http.StatusBadRequest, "auth.invalid_reset_token", "invalid or expired reset token"))
+		return
+	}
+
+	hashedPassword, err := util.HashPassword(req.Password)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenID:      resetToken.ID,
+		UserID:       resetToken.UserID,
+		PasswordHash: hashedPassword,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(newAPIError(http.StatusBadRequest, "auth.invalid_reset_token", "invalid or expired reset token"))
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingMailer keeps sent emails in memory
+type recordingMailer struct {
+	sent []util.Email
+}
+
+func (mailer *recordingMailer) SendEmail(email util.Email) error {
+	mailer.sent = append(mailer.sent, email)
+	return nil
+}
+
+func TestForgotPassword(t *testing.T) {
+	user := db.User{ID: 1, Username: "testuser1", Email: "testuser1@example.com"}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+						require.Equal(t, user.ID, arg.UserID)
+						require.Len(t, arg.TokenHash, 64)
+						require.WithinDuration(t, time.Now().Add(time.Hour), arg.ExpiresAt, time.Second)
+						require.Equal(t, time.UTC, arg.ExpiresAt.Location())
+						return db.PasswordResetToken{ID: 1, UserID: arg.UserID, TokenHash: arg.TokenHash}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Len(t, mailer.sent, 1)
+				require.Equal(t, user.Email, mailer.sent[0].To)
+
+				// The link carries the token, not its stored hash
+				link := regexp.MustCompile(`https://example.com/reset-password\?token=\S+`).FindString(mailer.sent[0].Body)
+				require.NotEmpty(t, link)
+				parsed, err := url.Parse(link)
+				require.NoError(t, err)
+				require.Len(t, util.HashOpaqueToken(parsed.Query().Get("token")), 64)
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": "nobody@example.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreatePasswordResetToken(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer) {
+				// Same answer as for a registered email
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Empty(t, mailer.sent)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "not-an-email"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByEmail(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:          "12345678901234567890123456789012",
+				PasswordResetTokenDuration: time.Hour,
+				AppBaseURL:                 "https://example.com/",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			mailer := &recordingMailer{}
+			server.mailer = mailer
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/password/forgot", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, mailer)
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	token, tokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+
+	resetToken := db.PasswordResetToken{
+		ID:        1,
+		UserID:    1,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": token, "password": "newsecret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPasswordResetTokenByHash(gomock.Any(), gomock.Eq(tokenHash)).
+					Times(1).
+					Return(resetToken, nil)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.ResetPasswordTxParams) error {
+						require.Equal(t, resetToken.ID, arg.TokenID)
+						require.Equal(t, resetToken.UserID, arg.UserID)
+						require.NoError(t, util.CheckPassword("newsecret", arg.PasswordHash))
+						return nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UsedToken",
+			body: gin.H{"token": token, "password": "newsecret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				used := resetToken
+				used.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetPasswordResetTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(used, nil)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			body: gin.H{"token": token, "password": "newsecret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := resetToken
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+				store.EXPECT().
+					GetPasswordResetTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(expired, nil)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentReset",
+			body: gin.H{"token": token, "password": "newsecret"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPasswordResetTokenByHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(resetToken, nil)
+				store.EXPECT().
+					ResetPasswordTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{"token": token, "password": "123"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPasswordResetTokenByHash(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/password/reset", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
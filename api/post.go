@@ -1,36 +1,39 @@
 package api
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/render"
 	"github.com/haotianxu2021/newPortfolio/util"
 )
 
 type createPostRequest struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content" binding:"required"`
-	UserID  int32  `json:"user_id" binding:"required"`
-	Type    string `json:"type" binding:"required"`
-	Status  string `json:"status"`
+	Title     string     `json:"title" binding:"required"`
+	Content   string     `json:"content" binding:"required"`
+	UserID    int32      `json:"user_id" binding:"required"`
+	Type      string     `json:"type" binding:"required"`
+	Format    string     `json:"format" binding:"omitempty,oneof=markdown html plain"`
+	Status    string     `json:"status" binding:"omitempty,oneof=draft scheduled published unlisted archived"`
+	PublishAt *time.Time `json:"publish_at"` // required for scheduled posts
 }
 
 type updatePostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Type    string `json:"type"`
-	Status  string `json:"status"`
-}
-
-type addImageRequest struct {
-	UserID   int32  `json:"user_id" binding:"required"`
-	FilePath string `json:"file_path" binding:"required"`
-	AltText  string `json:"alt_text"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Type      string     `json:"type"`
+	Format    string     `json:"format" binding:"omitempty,oneof=markdown html plain"`
+	Status    string     `json:"status" binding:"omitempty,oneof=draft scheduled published unlisted archived"`
+	PublishAt *time.Time `json:"publish_at"` // reschedules a scheduled post
 }
 
 type addTagRequest struct {
@@ -38,15 +41,100 @@ type addTagRequest struct {
 }
 
 type postResponse struct {
-	ID        int32          `json:"id"`
-	UserID    sql.NullInt32  `json:"user_id"`
-	Title     string         `json:"title"`
-	Content   string         `json:"content"`
-	Type      string         `json:"type"`
-	Status    sql.NullString `json:"status"`
-	Likes     int32          `json:"likes"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
+	ID          int32           `json:"id"`
+	UserID      sql.NullInt32   `json:"user_id"`
+	Title       string          `json:"title"`
+	Slug        string          `json:"slug"`
+	Content     string          `json:"content"`
+	Format      string          `json:"format"`
+	ContentHTML string          `json:"content_html"`
+	TOC         json.RawMessage `json:"toc"`
+	Type        string          `json:"type"`
+	Status      sql.NullString  `json:"status"`
+	Likes       int32           `json:"likes"`
+	PublishAt   sql.NullTime    `json:"publish_at"`
+	CreatedAt   sql.NullTime    `json:"created_at"`
+	UpdatedAt   sql.NullTime    `json:"updated_at"`
+}
+
+// renderPost renders content for the content_html and toc columns of a post
+func renderPost(format, content string) (sql.NullString, json.RawMessage, error) {
+	result, err := render.Render(format, content)
+	if err != nil {
+		return sql.NullString{}, nil, err
+	}
+	toc, err := json.Marshal(result.TOC)
+	if err != nil {
+		return sql.NullString{}, nil, err
+	}
+	return sql.NullString{String: result.HTML, Valid: true}, toc, nil
+}
+
+// resolvePublishAt works out publish_at for a post moving from status from to
+// status to, given the publish_at the client asked for and the stored one.
+// Scheduled posts go live at a time in the future and published and unlisted
+// posts remember when they first went live, other statuses keep what is stored.
+func resolvePublishAt(from, to string, requested *time.Time, current sql.NullTime, now time.Time) (sql.NullTime, error) {
+	if requested != nil && to != util.PostStatusScheduled {
+		return sql.NullTime{}, fmt.Errorf("publish_at can only be set on scheduled posts")
+	}
+
+	switch to {
+	case util.PostStatusScheduled:
+		if requested == nil {
+			if from == util.PostStatusScheduled && current.Valid {
+				return current, nil
+			}
+			return sql.NullTime{}, fmt.Errorf("publish_at is required for scheduled posts")
+		}
+		if !requested.After(now) {
+			return sql.NullTime{}, fmt.Errorf("publish_at must be in the future")
+		}
+		return sql.NullTime{Time: requested.UTC(), Valid: true}, nil
+	case util.PostStatusPublished, util.PostStatusUnlisted:
+		if util.IsReadablePostStatus(from) && current.Valid {
+			return current, nil
+		}
+		return sql.NullTime{Time: now.UTC(), Valid: true}, nil
+	}
+	return current, nil
+}
+
+// canSeeUnpublished reports whether user may see posts owned by ownerID that
+// are not published. Only their author, editors and admins may.
+func canSeeUnpublished(user db.User, authenticated bool, ownerID sql.NullInt32) bool {
+	if !authenticated {
+		return false
+	}
+	return (ownerID.Valid && ownerID.Int32 == user.ID) || util.CanEditAnyPost(user.Role)
+}
+
+// postVisibility turns the reader of a post list into the parameters that
+// keep posts which are not published out of it, unless the reader wrote them
+// or may edit any post
+func postVisibility(user db.User, authenticated bool) (viewerID sql.NullInt32, allStatuses bool) {
+	if !authenticated {
+		return sql.NullInt32{}, false
+	}
+	return sql.NullInt32{Int32: user.ID, Valid: true}, util.CanEditAnyPost(user.Role)
+}
+
+// getReadablePost returns the post with the given id if reader may see it.
+// Posts that are not published do not exist for other readers, so they are
+// reported as not found too.
+func (server *Server) getReadablePost(ctx *gin.Context, id int32, reader db.User, authenticated bool) (db.GetPostRow, error) {
+	post, err := server.store.GetPost(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.GetPostRow{}, errPostNotFound
+		}
+		return db.GetPostRow{}, err
+	}
+
+	if !util.IsReadablePostStatus(post.Status.String) && !canSeeUnpublished(reader, authenticated, post.UserID) {
+		return db.GetPostRow{}, errPostNotFound
+	}
+	return post, nil
 }
 
 func (server *Server) createPost(ctx *gin.Context) {
@@ -56,22 +144,47 @@ func (server *Server) createPost(ctx *gin.Context) {
 	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+			ctx.Error(errTokenUserGone)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+
+	if server.config.RequireEmailVerification && !user.EmailVerifiedAt.Valid {
+		ctx.Error(errEmailUnverified)
 		return
 	}
 
 	// Verify request userID matches authenticated user
 	var req createPostRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	if req.UserID != int32(user.ID) {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "you can only create posts for yourself"})
+		ctx.Error(notOwnerError("you can only create posts for yourself"))
+		return
+	}
+
+	status := req.Status
+	if status == "" {
+		status = util.PostStatusPublished
+	}
+	publishAt, err := resolvePublishAt("", status, req.PublishAt, sql.NullTime{}, time.Now())
+	if err != nil {
+		ctx.Error(newAPIError(http.StatusBadRequest, "post.invalid_publish_at", err.Error()))
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = render.FormatMarkdown
+	}
+	contentHTML, toc, err := renderPost(format, req.Content)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -84,26 +197,35 @@ func (server *Server) createPost(ctx *gin.Context) {
 		},
 		Type: req.Type,
 		Status: sql.NullString{
-			String: req.Status,
+			String: status,
 			Valid:  true,
 		},
+		PublishAt:   publishAt,
+		Format:      format,
+		ContentHtml: contentHTML,
+		Toc:         toc,
 	}
 
-	post, err := server.store.CreatePost(ctx, arg)
+	post, err := server.store.CreatePostWithSlugTx(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, postResponse{
-		ID:        post.ID,
-		UserID:    post.UserID,
-		Title:     post.Title,
-		Content:   post.Content,
-		Type:      post.Type,
-		Status:    post.Status,
-		CreatedAt: post.CreatedAt,
-		UpdatedAt: post.UpdatedAt,
+		ID:          post.ID,
+		UserID:      post.UserID,
+		Title:       post.Title,
+		Slug:        post.Slug,
+		Content:     post.Content,
+		Format:      post.Format,
+		ContentHTML: post.ContentHtml.String,
+		TOC:         post.Toc,
+		Type:        post.Type,
+		Status:      post.Status,
+		PublishAt:   post.PublishAt,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
 	})
 }
 
@@ -114,7 +236,7 @@ func (server *Server) updatePost(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
@@ -122,22 +244,36 @@ func (server *Server) updatePost(ctx *gin.Context) {
 	post, err := server.store.GetPost(ctx, int32(id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			ctx.Error(errPostNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// Verify ownership
-	if post.Username.String != authPayload.Username {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "you can only update your own posts"})
+	// Verify ownership, editors and admins can edit any post
+	if post.Username.String != authPayload.Username && !util.CanEditAnyPost(authPayload.Role) {
+		ctx.Error(notOwnerError("you can only update your own posts"))
 		return
 	}
 
 	var req updatePostRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	status := post.Status.String
+	if req.Status != "" {
+		if !util.CanTransitionPostStatus(post.Status.String, req.Status) {
+			ctx.Error(newAPIError(http.StatusConflict, "post.invalid_status_transition", fmt.Sprintf("cannot change post status from %s to %s", post.Status.String, req.Status)))
+			return
+		}
+		status = req.Status
+	}
+	publishAt, err := resolvePublishAt(post.Status.String, status, req.PublishAt, post.PublishAt, time.Now())
+	if err != nil {
+		ctx.Error(newAPIError(http.StatusBadRequest, "post.invalid_publish_at", err.Error()))
 		return
 	}
 
@@ -150,97 +286,297 @@ func (server *Server) updatePost(ctx *gin.Context) {
 			String: req.Status,
 			Valid:  req.Status != "",
 		},
+		PublishAt: publishAt,
+		Format:    req.Format,
 	}
 
-	updatedPost, err := server.store.UpdatePost(ctx, arg)
+	// Without new content or format the store keeps the stored rendering
+	if req.Content != "" || req.Format != "" {
+		format, content := post.Format, post.Content
+		if req.Format != "" {
+			format = req.Format
+		}
+		if req.Content != "" {
+			content = req.Content
+		}
+		arg.ContentHtml, arg.Toc, err = renderPost(format, content)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+
+	// The editor is recorded on the revision that keeps the previous version
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	result, err := server.store.EditPostTx(ctx, db.EditPostTxParams{
+		UpdatePostParams: arg,
+		EditedBy:         user.ID,
+	})
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, updatedPost)
+	ctx.JSON(http.StatusOK, result.Post)
 }
 
 func (server *Server) getPost(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil || id <= 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
-	post, err := server.store.GetPost(ctx, int32(id))
+	server.respondWithPost(ctx, int32(id))
+}
+
+// getPostBySlug serves a post by its slug. Slugs a post had before its title
+// changed answer with a permanent redirect to the current one.
+func (server *Server) getPostBySlug(ctx *gin.Context) {
+	slug := ctx.Param("slug")
+
+	id, err := server.store.GetPostIDBySlug(ctx, slug)
+	if err == nil {
+		server.respondWithPost(ctx, id)
+		return
+	}
+	if err != sql.ErrNoRows {
+		ctx.Error(err)
+		return
+	}
+
+	redirect, err := server.store.GetPostSlugRedirect(ctx, slug)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			ctx.Error(errPostNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"id":         post.ID,
-		"user_id":    post.UserID.Int32,
-		"title":      post.Title,
-		"content":    post.Content,
-		"type":       post.Type,
-		"status":     post.Status.String,
-		"created_at": post.CreatedAt,
-		"updated_at": post.UpdatedAt,
-		"username":   post.Username.String,
-		"first_name": post.FirstName,
-		"last_name":  post.LastName,
-		"tags":       post.Tags,
-		"images":     post.Images,
-		"likes":      post.Likes,
-	})
+	post, err := server.store.GetPost(ctx, redirect.PostID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errPostNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// The new slug would give away the title of a post others cannot read
+	if !util.IsReadablePostStatus(post.Status.String) && !canSeeUnpublished(user, authenticated, post.UserID) {
+		ctx.Error(errPostNotFound)
+		return
+	}
+
+	location := path.Join(path.Dir(ctx.Request.URL.Path), post.Slug)
+	if query := ctx.Request.URL.RawQuery; query != "" {
+		location += "?" + query
+	}
+	ctx.Redirect(http.StatusMovedPermanently, location)
 }
 
-func (server *Server) listPosts(ctx *gin.Context) {
-	var limit int32 = 10 // default limit
-	var offset int32 = 0 // default offset
-	var status string = "published"
-	// Parse limit from query parameter
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		limitInt, err := strconv.ParseInt(limitStr, 10, 32)
-		if err == nil {
-			limit = int32(limitInt)
+// respondWithPost writes the post with the given id, with its images and
+// whether the reader liked it, or 404 when the reader may not see it
+func (server *Server) respondWithPost(ctx *gin.Context, id int32) {
+	post, err := server.store.GetPost(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errPostNotFound)
+			return
 		}
+		ctx.Error(err)
+		return
 	}
 
-	// Parse offset from query parameter
-	if offsetStr := ctx.Query("offset"); offsetStr != "" {
-		offsetInt, err := strconv.ParseInt(offsetStr, 10, 32)
-		if err == nil {
-			offset = int32(offsetInt)
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// Drafts, scheduled and archived posts do not exist for other readers
+	if !util.IsReadablePostStatus(post.Status.String) && !canSeeUnpublished(user, authenticated, post.UserID) {
+		ctx.Error(errPostNotFound)
+		return
+	}
+
+	// Posts are rendered when first read after their content changed without
+	// a rendering, like restored revisions and posts from before formats
+	if !post.ContentHtml.Valid {
+		post.ContentHtml, post.Toc, err = renderPost(post.Format, post.Content)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		err = server.store.UpdatePostRendering(ctx, db.UpdatePostRenderingParams{
+			ID:          post.ID,
+			ContentHtml: post.ContentHtml,
+			Toc:         post.Toc,
+			Content:     post.Content,
+			Format:      post.Format,
+		})
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+	}
+
+	images, err := server.listPostImageResponses(ctx, post.ID)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	response := gin.H{
+		"id":           post.ID,
+		"user_id":      post.UserID.Int32,
+		"title":        post.Title,
+		"slug":         post.Slug,
+		"content":      post.Content,
+		"format":       post.Format,
+		"content_html": post.ContentHtml.String,
+		"toc":          post.Toc,
+		"type":         post.Type,
+		"status":       post.Status.String,
+		"publish_at":   post.PublishAt,
+		"created_at":   post.CreatedAt,
+		"updated_at":   post.UpdatedAt,
+		"username":     post.Username.String,
+		"first_name":   post.FirstName,
+		"last_name":    post.LastName,
+		"tags":         post.Tags,
+		"images":       images,
+		"likes":        post.Likes,
+	}
+
+	// Tell authenticated readers whether they already liked the post
+	if authenticated {
+		_, err := server.store.GetPostLike(ctx, db.GetPostLikeParams{
+			UserID: user.ID,
+			PostID: post.ID,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			ctx.Error(err)
+			return
+		}
+		response["liked_by_me"] = err == nil
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// optionalCurrentUser returns the user behind the request on routes using
+// optionalAuthMiddleware. The bool is false for anonymous requests.
+func (server *Server) optionalCurrentUser(ctx *gin.Context) (db.User, bool, error) {
+	authPayload, err := server.getAuthPayload(ctx)
+	if err != nil {
+		return db.User{}, false, nil
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.User{}, false, nil
 		}
+		return db.User{}, false, err
+	}
+
+	return user, true, nil
+}
+
+type listPostsRequest struct {
+	cursorPageRequest
+	Status string `form:"status,default=published"`
+}
+
+func (server *Server) listPosts(ctx *gin.Context) {
+	var req listPostsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if !util.IsValidPostStatus(req.Status) {
+		ctx.Error(invalidParamError(fmt.Sprintf("invalid status %s", req.Status)))
+		return
+	}
+	after, err := decodePostCursor(req.Cursor, sortNewest)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
-	// Get status from query parameter
-	if statusStr := ctx.Query("status"); statusStr != "" {
-		status = statusStr
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
+	viewerID, allStatuses := postVisibility(user, authenticated)
 
+	// One post more than asked for tells whether there is a next page
 	arg := db.ListPostsParams{
-		Column1: status, // Pass the raw status string, it can be empty
-		Limit:   limit,
-		Offset:  offset,
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+		Limit:       req.Limit + 1,
+	}
+	if after != nil {
+		arg.AfterID = sql.NullInt32{Int32: after.ID, Valid: true}
+		arg.AfterCreatedAt = sql.NullTime{Time: *after.CreatedAt, Valid: true}
 	}
 	posts, err := server.store.ListPosts(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(posts) > int(req.Limit) {
+		posts = posts[:req.Limit]
+		last := posts[len(posts)-1]
+		nextCursor = newestPostCursor(last.ID, last.CreatedAt).encode()
+	}
+
+	total, err := server.countPosts(ctx, req.IncludeTotal, db.CountPostsParams{
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+	})
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
+
 	response := make([]gin.H, len(posts))
+	postIDs := make([]int32, len(posts))
 	for i, post := range posts {
 		response[i] = gin.H{
 			"id":            post.ID,
 			"user_id":       post.UserID,
 			"title":         post.Title,
+			"slug":          post.Slug,
 			"content":       post.Content,
 			"type":          post.Type,
 			"status":        post.Status.String,
+			"publish_at":    post.PublishAt,
 			"created_at":    post.CreatedAt,
 			"updated_at":    post.UpdatedAt,
 			"username":      post.Username,
@@ -248,208 +584,218 @@ func (server *Server) listPosts(ctx *gin.Context) {
 			"tags":          post.Tags,
 			"likes":         post.Likes,
 		}
+		postIDs[i] = post.ID
 	}
 
-	ctx.JSON(http.StatusOK, response)
-}
-
-func (server *Server) incrementPostLikes(ctx *gin.Context) {
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
-		return
-	}
-
-	post, err := server.store.IncrementPostLikes(ctx, int32(id))
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+	// Tell authenticated readers which of the listed posts they already liked
+	if authenticated && len(posts) > 0 {
+		likedIDs, err := server.store.ListLikedPostIDs(ctx, db.ListLikedPostIDsParams{
+			UserID:  user.ID,
+			Column2: postIDs,
+		})
+		if err != nil {
+			ctx.Error(err)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+
+		liked := make(map[int32]bool, len(likedIDs))
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
+		for i, post := range posts {
+			response[i]["liked_by_me"] = liked[post.ID]
+		}
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"id":    post.ID,
-		"likes": post.Likes,
-	})
+	writePage(ctx, response, nextCursor, total)
 }
 
-func (server *Server) decrementPostLikes(ctx *gin.Context) {
-	idStr := ctx.Param("id")
-	id, err := strconv.ParseInt(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
-		return
+// countPosts counts all the posts of a list when the client asked for the total
+func (server *Server) countPosts(ctx *gin.Context, include bool, arg db.CountPostsParams) (*int64, error) {
+	if !include {
+		return nil, nil
 	}
 
-	post, err := server.store.DecrementPostLikes(ctx, int32(id))
+	total, err := server.store.CountPosts(ctx, arg)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
+	return &total, nil
+}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"id":    post.ID,
-		"likes": post.Likes,
-	})
+func (server *Server) incrementPostLikes(ctx *gin.Context) {
+	server.changePostLike(ctx, server.store.LikePostTx)
 }
 
-func (server *Server) deletePost(ctx *gin.Context) {
-	// Get auth payload
+func (server *Server) decrementPostLikes(ctx *gin.Context) {
+	server.changePostLike(ctx, server.store.UnlikePostTx)
+}
+
+// changePostLike likes or unlikes a post on behalf of the authenticated user.
+// Both operations are idempotent, so repeated requests never skew the counter.
+func (server *Server) changePostLike(ctx *gin.Context, likeTx func(context.Context, db.PostLikeTxParams) (db.PostLikeTxResult, error)) {
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
 
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
-	// Verify post exists and belongs to user
-	post, err := server.store.GetPost(ctx, int32(id))
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			ctx.Error(errTokenUserGone)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// Verify ownership
-	if post.Username.String != authPayload.Username {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "you can only delete your own posts"})
+	if _, err := server.getReadablePost(ctx, int32(id), user, true); err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	err = server.store.DeletePost(ctx, int32(id))
+	result, err := likeTx(ctx, db.PostLikeTxParams{
+		UserID: user.ID,
+		PostID: int32(id),
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if err == sql.ErrNoRows {
+			ctx.Error(errPostNotFound)
+			return
+		}
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "post deleted successfully"})
+	ctx.JSON(http.StatusOK, gin.H{
+		"id":          result.Post.ID,
+		"likes":       result.Post.Likes,
+		"liked_by_me": result.LikedByMe,
+	})
 }
 
-func (server *Server) addImage(ctx *gin.Context) {
+// listPostLikers handles retrieving the users who liked a post, most recent first
+func (server *Server) listPostLikers(ctx *gin.Context) {
 	idStr := ctx.Param("id")
-	postID, err := strconv.ParseInt(idStr, 10, 32)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || id <= 0 {
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
-	var req addImageRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+	var limit int32 = 10 // default limit
+	var offset int32 = 0 // default offset
+
+	// Parse limit from query parameter
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limitInt, err := strconv.ParseInt(limitStr, 10, 32)
+		if err == nil && limitInt > 0 {
+			limit = int32(limitInt)
+		}
 	}
 
-	// First create the image
-	imageArg := db.CreateImageParams{
-		UserID: sql.NullInt32{
-			Int32: req.UserID,
-			Valid: true,
-		},
-		FilePath: req.FilePath,
-		AltText: sql.NullString{
-			String: req.AltText,
-			Valid:  req.AltText != "",
-		},
+	// Parse offset from query parameter
+	if offsetStr := ctx.Query("offset"); offsetStr != "" {
+		offsetInt, err := strconv.ParseInt(offsetStr, 10, 32)
+		if err == nil && offsetInt >= 0 {
+			offset = int32(offsetInt)
+		}
 	}
 
-	image, err := server.store.CreateImage(ctx, imageArg)
+	user, authenticated, err := server.optionalCurrentUser(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// Then associate it with the post
-	err = server.store.AddPostImage(ctx, db.AddPostImageParams{
-		PostID:  int32(postID),
-		ImageID: image.ID,
-		DisplayOrder: sql.NullInt32{
-			Int32: 1,
-			Valid: true,
-		},
+	if _, err := server.getReadablePost(ctx, int32(id), user, authenticated); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	likers, err := server.store.ListPostLikers(ctx, db.ListPostLikersParams{
+		PostID: int32(id),
+		Limit:  limit,
+		Offset: offset,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, image)
+	response := make([]gin.H, len(likers))
+	for i, liker := range likers {
+		response[i] = gin.H{
+			"id":         liker.ID,
+			"username":   liker.Username,
+			"first_name": liker.FirstName,
+			"last_name":  liker.LastName,
+			"liked_at":   liker.LikedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
 }
 
-func (server *Server) deleteImage(ctx *gin.Context) {
+func (server *Server) deletePost(ctx *gin.Context) {
 	// Get auth payload
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
 
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid image id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
-	// Get the image to verify ownership
-	image, err := server.store.GetImage(ctx, int32(id))
+	// Verify post exists and belongs to user
+	post, err := server.store.GetPost(ctx, int32(id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "image not found"})
+			ctx.Error(errPostNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Get user by username
-	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// Verify ownership
-	if image.UserID.Int32 != user.ID {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "you can only delete your own images"})
+	// Verify ownership, admins can delete any post
+	if post.Username.String != authPayload.Username && !util.CanDeleteAnyContent(authPayload.Role) {
+		ctx.Error(notOwnerError("you can only delete your own posts"))
 		return
 	}
 
-	err = server.store.DeleteImage(ctx, int32(id))
+	err = server.store.DeletePost(ctx, int32(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"message": "image deleted successfully"})
+	ctx.JSON(http.StatusOK, gin.H{"message": "post deleted successfully"})
 }
 
 func (server *Server) addTag(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	postID, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		ctx.Error(invalidParamError("invalid post id"))
 		return
 	}
 
 	var req addTagRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	// First create or get the tag
 	tag, err := server.store.CreateTag(ctx, req.Name)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -459,7 +805,7 @@ func (server *Server) addTag(ctx *gin.Context) {
 		TagID:  tag.ID,
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -473,53 +819,56 @@ func (server *Server) deleteTag(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		ctx.Error(invalidParamError("invalid tag id"))
 		return
 	}
 
-	// Get user by username
-	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
+	// Admins can delete any tag, everyone else only tags used in their own posts
+	if !util.CanDeleteAnyContent(authPayload.Role) {
+		// Get user by username
+		user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.Error(errTokenUserGone)
+				return
+			}
+			ctx.Error(err)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
 
-	// Get all posts that use this tag
-	posts, err := server.store.GetPostsByTagID(ctx, int32(id))
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
+		// Get all posts that use this tag
+		posts, err := server.store.GetPostsByTagID(ctx, int32(id))
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
 
-	// Check if user owns any posts with this tag
-	hasAccess := false
-	for _, post := range posts {
-		if post.UserID.Int32 == user.ID {
-			hasAccess = true
-			break
+		// Check if user owns any posts with this tag
+		hasAccess := false
+		for _, post := range posts {
+			if post.UserID.Int32 == user.ID {
+				hasAccess = true
+				break
+			}
 		}
-	}
 
-	if !hasAccess {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "you can only delete tags used in your own posts"})
-		return
+		if !hasAccess {
+			ctx.Error(notOwnerError("you can only delete tags used in your own posts"))
+			return
+		}
 	}
 
 	// First remove the tag from all posts
 	err = server.store.DeleteTagFromPosts(ctx, int32(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	// Then delete the tag itself
 	err = server.store.DeleteTag(ctx, int32(id))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -533,7 +882,7 @@ func (server *Server) removeTagFromPost(ctx *gin.Context) {
 	postIDStr := ctx.Param("id")
 	postID, err := strconv.ParseInt(postIDStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
+		ctx.Error(invalidParamError("invalid post id"))
 		return
 	}
 
@@ -541,23 +890,23 @@ func (server *Server) removeTagFromPost(ctx *gin.Context) {
 	post, err := server.store.GetPost(ctx, int32(postID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+			ctx.Error(errPostNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// Verify ownership
-	if post.Username.String != authPayload.Username {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "you can only modify your own posts"})
+	// Verify ownership, editors and admins can edit any post
+	if post.Username.String != authPayload.Username && !util.CanEditAnyPost(authPayload.Role) {
+		ctx.Error(notOwnerError("you can only modify your own posts"))
 		return
 	}
 
 	tagIDStr := ctx.Param("tagId")
 	tagID, err := strconv.ParseInt(tagIDStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		ctx.Error(invalidParamError("invalid tag id"))
 		return
 	}
 
@@ -566,30 +915,79 @@ func (server *Server) removeTagFromPost(ctx *gin.Context) {
 		TagID:  int32(tagID),
 	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "tag removed from post successfully"})
 }
 
+// getPostByTagID handles retrieving the posts with a tag, newest first
 func (server *Server) getPostByTagID(ctx *gin.Context) {
 	// Parse tag ID from URL parameter
 	tagIDStr := ctx.Param("tagId")
 	tagID, err := strconv.ParseInt(tagIDStr, 10, 32)
+	if err != nil || tagID <= 0 {
+		ctx.Error(invalidParamError("invalid tag id"))
+		return
+	}
+
+	var req listPostsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	if !util.IsValidPostStatus(req.Status) {
+		ctx.Error(invalidParamError(fmt.Sprintf("invalid status %s", req.Status)))
+		return
+	}
+	after, err := decodePostCursor(req.Cursor, sortNewest)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid tag id"})
+		ctx.Error(err)
 		return
 	}
 
-	// Get posts by tag ID
-	posts, err := server.store.GetPostsByTagID(ctx, int32(tagID))
+	user, authenticated, err := server.optionalCurrentUser(ctx)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "no posts found with this tag"})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+	viewerID, allStatuses := postVisibility(user, authenticated)
+
+	// Get posts by tag ID after the cursor, leaving out posts the reader may
+	// not list. One more than asked for tells whether there is a next page.
+	arg := db.ListPostsParams{
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+		TagID:       sql.NullInt32{Int32: int32(tagID), Valid: true},
+		Limit:       req.Limit + 1,
+	}
+	if after != nil {
+		arg.AfterID = sql.NullInt32{Int32: after.ID, Valid: true}
+		arg.AfterCreatedAt = sql.NullTime{Time: *after.CreatedAt, Valid: true}
+	}
+	posts, err := server.store.ListPosts(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(posts) > int(req.Limit) {
+		posts = posts[:req.Limit]
+		last := posts[len(posts)-1]
+		nextCursor = newestPostCursor(last.ID, last.CreatedAt).encode()
+	}
+
+	total, err := server.countPosts(ctx, req.IncludeTotal, db.CountPostsParams{
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+		TagID:       arg.TagID,
+	})
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -597,19 +995,24 @@ func (server *Server) getPostByTagID(ctx *gin.Context) {
 	response := make([]gin.H, len(posts))
 	for i, post := range posts {
 		response[i] = gin.H{
-			"id":         post.ID,
-			"user_id":    post.UserID,
-			"title":      post.Title,
-			"content":    post.Content,
-			"type":       post.Type,
-			"status":     post.Status.String,
-			"created_at": post.CreatedAt,
-			"updated_at": post.UpdatedAt,
-			"username":   post.Username,
+			"id":            post.ID,
+			"user_id":       post.UserID,
+			"title":         post.Title,
+			"slug":          post.Slug,
+			"content":       post.Content,
+			"type":          post.Type,
+			"status":        post.Status.String,
+			"publish_at":    post.PublishAt,
+			"created_at":    post.CreatedAt,
+			"updated_at":    post.UpdatedAt,
+			"username":      post.Username,
+			"comment_count": post.CommentCount,
+			"tags":          post.Tags,
+			"likes":         post.Likes,
 		}
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	writePage(ctx, response, nextCursor, total)
 }
 
 // type listPostsByUserParams struct {
@@ -622,64 +1025,84 @@ func (server *Server) getPostByTagID(ctx *gin.Context) {
 // listPostsByUser handles retrieving all posts for a specific user
 func (server *Server) listPostsByUser(ctx *gin.Context) {
 	// Get user ID from URL
-	userIDStr := ctx.Param("userId")
+	userIDStr := ctx.Param("id")
 	userID, err := strconv.ParseInt(userIDStr, 10, 32)
 	if err != nil || userID <= 0 {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		ctx.Error(invalidParamError("invalid user id"))
 		return
 	}
 
-	// Set up default values
-	var limit int32 = 10            // default limit
-	var offset int32 = 0            // default offset
-	var status string = "published" // default status
-
-	// Parse limit from query parameter
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		limitInt, err := strconv.ParseInt(limitStr, 10, 32)
-		if err == nil && limitInt > 0 {
-			limit = int32(limitInt)
-		}
+	var req listPostsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
 	}
-
-	// Parse offset from query parameter
-	if offsetStr := ctx.Query("offset"); offsetStr != "" {
-		offsetInt, err := strconv.ParseInt(offsetStr, 10, 32)
-		if err == nil && offsetInt >= 0 {
-			offset = int32(offsetInt)
-		}
+	if !util.IsValidPostStatus(req.Status) {
+		ctx.Error(invalidParamError(fmt.Sprintf("invalid status %s", req.Status)))
+		return
+	}
+	after, err := decodePostCursor(req.Cursor, sortNewest)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
-	// Get status from query parameter
-	if statusStr := ctx.Query("status"); statusStr != "" {
-		status = statusStr
+	viewer, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
+	viewerID, allStatuses := postVisibility(viewer, authenticated)
 
 	// Check if the user exists first
 	user, err := server.store.GetUser(ctx, int32(userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
-	// Fetch posts by user ID with pagination and status filter
+	// Fetch posts by user ID after the cursor with status filter, one more
+	// than asked for tells whether there is a next page
 	arg := db.ListPostsByUserParams{
 		UserID: sql.NullInt32{
 			Int32: user.ID,
 			Valid: true,
 		},
-		Column2: status,
-		Limit:   limit,
-		Offset:  offset,
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+		Limit:       req.Limit + 1,
+	}
+	if after != nil {
+		arg.AfterID = sql.NullInt32{Int32: after.ID, Valid: true}
+		arg.AfterCreatedAt = sql.NullTime{Time: *after.CreatedAt, Valid: true}
 	}
 
 	posts, err := server.store.ListPostsByUser(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(posts) > int(req.Limit) {
+		posts = posts[:req.Limit]
+		last := posts[len(posts)-1]
+		nextCursor = newestPostCursor(last.ID, last.CreatedAt).encode()
+	}
+
+	total, err := server.countPosts(ctx, req.IncludeTotal, db.CountPostsParams{
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+		AuthorID:    arg.UserID,
+	})
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -689,9 +1112,11 @@ func (server *Server) listPostsByUser(ctx *gin.Context) {
 		response[i] = gin.H{
 			"id":            post.ID,
 			"title":         post.Title,
+			"slug":          post.Slug,
 			"content":       post.Content,
 			"type":          post.Type,
 			"status":        post.Status.String,
+			"publish_at":    post.PublishAt,
 			"created_at":    post.CreatedAt,
 			"updated_at":    post.UpdatedAt,
 			"username":      post.Username,
@@ -701,44 +1126,64 @@ func (server *Server) listPostsByUser(ctx *gin.Context) {
 		}
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	writePage(ctx, response, nextCursor, total)
 }
 
 func (server *Server) listPostsByLikes(ctx *gin.Context) {
-	var limit int32 = 10            // default limit
-	var offset int32 = 0            // default offset
-	var status string = "published" // default status
-
-	// Parse limit from query parameter
-	if limitStr := ctx.Query("limit"); limitStr != "" {
-		limitInt, err := strconv.ParseInt(limitStr, 10, 32)
-		if err == nil && limitInt > 0 {
-			limit = int32(limitInt)
-		}
+	var req listPostsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
 	}
-
-	// Parse offset from query parameter
-	if offsetStr := ctx.Query("offset"); offsetStr != "" {
-		offsetInt, err := strconv.ParseInt(offsetStr, 10, 32)
-		if err == nil && offsetInt >= 0 {
-			offset = int32(offsetInt)
-		}
+	if !util.IsValidPostStatus(req.Status) {
+		ctx.Error(invalidParamError(fmt.Sprintf("invalid status %s", req.Status)))
+		return
+	}
+	after, err := decodePostCursor(req.Cursor, sortMostLiked)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
 
-	// Get status from query parameter
-	if statusStr := ctx.Query("status"); statusStr != "" {
-		status = statusStr
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
+	viewerID, allStatuses := postVisibility(user, authenticated)
 
+	// One post more than asked for tells whether there is a next page
 	arg := db.ListPostsOrderByLikesParams{
-		Column1: status,
-		Limit:   limit,
-		Offset:  offset,
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+		Limit:       req.Limit + 1,
+	}
+	if after != nil {
+		arg.AfterID = sql.NullInt32{Int32: after.ID, Valid: true}
+		arg.AfterLikes = sql.NullInt32{Int32: after.Likes, Valid: true}
 	}
 
 	posts, err := server.store.ListPostsOrderByLikes(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(posts) > int(req.Limit) {
+		posts = posts[:req.Limit]
+		last := posts[len(posts)-1]
+		nextCursor = postCursor{Sort: sortMostLiked, ID: last.ID, Likes: last.Likes}.encode()
+	}
+
+	total, err := server.countPosts(ctx, req.IncludeTotal, db.CountPostsParams{
+		Status:      req.Status,
+		AllStatuses: allStatuses,
+		ViewerID:    viewerID,
+	})
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
@@ -748,9 +1193,11 @@ func (server *Server) listPostsByLikes(ctx *gin.Context) {
 			"id":            post.ID,
 			"user_id":       post.UserID,
 			"title":         post.Title,
+			"slug":          post.Slug,
 			"content":       post.Content,
 			"type":          post.Type,
 			"status":        post.Status.String,
+			"publish_at":    post.PublishAt,
 			"created_at":    post.CreatedAt,
 			"updated_at":    post.UpdatedAt,
 			"username":      post.Username,
@@ -760,7 +1207,7 @@ func (server *Server) listPostsByLikes(ctx *gin.Context) {
 		}
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	writePage(ctx, response, nextCursor, total)
 }
 
 // listUsersByPostLikes handles retrieving users ordered by their total post likes
@@ -791,7 +1238,7 @@ func (server *Server) listUsersByPostLikes(ctx *gin.Context) {
 
 	users, err := server.store.ListUsersOrderByPostLikes(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -815,12 +1262,82 @@ func (server *Server) listUsersByPostLikes(ctx *gin.Context) {
 }
 
 type PostFilter struct {
-	UserID    *int32  `form:"user_id"`
-	Status    *string `form:"status"`
-	SortBy    string  `form:"sort_by"`    // Possible values: "created_at", "likes", "title"
-	SortOrder string  `form:"sort_order"` // Possible values: "asc", "desc"
-	Limit     int32   `form:"limit,default=10"`
-	Offset    int32   `form:"offset,default=0"`
+	UserID        *int32   `form:"user_id"`
+	Status        *string  `form:"status"`
+	Type          *string  `form:"type"`
+	Author        *string  `form:"author"`         // author username
+	Tags          []string `form:"tags"`           // repeated or comma separated tag names
+	TagMatch      string   `form:"tag_match"`      // Possible values: "any", "all"
+	CreatedAfter  string   `form:"created_after"`  // RFC 3339 timestamp or YYYY-MM-DD date
+	CreatedBefore string   `form:"created_before"` // RFC 3339 timestamp or YYYY-MM-DD date
+	MinLikes      *int32   `form:"min_likes"`
+	SortBy        string   `form:"sort_by"`    // Possible values: "created_at", "likes", "title"
+	SortOrder     string   `form:"sort_order"` // Possible values: "asc", "desc"
+	cursorPageRequest
+}
+
+// ValidateFilterParams checks the filter values and converts them to store parameters
+func (f *PostFilter) ValidateFilterParams() (db.FilterParams, error) {
+	if err := f.ValidateSortParams(); err != nil {
+		return db.FilterParams{}, err
+	}
+
+	params := db.FilterParams{
+		UserID:    f.UserID,
+		Status:    f.Status,
+		Type:      f.Type,
+		Username:  f.Author,
+		MinLikes:  f.MinLikes,
+		SortBy:    f.SortBy,
+		SortOrder: f.SortOrder,
+		Limit:     f.Limit,
+	}
+
+	for _, tags := range f.Tags {
+		params.Tags = append(params.Tags, strings.Split(tags, ",")...)
+	}
+
+	// Validate tag_match
+	params.TagMatch = strings.ToLower(f.TagMatch)
+	if params.TagMatch == "" {
+		params.TagMatch = "any"
+	}
+	if params.TagMatch != "any" && params.TagMatch != "all" {
+		return db.FilterParams{}, fmt.Errorf("invalid tag_match parameter: %s", f.TagMatch)
+	}
+
+	// Validate date range
+	if f.CreatedAfter != "" {
+		createdAfter, err := parseFilterTime(f.CreatedAfter)
+		if err != nil {
+			return db.FilterParams{}, fmt.Errorf("invalid created_after parameter: %s", f.CreatedAfter)
+		}
+		params.CreatedAfter = &createdAfter
+	}
+	if f.CreatedBefore != "" {
+		createdBefore, err := parseFilterTime(f.CreatedBefore)
+		if err != nil {
+			return db.FilterParams{}, fmt.Errorf("invalid created_before parameter: %s", f.CreatedBefore)
+		}
+		params.CreatedBefore = &createdBefore
+	}
+	if params.CreatedAfter != nil && params.CreatedBefore != nil && !params.CreatedAfter.Before(*params.CreatedBefore) {
+		return db.FilterParams{}, fmt.Errorf("created_after must be before created_before")
+	}
+
+	if f.MinLikes != nil && *f.MinLikes < 0 {
+		return db.FilterParams{}, fmt.Errorf("invalid min_likes parameter: %d", *f.MinLikes)
+	}
+
+	return params, nil
+}
+
+// parseFilterTime accepts either a full RFC 3339 timestamp or a plain date
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
 }
 
 func (f *PostFilter) ValidateSortParams() error {
@@ -848,47 +1365,93 @@ func (f *PostFilter) ValidateSortParams() error {
 		return fmt.Errorf("invalid sort_order parameter: %s", f.SortOrder)
 	}
 
-	// Validate limit and offset
+	// Validate limit
 	if f.Limit <= 0 {
 		f.Limit = 10
 	}
-	if f.Offset < 0 {
-		f.Offset = 0
-	}
 
 	return nil
 }
 
+// sortKey names the order of the filtered posts, which their cursors belong to
+func (f *PostFilter) sortKey() string {
+	return f.SortBy + ":" + f.SortOrder
+}
+
 // FilterPosts handles retrieving posts with filtering and sorting
 func (server *Server) FilterPosts(ctx *gin.Context) {
 	var filter PostFilter
 	if err := ctx.ShouldBindQuery(&filter); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
-	if err := filter.ValidateSortParams(); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Convert PostFilter to FilterParams
+	params, err := filter.ValidateFilterParams()
+	if err != nil {
+		ctx.Error(invalidParamError(err.Error()))
 		return
 	}
+	after, err := decodePostCursor(filter.Cursor, filter.sortKey())
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if after != nil {
+		params.After = &db.FilterCursor{ID: after.ID, Likes: after.Likes, Title: after.Title}
+		if after.CreatedAt != nil {
+			params.After.CreatedAt = *after.CreatedAt
+		}
+	}
 
-	// Convert PostFilter to FilterParams
-	params := db.FilterParams{
-		UserID:    filter.UserID,
-		Status:    filter.Status,
-		SortBy:    filter.SortBy,
-		SortOrder: filter.SortOrder,
-		Limit:     filter.Limit,
-		Offset:    filter.Offset,
+	// Posts that are not published are only listed to their author and editors
+	user, authenticated, err := server.optionalCurrentUser(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
 	}
+	viewerID, allStatuses := postVisibility(user, authenticated)
+	if viewerID.Valid {
+		params.ViewerID = &viewerID.Int32
+	}
+	params.AllStatuses = allStatuses
 
-	// Use the store interface to filter posts
+	// Use the store interface to filter posts, one more than asked for tells
+	// whether there is a next page
+	params.Limit++
 	posts, err := server.store.FilterPosts(ctx, params)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
+	var nextCursor string
+	if len(posts) > int(filter.Limit) {
+		posts = posts[:filter.Limit]
+		last := posts[len(posts)-1]
+		cursor := postCursor{Sort: filter.sortKey(), ID: last.ID}
+		switch filter.SortBy {
+		case "likes":
+			cursor.Likes = last.Likes
+		case "title":
+			cursor.Title = last.Title
+		default:
+			cursor.CreatedAt = &last.CreatedAt.Time
+		}
+		nextCursor = cursor.encode()
+	}
+
+	var total *int64
+	if filter.IncludeTotal {
+		// The count ignores the cursor and limit
+		count, err := server.store.CountFilteredPosts(ctx, params)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		total = &count
+	}
+
 	// Convert to response format
 	response := make([]gin.H, len(posts))
 	for i, post := range posts {
@@ -896,9 +1459,11 @@ func (server *Server) FilterPosts(ctx *gin.Context) {
 			"id":            post.ID,
 			"user_id":       post.UserID.Int32,
 			"title":         post.Title,
+			"slug":          post.Slug,
 			"content":       post.Content,
 			"type":          post.Type,
 			"status":        post.Status.String,
+			"publish_at":    post.PublishAt,
 			"created_at":    post.CreatedAt,
 			"updated_at":    post.UpdatedAt,
 			"likes":         post.Likes,
@@ -908,5 +1473,83 @@ func (server *Server) FilterPosts(ctx *gin.Context) {
 		}
 	}
 
-	ctx.JSON(http.StatusOK, response)
+	writePage(ctx, response, nextCursor, total)
+}
+
+type searchPostsRequest struct {
+	cursorPageRequest
+	Q string `form:"q"`
+}
+
+// searchPosts handles full-text search over published posts. Quoted phrases
+// and prefix* words are supported, see db.BuildTSQuery.
+func (server *Server) searchPosts(ctx *gin.Context) {
+	var req searchPostsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+	tsQuery := db.BuildTSQuery(req.Q)
+	if tsQuery == "" {
+		ctx.Error(invalidParamError("search query q is required"))
+		return
+	}
+	after, err := decodePostCursor(req.Cursor, sortRelevance)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	// One match more than asked for tells whether there is a next page
+	arg := db.SearchPostsParams{
+		TSQuery: tsQuery,
+		Limit:   req.Limit + 1,
+	}
+	if after != nil {
+		arg.After = &db.SearchCursor{ID: after.ID, Rank: after.Rank, CreatedAt: *after.CreatedAt}
+	}
+	posts, err := server.store.SearchPosts(ctx, arg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	var nextCursor string
+	if len(posts) > int(req.Limit) {
+		posts = posts[:req.Limit]
+		last := posts[len(posts)-1]
+		nextCursor = postCursor{Sort: sortRelevance, ID: last.ID, Rank: last.Rank, CreatedAt: &last.CreatedAt.Time}.encode()
+	}
+
+	var total *int64
+	if req.IncludeTotal {
+		// The count ignores the cursor and limit
+		count, err := server.store.CountSearchPosts(ctx, tsQuery)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		total = &count
+	}
+
+	response := make([]gin.H, len(posts))
+	for i, post := range posts {
+		response[i] = gin.H{
+			"id":              post.ID,
+			"user_id":         post.UserID.Int32,
+			"title":           post.Title,
+			"slug":            post.Slug,
+			"type":            post.Type,
+			"status":          post.Status.String,
+			"created_at":      post.CreatedAt,
+			"updated_at":      post.UpdatedAt,
+			"likes":           post.Likes,
+			"username":        post.Username.String,
+			"rank":            post.Rank,
+			"title_highlight": post.TitleHighlight,
+			"snippet":         post.Snippet,
+		}
+	}
+
+	writePage(ctx, response, nextCursor, total)
 }
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type postRevisionResponse struct {
+	Revision         int32          `json:"revision"`
+	Title            string         `json:"title"`
+	Content          string         `json:"content"`
+	Type             string         `json:"type"`
+	Format           string         `json:"format"`
+	EditedBy         sql.NullInt32  `json:"edited_by"`
+	EditedByUsername sql.NullString `json:"edited_by_username"`
+	CreatedAt        sql.NullTime   `json:"created_at"`
+}
+
+type postRevisionDiffResponse struct {
+	From      int32  `json:"from"`
+	To        int32  `json:"to"` // zero for the current version
+	FromTitle string `json:"from_title"`
+	ToTitle   string `json:"to_title"`
+	Diff      string `json:"diff"` // unified diff of the content, empty when it did not change
+}
+
+// listPostRevisions returns the earlier versions of a post, newest first. Each
+// revision is the post as it was before the edit that replaced it.
+func (server *Server) listPostRevisions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || postID <= 0 {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	var limit int32 = 10 // default limit
+	var offset int32 = 0 // default offset
+
+	// Parse limit from query parameter
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limitInt, err := strconv.ParseInt(limitStr, 10, 32)
+		if err == nil && limitInt > 0 {
+			limit = int32(limitInt)
+		}
+	}
+
+	// Parse offset from query parameter
+	if offsetStr := ctx.Query("offset"); offsetStr != "" {
+		offsetInt, err := strconv.ParseInt(offsetStr, 10, 32)
+		if err == nil && offsetInt >= 0 {
+			offset = int32(offsetInt)
+		}
+	}
+
+	if _, ok := server.getEditablePost(ctx, int32(postID), authPayload, "you can only view revisions of your own posts"); !ok {
+		return
+	}
+
+	revisions, err := server.store.ListPostRevisions(ctx, db.ListPostRevisionsParams{
+		PostID: int32(postID),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	response := make([]postRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		response[i] = postRevisionResponse{
+			Revision:         revision.Revision,
+			Title:            revision.Title,
+			Content:          revision.Content,
+			Type:             revision.Type,
+			Format:           revision.Format,
+			EditedBy:         revision.EditedBy,
+			EditedByUsername: revision.EditedByUsername,
+			CreatedAt:        revision.CreatedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, response)
+}
+
+// diffPostRevisions compares the content of two versions of a post. from and
+// to are revision numbers, leaving out to compares with the current version.
+func (server *Server) diffPostRevisions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || postID <= 0 {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	from, err := strconv.ParseInt(ctx.Query("from"), 10, 32)
+	if err != nil || from <= 0 {
+		ctx.Error(invalidParamError("invalid from revision"))
+		return
+	}
+	var to int64
+	if toStr := ctx.Query("to"); toStr != "" {
+		to, err = strconv.ParseInt(toStr, 10, 32)
+		if err != nil || to <= 0 {
+			ctx.Error(invalidParamError("invalid to revision"))
+			return
+		}
+	}
+
+	post, ok := server.getEditablePost(ctx, int32(postID), authPayload, "you can only view revisions of your own posts")
+	if !ok {
+		return
+	}
+
+	fromRevision, ok := server.getPostRevision(ctx, post.ID, int32(from))
+	if !ok {
+		return
+	}
+	toTitle, toContent, toName := post.Title, post.Content, "current"
+	if to != 0 {
+		toRevision, ok := server.getPostRevision(ctx, post.ID, int32(to))
+		if !ok {
+			return
+		}
+		toTitle, toContent, toName = toRevision.Title, toRevision.Content, fmt.Sprintf("revision %d", to)
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(fromRevision.Content),
+		B:        difflib.SplitLines(toContent),
+		FromFile: fmt.Sprintf("revision %d", from),
+		ToFile:   toName,
+		Context:  3,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, postRevisionDiffResponse{
+		From:      int32(from),
+		To:        int32(to),
+		FromTitle: fromRevision.Title,
+		ToTitle:   toTitle,
+		Diff:      diff,
+	})
+}
+
+// restorePostRevision brings back an earlier version of a post. The version it
+// replaces is kept as a new revision, so restoring can be undone.
+func (server *Server) restorePostRevision(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	postID, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil || postID <= 0 {
+		ctx.Error(invalidParamError("invalid post id"))
+		return
+	}
+
+	revStr := ctx.Param("rev")
+	rev, err := strconv.ParseInt(revStr, 10, 32)
+	if err != nil || rev <= 0 {
+		ctx.Error(invalidParamError("invalid revision"))
+		return
+	}
+
+	if _, ok := server.getEditablePost(ctx, int32(postID), authPayload, "you can only restore revisions of your own posts"); !ok {
+		return
+	}
+
+	user, err := server.store.GetUserByUsername(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	result, err := server.store.RestorePostRevisionTx(ctx, db.RestorePostRevisionTxParams{
+		PostID:   int32(postID),
+		Revision: int32(rev),
+		EditedBy: user.ID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errRevisionNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result.Post)
+}
+
+// getPostRevision loads a revision of a post and answers with 404 when the
+// post has no such revision. The bool is false once a response was written.
+func (server *Server) getPostRevision(ctx *gin.Context, postID, revision int32) (db.PostRevision, bool) {
+	postRevision, err := server.store.GetPostRevision(ctx, db.GetPostRevisionParams{
+		PostID:   postID,
+		Revision: revision,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(newAPIError(http.StatusNotFound, "revision.not_found", fmt.Sprintf("revision %d not found", revision)))
+			return postRevision, false
+		}
+		ctx.Error(err)
+		return postRevision, false
+	}
+
+	return postRevision, true
+}
@@ -0,0 +1,337 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPostRevisions(t *testing.T) {
+	post := db.GetPostRow{
+		ID:       1,
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			query:    "?limit=5&offset=5",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().
+					ListPostRevisions(gomock.Any(), gomock.Eq(db.ListPostRevisionsParams{
+						PostID: 1,
+						Limit:  5,
+						Offset: 5,
+					})).
+					Times(1).
+					Return([]db.ListPostRevisionsRow{
+						{Revision: 2, Title: "second", EditedByUsername: sql.NullString{String: "testuser1", Valid: true}},
+						{Revision: 1, Title: "first"},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []postRevisionResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 2)
+				require.Equal(t, int32(2), got[0].Revision)
+				require.Equal(t, "testuser1", got[0].EditedByUsername.String)
+				require.Equal(t, int32(1), got[1].Revision)
+			},
+		},
+		{
+			name:     "NotOwner",
+			username: "testuser2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().ListPostRevisions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "PostNotFound",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(db.GetPostRow{}, sql.ErrNoRows)
+				store.EXPECT().ListPostRevisions(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/v1/posts/1/revisions"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, tc.username))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDiffPostRevisions(t *testing.T) {
+	post := db.GetPostRow{
+		ID:       1,
+		Title:    "current title",
+		Content:  "one\ntwo\nthree",
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+	first := db.PostRevision{PostID: 1, Revision: 1, Title: "first title", Content: "one\nthree"}
+	second := db.PostRevision{PostID: 1, Revision: 2, Title: "second title", Content: "one\ntwo\nthree"}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "AgainstCurrent",
+			query: "?from=1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: 1, Revision: 1})).
+					Times(1).
+					Return(first, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got postRevisionDiffResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, int32(1), got.From)
+				require.Equal(t, int32(0), got.To)
+				require.Equal(t, "first title", got.FromTitle)
+				require.Equal(t, "current title", got.ToTitle)
+				require.Contains(t, got.Diff, "--- revision 1")
+				require.Contains(t, got.Diff, "+++ current")
+				require.Contains(t, got.Diff, "\n+two\n")
+			},
+		},
+		{
+			name:  "BetweenRevisions",
+			query: "?from=2&to=1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: 1, Revision: 2})).
+					Times(1).
+					Return(second, nil)
+				store.EXPECT().
+					GetPostRevision(gomock.Any(), gomock.Eq(db.GetPostRevisionParams{PostID: 1, Revision: 1})).
+					Times(1).
+					Return(first, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got postRevisionDiffResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "second title", got.FromTitle)
+				require.Equal(t, "first title", got.ToTitle)
+				require.Contains(t, got.Diff, "\n-two\n")
+			},
+		},
+		{
+			name:  "Unchanged",
+			query: "?from=2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetPostRevision(gomock.Any(), gomock.Any()).Times(1).Return(second, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got postRevisionDiffResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Empty(t, got.Diff)
+			},
+		},
+		{
+			name:  "RevisionNotFound",
+			query: "?from=7",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetPostRevision(gomock.Any(), gomock.Any()).Times(1).Return(db.PostRevision{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "MissingFrom",
+			query: "?to=1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/v1/posts/1/revisions/diff"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, "testuser1"))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRestorePostRevision(t *testing.T) {
+	post := db.GetPostRow{
+		ID:       1,
+		Username: sql.NullString{String: "testuser1", Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			url:      "/api/v1/posts/1/revisions/2/restore",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					RestorePostRevisionTx(gomock.Any(), gomock.Eq(db.RestorePostRevisionTxParams{
+						PostID:   1,
+						Revision: 2,
+						EditedBy: 1,
+					})).
+					Times(1).
+					Return(db.EditPostTxResult{
+						Post:     db.Post{ID: 1, Title: "restored"},
+						Revision: &db.PostRevision{PostID: 1, Revision: 3},
+					}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.Post
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "restored", got.Title)
+			},
+		},
+		{
+			name:     "RevisionNotFound",
+			url:      "/api/v1/posts/1/revisions/9/restore",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Any()).Times(1).Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					RestorePostRevisionTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EditPostTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "NotOwner",
+			url:      "/api/v1/posts/1/revisions/2/restore",
+			username: "testuser2",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(1).Return(post, nil)
+				store.EXPECT().RestorePostRevisionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "InvalidRevision",
+			url:      "/api/v1/posts/1/revisions/0/restore",
+			username: "testuser1",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetPost(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RestorePostRevisionTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodPost, tc.url, nil)
+			require.NoError(t, err)
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, tc.username))
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/storage"
 	"github.com/haotianxu2021/newPortfolio/util"
 )
 
@@ -32,6 +34,8 @@ type Server struct {
 	router     *gin.Engine
 	httpServer *http.Server
 	tokenMaker util.TokenMaker
+	mailer     util.Mailer
+	blobStore  storage.BlobStore
 	config     util.Config
 }
 
@@ -42,15 +46,35 @@ func NewServer(store db.Store, config util.Config) (*Server, error) {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	mailer, err := util.NewMailer(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mailer: %w", err)
+	}
+
+	blobStore, err := storage.NewBlobStore(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create blob store: %w", err)
+	}
+
+	if _, err := openAPISpec(); err != nil {
+		return nil, fmt.Errorf("cannot build OpenAPI spec: %w", err)
+	}
+
+	useRequestFieldNames()
+
 	server := &Server{
 		store:      store,
 		router:     gin.Default(),
 		tokenMaker: tokenMaker,
+		mailer:     mailer,
+		blobStore:  blobStore,
 		config:     config,
 	}
 
 	// Add CORS middleware
 	server.router.Use(corsMiddleware())
+	// Render the errors handlers record as the error envelope
+	server.router.Use(errorMiddleware())
 
 	// setup routes
 	server.setupRouter()
@@ -83,7 +107,7 @@ func (server *Server) Shutdown(ctx context.Context) error {
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 
 		if c.Request.Method == "OPTIONS" {
@@ -100,14 +124,16 @@ func (server *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header is required"})
+			c.Error(errAuthRequired)
+			c.Abort()
 			return
 		}
 
 		tokenString := authHeader[len("Bearer "):]
 		payload, err := server.tokenMaker.VerifyToken(tokenString)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
+			c.Error(errInvalidToken)
+			c.Abort()
 			return
 		}
 
@@ -119,24 +145,91 @@ func (server *Server) authMiddleware() gin.HandlerFunc {
 	}
 }
 
+// optionalAuthMiddleware sets the user in context when a valid token is sent,
+// but lets anonymous requests through to public routes
+func (server *Server) optionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if strings.HasPrefix(authHeader, "Bearer ") {
+			payload, err := server.tokenMaker.VerifyToken(authHeader[len("Bearer "):])
+			if err == nil {
+				c.Set(authorizationPayloadKey, payload)
+				c.Set("username", payload.Username)
+			}
+		}
+		c.Next()
+	}
+}
+
+// requireRoles only lets requests through whose token carries one of the given
+// roles. It must run after authMiddleware.
+func (server *Server) requireRoles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authPayload, err := server.getAuthPayload(c)
+		if err != nil {
+			c.Error(newAPIError(http.StatusUnauthorized, "auth.required", err.Error()))
+			c.Abort()
+			return
+		}
+
+		for _, role := range roles {
+			if authPayload.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.Error(errRoleNotAllowed)
+		c.Abort()
+	}
+}
+
 // setupRouter sets up all the routes for our API
 func (server *Server) setupRouter() {
 	router := server.router
 
+	// Serve uploads kept on local disk, S3 serves its own
+	if server.config.StorageDriver != "s3" && server.config.StorageDir != "" {
+		router.Static("/uploads", server.config.StorageDir)
+	}
+
+	router.GET("/robots.txt", server.getRobotsTxt)
+	router.GET("/sitemap.xml", server.getSitemap)
+	router.GET("/sitemaps/:file", server.getSitemapPage)
+
 	// Add routes to the router
 	v1 := router.Group("/api/v1")
 	{
+		v1.GET("/openapi.json", server.getOpenAPISpec)
+		v1.GET("/docs", server.getAPIDocs)
+
 		// Public routes
 		v1.POST("/users", server.createUser)
 		v1.POST("/login", server.loginUser)
+		v1.POST("/logout", server.logoutUser)
+		v1.POST("/tokens/renew", server.renewAccessToken)
+		v1.POST("/password/forgot", server.forgotPassword)
+		v1.POST("/password/reset", server.resetPassword)
+		v1.GET("/verify-email", server.verifyEmail)
 		v1.GET("/users/:id", server.getUser)
 		v1.GET("/users", server.listUsers)
-		v1.GET("/posts/:id", server.getPost)
-		v1.GET("/posts", server.listPosts)
-		v1.GET("/posts/filter", server.FilterPosts)
-		v1.GET("/users/:id/posts", server.listPostsByUser)
-		v1.GET("/posts/by-likes", server.listPostsByLikes)
+		v1.GET("/posts/:id", server.optionalAuthMiddleware(), server.getPost)
+		v1.GET("/posts/by-slug/:slug", server.optionalAuthMiddleware(), server.getPostBySlug)
+		v1.GET("/posts", server.optionalAuthMiddleware(), server.listPosts)
+		v1.GET("/posts/filter", server.optionalAuthMiddleware(), server.FilterPosts)
+		v1.GET("/posts/search", server.searchPosts)
+		v1.GET("/users/:id/posts", server.optionalAuthMiddleware(), server.listPostsByUser)
+		v1.GET("/posts/by-likes", server.optionalAuthMiddleware(), server.listPostsByLikes)
 		v1.GET("/users/by-likes", server.listUsersByPostLikes)
+		v1.GET("/posts/:id/comments", server.optionalAuthMiddleware(), server.listPostComments)
+		v1.GET("/comments/:id/replies", server.optionalAuthMiddleware(), server.listCommentReplies)
+		v1.GET("/posts/:id/likers", server.optionalAuthMiddleware(), server.listPostLikers)
+		v1.GET("/posts/:id/images", server.optionalAuthMiddleware(), server.listPostImages)
+		for _, format := range feedFormats {
+			v1.GET("/feed."+format, server.siteFeed(format))
+			v1.GET("/users/:id/feed."+format, server.userFeed(format))
+			v1.GET("/tags/:id/feed."+format, server.tagFeed(format))
+		}
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(server.authMiddleware())
@@ -144,23 +237,51 @@ func (server *Server) setupRouter() {
 			// User routes
 			protected.PUT("/users/:id", server.updateUser)
 			protected.PUT("/users/:id/password", server.updateUserPassword)
+			protected.GET("/users/:id/usage", server.getUserStorageUsage)
+			protected.POST("/verify-email/resend", server.resendVerificationEmail)
+
+			// Routes for users who write content, readers may only comment and like
+			authors := protected.Group("")
+			authors.Use(server.requireRoles(util.RoleAdmin, util.RoleEditor, util.RoleAuthor))
+			{
+				// Post routes
+				authors.POST("/posts", server.createPost)
+				authors.PUT("/posts/:id", server.updatePost)
+				authors.DELETE("/posts/:id", server.deletePost)
 
-			// Post routes
-			protected.POST("/posts", server.createPost)
-			protected.PUT("/posts/:id", server.updatePost)
-			protected.DELETE("/posts/:id", server.deletePost)
+				// Post revisions routes
+				authors.GET("/posts/:id/revisions", server.listPostRevisions)
+				authors.GET("/posts/:id/revisions/diff", server.diffPostRevisions)
+				authors.POST("/posts/:id/revisions/:rev/restore", server.restorePostRevision)
 
-			// Post images routes
-			protected.POST("/posts/:id/images", server.addImage)
-			protected.DELETE("/images/:id", server.deleteImage)
+				// Post images routes
+				authors.POST("/posts/:id/images", server.addImage)
+				authors.PUT("/posts/:id/images/order", server.reorderPostImages)
+				authors.DELETE("/posts/:id/images/:imageId", server.detachImage)
+				authors.PATCH("/images/:id", server.updateImage)
+				authors.DELETE("/images/:id", server.deleteImage)
 
-			// Post tags routes
-			protected.POST("/posts/:id/tags", server.addTag)
-			protected.DELETE("/tags/:id", server.deleteTag)
-			protected.DELETE("/posts/:id/tags/:tagId", server.removeTagFromPost)
+				// Post tags routes
+				authors.POST("/posts/:id/tags", server.addTag)
+				authors.DELETE("/tags/:id", server.deleteTag)
+				authors.DELETE("/posts/:id/tags/:tagId", server.removeTagFromPost)
+			}
+
+			// Admin routes
+			admin := protected.Group("/admin")
+			admin.Use(server.requireRoles(util.RoleAdmin))
+			{
+				admin.PUT("/users/:id/role", server.updateUserRole)
+			}
 
 			protected.POST("/posts/:id/like", server.incrementPostLikes)
 			protected.POST("/posts/:id/unlike", server.decrementPostLikes)
+
+			// Comment routes
+			protected.POST("/posts/:id/comments", server.createComment)
+			protected.POST("/comments/:id/replies", server.createReply)
+			protected.PUT("/comments/:id", server.updateComment)
+			protected.DELETE("/comments/:id", server.deleteComment)
 		}
 	}
 }
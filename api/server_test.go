@@ -16,12 +16,17 @@ import (
 	"github.com/golang/mock/gomock"
 	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
 	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/render"
 	"github.com/haotianxu2021/newPortfolio/util"
 	"github.com/stretchr/testify/require"
 )
 
 func createTestToken(t *testing.T, maker util.TokenMaker, username string) string {
-	token, err := maker.CreateToken(username, 24*time.Hour)
+	return createTestTokenWithRole(t, maker, username, util.RoleAuthor)
+}
+
+func createTestTokenWithRole(t *testing.T, maker util.TokenMaker, username string, role string) string {
+	token, err := maker.CreateToken(username, role, 24*time.Hour)
 	require.NoError(t, err)
 	return token
 }
@@ -171,9 +176,7 @@ func TestRemoveTagFromPost(t *testing.T) {
 }
 
 func createTestTokenSever(t *testing.T, server *Server, username string) string {
-	token, err := server.tokenMaker.CreateToken(username, 24*time.Hour)
-	require.NoError(t, err)
-	return token
+	return createTestToken(t, server.tokenMaker, username)
 }
 
 func addAuthHeader(request *http.Request, token string) {
@@ -195,6 +198,7 @@ func TestCreatePost(t *testing.T) {
 			Valid:  true,
 		},
 	}
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
 
 	testCases := []struct {
 		name          string
@@ -214,18 +218,21 @@ func TestCreatePost(t *testing.T) {
 			},
 			buildStubs: func(store *mockdb.MockStore) {
 				arg := db.CreatePostParams{
-					Title:   post.Title,
-					Content: post.Content,
-					UserID:  post.UserID,
-					Type:    post.Type,
-					Status:  post.Status,
+					Title:       post.Title,
+					Content:     post.Content,
+					UserID:      post.UserID,
+					Type:        post.Type,
+					Status:      post.Status,
+					Format:      "markdown",
+					ContentHtml: sql.NullString{String: "<p>Test Content</p>\n", Valid: true},
+					Toc:         json.RawMessage(`[]`),
 				}
 				store.EXPECT().
 					GetUserByUsername(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.User{ID: 1}, nil)
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Eq(arg)).
+					CreatePostWithSlugTx(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(post, nil)
 			},
@@ -239,6 +246,140 @@ func TestCreatePost(t *testing.T) {
 				requireBodyMatchPost(t, recorder.Body, post)
 			},
 		},
+		{
+			name: "Scheduled",
+			body: gin.H{
+				"title":      post.Title,
+				"content":    post.Content,
+				"user_id":    post.UserID.Int32,
+				"type":       post.Type,
+				"status":     "scheduled",
+				"publish_at": publishAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CreatePostParams{
+					Title:       post.Title,
+					Content:     post.Content,
+					UserID:      post.UserID,
+					Type:        post.Type,
+					Status:      sql.NullString{String: "scheduled", Valid: true},
+					PublishAt:   sql.NullTime{Time: publishAt, Valid: true},
+					Format:      "markdown",
+					ContentHtml: sql.NullString{String: "<p>Test Content</p>\n", Valid: true},
+					Toc:         json.RawMessage(`[]`),
+				}
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(post, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "HTMLIsSanitized",
+			body: gin.H{
+				"title":   post.Title,
+				"content": `<h2>Hi</h2><script>alert(1)</script>`,
+				"user_id": post.UserID.Int32,
+				"type":    post.Type,
+				"status":  "draft",
+				"format":  "html",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Eq(db.CreatePostParams{
+						Title:       post.Title,
+						Content:     `<h2>Hi</h2><script>alert(1)</script>`,
+						UserID:      post.UserID,
+						Type:        post.Type,
+						Status:      sql.NullString{String: "draft", Valid: true},
+						Format:      "html",
+						ContentHtml: sql.NullString{String: "<h2>Hi</h2>", Valid: true},
+						Toc:         json.RawMessage(`[]`),
+					})).
+					Times(1).
+					Return(post, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidFormat",
+			body: gin.H{
+				"title":   post.Title,
+				"content": post.Content,
+				"user_id": post.UserID.Int32,
+				"type":    post.Type,
+				"format":  "rtf",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ScheduledWithoutPublishAt",
+			body: gin.H{
+				"title":   post.Title,
+				"content": post.Content,
+				"user_id": post.UserID.Int32,
+				"type":    post.Type,
+				"status":  "scheduled",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidStatus",
+			body: gin.H{
+				"title":   post.Title,
+				"content": post.Content,
+				"user_id": post.UserID.Int32,
+				"type":    post.Type,
+				"status":  "secret",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 1}, nil)
+				store.EXPECT().
+					CreatePostWithSlugTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
 		{
 			name: "InvalidRequest",
 			body: gin.H{
@@ -297,7 +438,7 @@ func TestCreatePost(t *testing.T) {
 					Times(1).
 					Return(db.User{ID: 1}, nil)
 				store.EXPECT().
-					CreatePost(gomock.Any(), gomock.Any()).
+					CreatePostWithSlugTx(gomock.Any(), gomock.Any()).
 					Times(1).
 					Return(db.Post{}, sql.ErrConnDone) // Return an error to simulate internal server error
 			},
@@ -391,6 +532,12 @@ func TestGetPost(t *testing.T) {
 			String: "User",
 			Valid:  true,
 		},
+		Format: "markdown",
+		ContentHtml: sql.NullString{
+			String: "<p>Test Content</p>\n",
+			Valid:  true,
+		},
+		Toc: json.RawMessage(`[]`),
 	}
 
 	testCases := []struct {
@@ -407,12 +554,182 @@ func TestGetPost(t *testing.T) {
 					GetPost(gomock.Any(), gomock.Eq(post.ID)).
 					Times(1).
 					Return(post, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1"}, nil)
+				store.EXPECT().
+					GetPostLike(gomock.Any(), gomock.Eq(db.GetPostLikeParams{UserID: 2, PostID: post.ID})).
+					Times(1).
+					Return(db.PostLike{UserID: 2, PostID: post.ID}, nil)
+				store.EXPECT().
+					ListPostImages(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return([]db.Image{{
+						ID:       7,
+						FilePath: "images/1/photo.png",
+						Checksum: "abc",
+						Width:    1000,
+						Height:   500,
+					}}, nil)
+				store.EXPECT().
+					ListImageVariantsByPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return([]db.ImageVariant{
+						{ImageID: 7, Name: "thumbnail", FilePath: "images/1/photo-thumbnail.png", Width: 320, Height: 160},
+						{ImageID: 7, Name: "medium", FilePath: "images/1/photo-medium.png", Width: 800, Height: 400},
+					}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got struct {
+					Images []imageResponse `json:"images"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got.Images, 1)
+				require.Len(t, got.Images[0].Variants, 2)
+				require.Equal(t, "/images/1/photo-thumbnail.png 320w, /images/1/photo-medium.png 800w, /images/1/photo.png 1000w",
+					got.Images[0].Srcset)
+
 				requireBodyMatchGetPost(t, recorder.Body, post)
 			},
 		},
+		{
+			name:   "Anonymous",
+			postID: post.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					GetPostLike(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ListPostImages(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Image{}, nil)
+				store.EXPECT().
+					ListImageVariantsByPost(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ImageVariant{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "liked_by_me")
+			},
+		},
+		{
+			name:   "RendersOnFirstRead",
+			postID: post.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				unrendered := post
+				unrendered.Content = "# Intro\n\nHello"
+				unrendered.ContentHtml = sql.NullString{}
+				unrendered.Toc = nil
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(unrendered, nil)
+				store.EXPECT().
+					UpdatePostRendering(gomock.Any(), gomock.Eq(db.UpdatePostRenderingParams{
+						ID:          post.ID,
+						ContentHtml: sql.NullString{String: "<h1 id=\"intro\">Intro</h1>\n<p>Hello</p>\n", Valid: true},
+						Toc:         json.RawMessage(`[{"level":1,"id":"intro","text":"Intro"}]`),
+						Content:     unrendered.Content,
+						Format:      "markdown",
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1"}, nil)
+				store.EXPECT().
+					GetPostLike(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostLike{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListPostImages(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Image{}, nil)
+				store.EXPECT().
+					ListImageVariantsByPost(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ImageVariant{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got struct {
+					Format      string            `json:"format"`
+					ContentHTML string            `json:"content_html"`
+					TOC         []render.TOCEntry `json:"toc"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "markdown", got.Format)
+				require.Equal(t, "<h1 id=\"intro\">Intro</h1>\n<p>Hello</p>\n", got.ContentHTML)
+				require.Equal(t, []render.TOCEntry{{Level: 1, ID: "intro", Text: "Intro"}}, got.TOC)
+			},
+		},
+		{
+			name:   "DraftOfOtherUser",
+			postID: post.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				draft := post
+				draft.Status = sql.NullString{String: "draft", Valid: true}
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1", Role: util.RoleAuthor}, nil)
+				store.EXPECT().
+					ListPostImages(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "OwnDraft",
+			postID: post.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				draft := post
+				draft.Status = sql.NullString{String: "draft", Valid: true}
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(draft, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: post.UserID.Int32, Username: "testuser1", Role: util.RoleAuthor}, nil)
+				store.EXPECT().
+					GetPostLike(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostLike{}, sql.ErrNoRows)
+				store.EXPECT().
+					ListPostImages(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Image{}, nil)
+				store.EXPECT().
+					ListImageVariantsByPost(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ImageVariant{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"status":"draft"`)
+			},
+		},
 		{
 			name:   "NotFound",
 			postID: post.ID,
@@ -476,7 +793,7 @@ func TestGetPost(t *testing.T) {
 			request, err := http.NewRequest(http.MethodGet, url, nil)
 			require.NoError(t, err)
 
-			if tc.name != "UnauthorizedError" {
+			if tc.name != "UnauthorizedError" && tc.name != "Anonymous" {
 				// Create token for the post owner (user_id 1)
 				token := createTestTokenSever(t, server, "testuser1")
 				addAuthHeader(request, token)
@@ -488,77 +805,485 @@ func TestGetPost(t *testing.T) {
 	}
 }
 
-func requireBodyMatchGetPost(t *testing.T, body *bytes.Buffer, post db.GetPostRow) {
-	data, err := io.ReadAll(body)
-	require.NoError(t, err)
-
-	var gotPost gin.H
-	err = json.Unmarshal(data, &gotPost)
-	require.NoError(t, err)
-
-	require.Equal(t, post.ID, int32(gotPost["id"].(float64)))
-	require.Equal(t, post.Title, gotPost["title"])
-	require.Equal(t, post.Content, gotPost["content"])
-	require.Equal(t, post.Type, gotPost["type"])
-	require.Equal(t, post.Status.String, gotPost["status"])
-	require.Equal(t, post.Username.String, gotPost["username"])
-}
-
-func TestListPosts(t *testing.T) {
-	n := 5
-	posts := make([]db.ListPostsRow, n)
-	for i := 0; i < n; i++ {
-		posts[i] = db.ListPostsRow{
-			ID:      int32(i + 1),
-			Title:   fmt.Sprintf("Title %d", i+1),
-			Content: fmt.Sprintf("Content %d", i+1),
-			Type:    "blog",
-			Status: sql.NullString{
-				String: "published",
-				Valid:  true,
-			},
-		}
+func TestGetPostBySlug(t *testing.T) {
+	post := db.GetPostRow{
+		ID:     1,
+		Title:  "New Title",
+		Slug:   "new-title",
+		Format: "markdown",
+		ContentHtml: sql.NullString{
+			String: "<p>content</p>\n",
+			Valid:  true,
+		},
+		UserID:   sql.NullInt32{Int32: 1, Valid: true},
+		Status:   sql.NullString{String: "published", Valid: true},
+		Username: sql.NullString{String: "testuser", Valid: true},
 	}
 
 	testCases := []struct {
 		name          string
-		query         string
+		slug          string
 		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
-			name:  "OK",
-			query: "?limit=5&offset=0",
+			name: "OK",
+			slug: post.Slug,
 			buildStubs: func(store *mockdb.MockStore) {
-				arg := db.ListPostsParams{
-					Column1: "published",
-					Limit:   5,
-					Offset:  0,
-				}
 				store.EXPECT().
-					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					GetPostIDBySlug(gomock.Any(), gomock.Eq(post.Slug)).
 					Times(1).
-					Return(posts, nil)
+					Return(post.ID, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
+				store.EXPECT().
+					ListPostImages(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Image{}, nil)
+				store.EXPECT().
+					ListImageVariantsByPost(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ImageVariant{}, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
-				requireBodyMatchPosts(t, recorder.Body, posts)
+				requireBodyMatchGetPost(t, recorder.Body, post)
 			},
 		},
 		{
-			name:  "InternalError",
-			query: "?limit=5&offset=0",
+			name: "OldSlugRedirects",
+			slug: "old-title",
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
-					ListPosts(gomock.Any(), gomock.Any()).
+					GetPostIDBySlug(gomock.Any(), gomock.Eq("old-title")).
 					Times(1).
-					Return([]db.ListPostsRow{}, sql.ErrConnDone)
+					Return(int32(0), sql.ErrNoRows)
+				store.EXPECT().
+					GetPostSlugRedirect(gomock.Any(), gomock.Eq("old-title")).
+					Times(1).
+					Return(db.PostSlugRedirect{Slug: "old-title", PostID: post.ID}, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(post, nil)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				require.Equal(t, http.StatusMovedPermanently, recorder.Code)
+				require.Equal(t, "/api/v1/posts/by-slug/new-title", recorder.Header().Get("Location"))
 			},
 		},
-	}
+		{
+			name: "OldSlugOfDraft",
+			slug: "old-title",
+			buildStubs: func(store *mockdb.MockStore) {
+				draft := post
+				draft.Status = sql.NullString{String: "draft", Valid: true}
+
+				store.EXPECT().
+					GetPostIDBySlug(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int32(0), sql.ErrNoRows)
+				store.EXPECT().
+					GetPostSlugRedirect(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostSlugRedirect{Slug: "old-title", PostID: post.ID}, nil)
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(draft, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+				require.Empty(t, recorder.Header().Get("Location"))
+			},
+		},
+		{
+			name: "NotFound",
+			slug: "missing",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPostIDBySlug(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int32(0), sql.ErrNoRows)
+				store.EXPECT().
+					GetPostSlugRedirect(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.PostSlugRedirect{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/api/v1/posts/by-slug/"+tc.slug, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func requireBodyMatchGetPost(t *testing.T, body *bytes.Buffer, post db.GetPostRow) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotPost gin.H
+	err = json.Unmarshal(data, &gotPost)
+	require.NoError(t, err)
+
+	require.Equal(t, post.ID, int32(gotPost["id"].(float64)))
+	require.Equal(t, post.Title, gotPost["title"])
+	require.Equal(t, post.Content, gotPost["content"])
+	require.Equal(t, post.Type, gotPost["type"])
+	require.Equal(t, post.Status.String, gotPost["status"])
+	require.Equal(t, post.Username.String, gotPost["username"])
+}
+
+func TestUnpublishedPostActivity(t *testing.T) {
+	author := db.User{ID: 2, Username: "author1", Role: util.RoleAuthor}
+	reader := db.User{ID: 3, Username: "reader1", Role: util.RoleReader}
+	draft := db.GetPostRow{
+		ID:     1,
+		UserID: sql.NullInt32{Int32: author.ID, Valid: true},
+		Status: sql.NullString{String: util.PostStatusDraft, Valid: true},
+	}
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		user       *db.User
+		buildStubs func(store *mockdb.MockStore)
+		status     int
+	}{
+		{
+			name:   "ImagesAnonymous",
+			method: http.MethodGet,
+			url:    "/api/v1/posts/1/images",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPostImages(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "CommentsAnonymous",
+			method: http.MethodGet,
+			url:    "/api/v1/posts/1/comments",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListCommentThreads(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "LikersOtherReader",
+			method: http.MethodGet,
+			url:    "/api/v1/posts/1/likers",
+			user:   &reader,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPostLikers(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusNotFound,
+		},
+		{
+			name:   "LikersAuthor",
+			method: http.MethodGet,
+			url:    "/api/v1/posts/1/likers",
+			user:   &author,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPostLikers(gomock.Any(), gomock.Any()).Times(1).Return([]db.ListPostLikersRow{}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "CommentOtherReader",
+			method: http.MethodPost,
+			url:    "/api/v1/posts/1/comments",
+			user:   &reader,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateComment(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusNotFound,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetPost(gomock.Any(), gomock.Eq(draft.ID)).Times(1).Return(draft, nil)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader
+			if tc.method == http.MethodPost {
+				body = bytes.NewReader([]byte(`{"content":"Nice write-up"}`))
+			}
+			request, err := http.NewRequest(tc.method, tc.url, body)
+			require.NoError(t, err)
+
+			if tc.user != nil {
+				store.EXPECT().GetUserByUsername(gomock.Any(), gomock.Eq(tc.user.Username)).Times(1).Return(*tc.user, nil)
+				addAuthHeader(request, createTestTokenWithRole(t, server.tokenMaker, tc.user.Username, tc.user.Role))
+			}
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code)
+		})
+	}
+}
+
+func TestListPosts(t *testing.T) {
+	n := 6
+	newest := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	posts := make([]db.ListPostsRow, n)
+	for i := 0; i < n; i++ {
+		posts[i] = db.ListPostsRow{
+			ID:      int32(n - i),
+			Title:   fmt.Sprintf("Title %d", i+1),
+			Content: fmt.Sprintf("Content %d", i+1),
+			Type:    "blog",
+			Status: sql.NullString{
+				String: "published",
+				Valid:  true,
+			},
+			CreatedAt: sql.NullTime{Time: newest.Add(-time.Duration(i) * time.Hour), Valid: true},
+		}
+	}
+	cursor := newestPostCursor(posts[4].ID, posts[4].CreatedAt).encode()
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?limit=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				// One more post than asked for means there is a next page
+				arg := db.ListPostsParams{
+					Status:   "published",
+					ViewerID: sql.NullInt32{Int32: 2, Valid: true},
+					Limit:    6,
+				}
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1"}, nil)
+				store.EXPECT().
+					ListLikedPostIDs(gomock.Any(), gomock.Eq(db.ListLikedPostIDsParams{
+						UserID:  2,
+						Column2: []int32{6, 5, 4, 3, 2},
+					})).
+					Times(1).
+					Return([]int32{2, 4}, nil)
+				store.EXPECT().CountPosts(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page struct {
+					Data       []gin.H `json:"data"`
+					NextCursor string  `json:"next_cursor"`
+					Total      *int64  `json:"total"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+				requireBodyMatchPosts(t, recorder.Body, posts[:5])
+				for _, got := range page.Data {
+					id := int32(got["id"].(float64))
+					require.Equal(t, id == 2 || id == 4, got["liked_by_me"])
+				}
+				require.Equal(t, cursor, page.NextCursor)
+				require.Nil(t, page.Total)
+				require.Equal(t,
+					`</api/v1/posts?limit=5>; rel="first", </api/v1/posts?cursor=`+cursor+`&limit=5>; rel="next"`,
+					recorder.Header().Get("Link"))
+			},
+		},
+		{
+			name:  "NextPage",
+			query: "?limit=5&include_total=true&cursor=" + cursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1"}, nil)
+				arg := db.ListPostsParams{
+					Status:         "published",
+					ViewerID:       sql.NullInt32{Int32: 2, Valid: true},
+					AfterID:        sql.NullInt32{Int32: posts[4].ID, Valid: true},
+					AfterCreatedAt: posts[4].CreatedAt,
+					Limit:          6,
+				}
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(posts[5:], nil)
+				store.EXPECT().
+					ListLikedPostIDs(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]int32{}, nil)
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{
+						Status:   "published",
+						ViewerID: sql.NullInt32{Int32: 2, Valid: true},
+					})).
+					Times(1).
+					Return(int64(6), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page gin.H
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+				requireBodyMatchPosts(t, recorder.Body, posts[5:])
+				require.NotContains(t, page, "next_cursor")
+				require.Equal(t, 6.0, page["total"])
+				require.Equal(t, `</api/v1/posts?include_total=true&limit=5>; rel="first"`, recorder.Header().Get("Link"))
+			},
+		},
+		{
+			name:  "InvalidCursor",
+			query: "?cursor=not-a-cursor",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusBadRequest, "request.invalid_cursor")
+			},
+		},
+		{
+			name:  "CursorOfOtherOrder",
+			query: "?cursor=" + postCursor{Sort: sortMostLiked, ID: 3, Likes: 2}.encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusBadRequest, "request.invalid_cursor")
+			},
+		},
+		{
+			name:  "InvalidLimit",
+			query: "?limit=0",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusBadRequest, "validation.failed")
+			},
+		},
+		{
+			name:  "OwnDrafts",
+			query: "?status=draft",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1", Role: util.RoleAuthor}, nil)
+				// Only the reader's own drafts may be listed
+				arg := db.ListPostsParams{
+					Status:   "draft",
+					ViewerID: sql.NullInt32{Int32: 2, Valid: true},
+					Limit:    11,
+				}
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListPostsRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "AllDraftsForEditor",
+			query: "?status=draft",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1", Role: util.RoleEditor}, nil)
+				arg := db.ListPostsParams{
+					Status:      "draft",
+					AllStatuses: true,
+					ViewerID:    sql.NullInt32{Int32: 2, Valid: true},
+					Limit:       11,
+				}
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ListPostsRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidStatus",
+			query: "?status=secret",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?limit=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.User{ID: 2, Username: "testuser1"}, nil)
+				store.EXPECT().
+					ListPosts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.ListPostsRow{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
 
 	for i := range testCases {
 		tc := testCases[i]
@@ -595,13 +1320,138 @@ func TestListPosts(t *testing.T) {
 	}
 }
 
+func TestListPostsByUserAndLikes(t *testing.T) {
+	createdAt := sql.NullTime{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "ByUser",
+			url:  "/api/v1/users/3/posts?limit=1&include_total=true",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(int32(3))).Times(1).Return(db.User{ID: 3}, nil)
+				store.EXPECT().
+					ListPostsByUser(gomock.Any(), gomock.Eq(db.ListPostsByUserParams{
+						UserID: sql.NullInt32{Int32: 3, Valid: true},
+						Status: "published",
+						Limit:  2,
+					})).
+					Times(1).
+					Return([]db.ListPostsByUserRow{{ID: 9, CreatedAt: createdAt}, {ID: 8, CreatedAt: createdAt}}, nil)
+				store.EXPECT().
+					CountPosts(gomock.Any(), gomock.Eq(db.CountPostsParams{
+						Status:   "published",
+						AuthorID: sql.NullInt32{Int32: 3, Valid: true},
+					})).
+					Times(1).
+					Return(int64(2), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page pageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+				require.Len(t, page.Data, 1)
+				require.Equal(t, newestPostCursor(9, createdAt).encode(), page.NextCursor)
+				require.Equal(t, int64(2), *page.Total)
+			},
+		},
+		{
+			name: "ByUserAfterCursor",
+			url:  "/api/v1/users/3/posts?limit=1&cursor=" + newestPostCursor(9, createdAt).encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(int32(3))).Times(1).Return(db.User{ID: 3}, nil)
+				store.EXPECT().
+					ListPostsByUser(gomock.Any(), gomock.Eq(db.ListPostsByUserParams{
+						UserID:         sql.NullInt32{Int32: 3, Valid: true},
+						Status:         "published",
+						AfterID:        sql.NullInt32{Int32: 9, Valid: true},
+						AfterCreatedAt: createdAt,
+						Limit:          2,
+					})).
+					Times(1).
+					Return([]db.ListPostsByUserRow{{ID: 8, CreatedAt: createdAt}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page gin.H
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+				require.NotContains(t, page, "next_cursor")
+				require.NotContains(t, page, "total")
+			},
+		},
+		{
+			name: "ByLikesAfterCursor",
+			url:  "/api/v1/posts/by-likes?limit=1&cursor=" + postCursor{Sort: sortMostLiked, ID: 5, Likes: 4}.encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPostsOrderByLikes(gomock.Any(), gomock.Eq(db.ListPostsOrderByLikesParams{
+						Status:     "published",
+						AfterID:    sql.NullInt32{Int32: 5, Valid: true},
+						AfterLikes: sql.NullInt32{Int32: 4, Valid: true},
+						Limit:      2,
+					})).
+					Times(1).
+					Return([]db.ListPostsOrderByLikesRow{{ID: 2, Likes: 4}, {ID: 7, Likes: 1}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page pageResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+				require.Equal(t, postCursor{Sort: sortMostLiked, ID: 2, Likes: 4}.encode(), page.NextCursor)
+			},
+		},
+		{
+			name: "ByLikesWithNewestCursor",
+			url:  "/api/v1/posts/by-likes?cursor=" + newestPostCursor(9, createdAt).encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListPostsOrderByLikes(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{TokenSymmetricKey: "12345678901234567890123456789012"})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
 func requireBodyMatchPosts(t *testing.T, body *bytes.Buffer, posts []db.ListPostsRow) {
 	data, err := io.ReadAll(body)
 	require.NoError(t, err)
 
-	var gotPosts []gin.H
-	err = json.Unmarshal(data, &gotPosts)
+	var page struct {
+		Data []gin.H `json:"data"`
+	}
+	err = json.Unmarshal(data, &page)
 	require.NoError(t, err)
+	gotPosts := page.Data
 	require.Equal(t, len(posts), len(gotPosts))
 
 	for i := range posts {
@@ -624,6 +1474,8 @@ func TestUpdatePost(t *testing.T) {
 			Valid:  true,
 		},
 	}
+	publishAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	firstPublished := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
 
 	testCases := []struct {
 		name          string
@@ -713,6 +1565,164 @@ func TestUpdatePost(t *testing.T) {
 				require.Equal(t, http.StatusNotFound, recorder.Code)
 			},
 		},
+		{
+			name:   "Schedule",
+			postID: post.ID,
+			body: gin.H{
+				"status":     "scheduled",
+				"publish_at": publishAt,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.GetPostRow{
+						ID:       post.ID,
+						Status:   sql.NullString{String: "draft", Valid: true},
+						Username: sql.NullString{String: "testuser1", Valid: true},
+					}, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 1, Username: "testuser1"}, nil)
+				store.EXPECT().
+					EditPostTx(gomock.Any(), gomock.Eq(db.EditPostTxParams{
+						UpdatePostParams: db.UpdatePostParams{
+							ID:        post.ID,
+							Status:    sql.NullString{String: "scheduled", Valid: true},
+							PublishAt: sql.NullTime{Time: publishAt, Valid: true},
+						},
+						EditedBy: 1,
+					})).
+					Times(1).
+					Return(db.EditPostTxResult{Post: post}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "PublishKeepsFirstPublishAt",
+			postID: post.ID,
+			body: gin.H{
+				"status": "published",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.GetPostRow{
+						ID:        post.ID,
+						Status:    sql.NullString{String: "unlisted", Valid: true},
+						PublishAt: sql.NullTime{Time: firstPublished, Valid: true},
+						Username:  sql.NullString{String: "testuser1", Valid: true},
+					}, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 1, Username: "testuser1"}, nil)
+				store.EXPECT().
+					EditPostTx(gomock.Any(), gomock.Eq(db.EditPostTxParams{
+						UpdatePostParams: db.UpdatePostParams{
+							ID:        post.ID,
+							Status:    sql.NullString{String: "published", Valid: true},
+							PublishAt: sql.NullTime{Time: firstPublished, Valid: true},
+						},
+						EditedBy: 1,
+					})).
+					Times(1).
+					Return(db.EditPostTxResult{Post: post}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "RendersNewContent",
+			postID: post.ID,
+			body: gin.H{
+				"content": "Some **bold** text",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.GetPostRow{
+						ID:        post.ID,
+						Format:    "markdown",
+						Status:    sql.NullString{String: "published", Valid: true},
+						PublishAt: sql.NullTime{Time: firstPublished, Valid: true},
+						Username:  sql.NullString{String: "testuser1", Valid: true},
+					}, nil)
+				store.EXPECT().
+					GetUserByUsername(gomock.Any(), gomock.Eq("testuser1")).
+					Times(1).
+					Return(db.User{ID: 1, Username: "testuser1"}, nil)
+				store.EXPECT().
+					EditPostTx(gomock.Any(), gomock.Eq(db.EditPostTxParams{
+						UpdatePostParams: db.UpdatePostParams{
+							ID:          post.ID,
+							Content:     "Some **bold** text",
+							PublishAt:   sql.NullTime{Time: firstPublished, Valid: true},
+							ContentHtml: sql.NullString{String: "<p>Some <strong>bold</strong> text</p>\n", Valid: true},
+							Toc:         json.RawMessage(`[]`),
+						},
+						EditedBy: 1,
+					})).
+					Times(1).
+					Return(db.EditPostTxResult{Post: post}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidTransition",
+			postID: post.ID,
+			body: gin.H{
+				"status": "published",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.GetPostRow{
+						ID:       post.ID,
+						Status:   sql.NullString{String: "archived", Valid: true},
+						Username: sql.NullString{String: "testuser1", Valid: true},
+					}, nil)
+				store.EXPECT().
+					EditPostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		},
+		{
+			name:   "PublishAtInThePast",
+			postID: post.ID,
+			body: gin.H{
+				"status":     "scheduled",
+				"publish_at": firstPublished,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(post.ID)).
+					Times(1).
+					Return(db.GetPostRow{
+						ID:       post.ID,
+						Status:   sql.NullString{String: "draft", Valid: true},
+						Username: sql.NullString{String: "testuser1", Valid: true},
+					}, nil)
+				store.EXPECT().
+					EditPostTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
 		{
 			name:   "UnauthorizedError",
 			postID: post.ID,
@@ -727,7 +1737,7 @@ func TestUpdatePost(t *testing.T) {
 					GetPost(gomock.Any(), gomock.Any()).
 					Times(0)
 				store.EXPECT().
-					UpdatePost(gomock.Any(), gomock.Any()).
+					EditPostTx(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -783,37 +1793,65 @@ func TestDeletePost(t *testing.T) {
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
-			name:   "OK",
+			name:   "OK",
+			postID: 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				// First expect GetPost for ownership verification
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(db.GetPostRow{
+						ID: 1,
+						Username: sql.NullString{
+							String: "testuser1",
+							Valid:  true,
+						},
+					}, nil)
+
+				// Then expect DeletePost
+				store.EXPECT().
+					DeletePost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestToken(t, tokenMaker, "testuser1")
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "NotOwner",
 			postID: 1,
 			buildStubs: func(store *mockdb.MockStore) {
-				// First expect GetPost for ownership verification
 				store.EXPECT().
 					GetPost(gomock.Any(), gomock.Eq(int32(1))).
 					Times(1).
 					Return(db.GetPostRow{
 						ID: 1,
 						Username: sql.NullString{
-							String: "testuser1",
+							String: "testuser2", // Different user
 							Valid:  true,
 						},
 					}, nil)
 
-				// Then expect DeletePost
+				// DeletePost should not be called
 				store.EXPECT().
-					DeletePost(gomock.Any(), gomock.Eq(int32(1))).
-					Times(1).
-					Return(nil)
+					DeletePost(gomock.Any(), gomock.Any()).
+					Times(0)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
 				token := createTestToken(t, tokenMaker, "testuser1")
 				addAuthHeader(request, token)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
 			},
 		},
 		{
-			name:   "NotOwner",
+			name:   "AdminDeletesAnyPost",
 			postID: 1,
 			buildStubs: func(store *mockdb.MockStore) {
 				store.EXPECT().
@@ -827,17 +1865,36 @@ func TestDeletePost(t *testing.T) {
 						},
 					}, nil)
 
-				// DeletePost should not be called
+				store.EXPECT().
+					DeletePost(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "admin1", util.RoleAdmin)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ReaderForbidden",
+			postID: 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetPost(gomock.Any(), gomock.Any()).
+					Times(0)
 				store.EXPECT().
 					DeletePost(gomock.Any(), gomock.Any()).
 					Times(0)
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
-				token := createTestToken(t, tokenMaker, "testuser1")
+				token := createTestTokenWithRole(t, tokenMaker, "testuser1", util.RoleReader)
 				addAuthHeader(request, token)
 			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, http.StatusForbidden, recorder.Code)
 			},
 		},
 		{
@@ -1015,6 +2072,33 @@ func TestDeleteTag(t *testing.T) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name:  "AdminDeletesAnyTag",
+			tagID: 1,
+			buildStubs: func(store *mockdb.MockStore) {
+				// Admins skip the ownership lookups
+				store.EXPECT().
+					GetPostsByTagID(gomock.Any(), gomock.Any()).
+					Times(0)
+
+				store.EXPECT().
+					DeleteTagFromPosts(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(nil)
+
+				store.EXPECT().
+					DeleteTag(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return(nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "admin1", util.RoleAdmin)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
 		{
 			name:  "NoPostsWithTag",
 			tagID: 1,
@@ -1144,6 +2228,11 @@ func TestDeleteImage(t *testing.T) {
 					Times(1).
 					Return(db.User{ID: 1}, nil)
 
+				store.EXPECT().
+					ListImageVariants(gomock.Any(), gomock.Eq(int32(1))).
+					Times(1).
+					Return([]db.ImageVariant{}, nil)
+
 				// Finally expect DeleteImage
 				store.EXPECT().
 					DeleteImage(gomock.Any(), gomock.Eq(int32(1))).
@@ -1246,3 +2335,295 @@ func TestDeleteImage(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterPosts(t *testing.T) {
+	author := "testuser"
+	minLikes := int32(3)
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?tags=go,sql&tags=web&tag_match=all&author=testuser&min_likes=3&created_after=2024-01-01&sort_by=likes&sort_order=asc",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.FilterParams{
+					Username:     &author,
+					Tags:         []string{"go", "sql", "web"},
+					TagMatch:     "all",
+					CreatedAfter: &createdAfter,
+					MinLikes:     &minLikes,
+					SortBy:       "likes",
+					SortOrder:    "asc",
+					Limit:        11,
+				}
+				store.EXPECT().
+					FilterPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.FilteredPost{{ID: 1, Title: "Go and SQL"}}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:  "NextPageWithTotal",
+			query: "?sort_by=likes&limit=1&include_total=true&cursor=" + postCursor{Sort: "likes:desc", ID: 4, Likes: 3}.encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.FilterParams{
+					TagMatch:  "any",
+					SortBy:    "likes",
+					SortOrder: "desc",
+					After:     &db.FilterCursor{ID: 4, Likes: 3},
+					Limit:     2,
+				}
+				store.EXPECT().
+					FilterPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.FilteredPost{{ID: 2, Likes: 3}, {ID: 7, Likes: 1}}, nil)
+				store.EXPECT().
+					CountFilteredPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(12), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page struct {
+					Data       []gin.H `json:"data"`
+					NextCursor string  `json:"next_cursor"`
+					Total      int64   `json:"total"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+				require.Len(t, page.Data, 1)
+				require.Equal(t, 2.0, page.Data[0]["id"])
+				require.Equal(t, int64(12), page.Total)
+
+				next, err := decodePostCursor(page.NextCursor, "likes:desc")
+				require.NoError(t, err)
+				require.Equal(t, &postCursor{Sort: "likes:desc", ID: 2, Likes: 3}, next)
+				require.Contains(t, recorder.Header().Get("Link"), `rel="next"`)
+			},
+		},
+		{
+			name:  "CursorOfOtherOrder",
+			query: "?sort_by=title&cursor=" + postCursor{Sort: "likes:desc", ID: 4, Likes: 3}.encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					FilterPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidTagMatch",
+			query: "?tags=go&tag_match=some",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					FilterPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidDateRange",
+			query: "?created_after=2024-02-01&created_before=2024-01-01",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					FilterPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidSortBy",
+			query: "?sort_by=likes%3BDROP%20TABLE%20posts",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					FilterPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// Create test config
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			url := "/api/v1/posts/filter" + tc.query
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := []db.SearchPostRow{
+		{ID: 1, Title: "Go API", TitleHighlight: "<mark>Go</mark> API", Rank: 0.6, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+		{ID: 7, Title: "Go tips", TitleHighlight: "<mark>Go</mark> tips", Rank: 0.3, CreatedAt: sql.NullTime{Time: createdAt, Valid: true}},
+	}
+	cursor := postCursor{Sort: sortRelevance, ID: 1, Rank: 0.6, CreatedAt: &createdAt}.encode()
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "?q=%22rest+api%22+go*&limit=5",
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SearchPostsParams{
+					TSQuery: "(rest <-> api) & go:*",
+					Limit:   6,
+				}
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(results, nil)
+				store.EXPECT().
+					CountSearchPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page gin.H
+				err := json.Unmarshal(recorder.Body.Bytes(), &page)
+				require.NoError(t, err)
+				require.Len(t, page["data"], 2)
+				require.Equal(t, results[0].TitleHighlight, page["data"].([]interface{})[0].(map[string]interface{})["title_highlight"])
+				require.NotContains(t, page, "next_cursor")
+				require.NotContains(t, page, "total")
+			},
+		},
+		{
+			name:  "NextPage",
+			query: "?q=go&limit=1&include_total=true&cursor=" + cursor,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.SearchPostsParams{
+					TSQuery: "go",
+					After:   &db.SearchCursor{ID: 1, Rank: 0.6, CreatedAt: createdAt},
+					Limit:   2,
+				}
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(results, nil)
+				store.EXPECT().
+					CountSearchPosts(gomock.Any(), gomock.Eq("go")).
+					Times(1).
+					Return(int64(3), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var page pageResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &page)
+				require.NoError(t, err)
+				require.Len(t, page.Data, 1)
+				require.Equal(t, cursor, page.NextCursor)
+				require.Equal(t, int64(3), *page.Total)
+				require.Contains(t, recorder.Header().Get("Link"), `rel="next"`)
+			},
+		},
+		{
+			name:  "InvalidCursor",
+			query: "?q=go&cursor=" + newestPostCursor(1, sql.NullTime{Time: createdAt, Valid: true}).encode(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireErrorEnvelope(t, recorder, http.StatusBadRequest, "request.invalid_cursor")
+			},
+		},
+		{
+			name:  "EmptyQuery",
+			query: "?q=%22%22+%26",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:  "InternalError",
+			query: "?q=go",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					SearchPosts(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			// Create test config
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			url := "/api/v1/posts/search" + tc.query
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+)
+
+// sitemapMaxURLs is the most URLs the sitemap protocol allows in one sitemap.
+// Larger sites are split into numbered sitemaps listed by a sitemap index.
+const sitemapMaxURLs = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+// sitemapURL is a url entry of a sitemap or a sitemap entry of an index
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod,omitempty"`
+}
+
+// getSitemap serves the sitemap of published posts and the profiles and tags
+// they link to, or an index of numbered sitemaps when they do not fit in one
+func (server *Server) getSitemap(ctx *gin.Context) {
+	total, err := server.store.CountSitemapEntries(ctx)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	if total <= sitemapMaxURLs {
+		server.serveSitemapPage(ctx, 0)
+		return
+	}
+
+	pages := (total + sitemapMaxURLs - 1) / sitemapMaxURLs
+	index := sitemapIndex{Xmlns: sitemapNamespace, Sitemaps: make([]sitemapURL, pages)}
+	for i := range index.Sitemaps {
+		index.Sitemaps[i].Loc = fmt.Sprintf("%s/sitemaps/%d.xml", server.config.SiteURL, i+1)
+	}
+	writeXML(ctx, index)
+}
+
+// getSitemapPage serves one of the numbered sitemaps listed by the index
+func (server *Server) getSitemapPage(ctx *gin.Context) {
+	file := ctx.Param("file")
+	page, err := strconv.ParseInt(strings.TrimSuffix(file, ".xml"), 10, 32)
+	if err != nil || !strings.HasSuffix(file, ".xml") || page < 1 || page > math.MaxInt32/sitemapMaxURLs {
+		ctx.Error(errSitemapNotFound)
+		return
+	}
+
+	server.serveSitemapPage(ctx, int32(page-1))
+}
+
+func (server *Server) serveSitemapPage(ctx *gin.Context, page int32) {
+	entries, err := server.store.ListSitemapEntries(ctx, db.ListSitemapEntriesParams{
+		Limit:  sitemapMaxURLs,
+		Offset: page * sitemapMaxURLs,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	if page > 0 && len(entries) == 0 {
+		ctx.Error(errSitemapNotFound)
+		return
+	}
+
+	urlset := sitemapURLSet{Xmlns: sitemapNamespace, URLs: make([]sitemapURL, len(entries))}
+	for i, entry := range entries {
+		urlset.URLs[i].Loc = server.sitemapLoc(entry)
+		if entry.Lastmod.Valid {
+			urlset.URLs[i].Lastmod = entry.Lastmod.Time.UTC().Format(time.RFC3339)
+		}
+	}
+	writeXML(ctx, urlset)
+}
+
+// sitemapLoc is the page of the site showing entry
+func (server *Server) sitemapLoc(entry db.ListSitemapEntriesRow) string {
+	switch entry.Kind {
+	case "post":
+		return server.config.SiteURL + "/posts/" + url.PathEscape(entry.Slug)
+	case "user":
+		return fmt.Sprintf("%s/users/%d", server.config.SiteURL, entry.ID)
+	default:
+		return fmt.Sprintf("%s/tags/%d", server.config.SiteURL, entry.ID)
+	}
+}
+
+// getRobotsTxt tells crawlers which paths to skip and where the sitemap is
+func (server *Server) getRobotsTxt(ctx *gin.Context) {
+	var sb strings.Builder
+	sb.WriteString("User-agent: *\n")
+	if len(server.config.RobotsDisallow) == 0 {
+		// An empty rule allows everything
+		sb.WriteString("Disallow:\n")
+	}
+	for _, path := range server.config.RobotsDisallow {
+		fmt.Fprintf(&sb, "Disallow: %s\n", path)
+	}
+	fmt.Fprintf(&sb, "\nSitemap: %s/sitemap.xml\n", server.config.SiteURL)
+
+	ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(sb.String()))
+}
+
+// writeXML responds with v as an XML document
+func writeXML(ctx *gin.Context, v interface{}) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), body...))
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSitemap(t *testing.T) {
+	lastmod := sql.NullTime{Time: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), Valid: true}
+	entries := []db.ListSitemapEntriesRow{
+		{Kind: "post", ID: 2, Slug: "hello-world", Lastmod: lastmod},
+		{Kind: "tag", ID: 3, Lastmod: lastmod},
+		{Kind: "user", ID: 7},
+	}
+
+	testCases := []struct {
+		name          string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			url:  "/sitemap.xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountSitemapEntries(gomock.Any()).Times(1).Return(int64(len(entries)), nil)
+				store.EXPECT().
+					ListSitemapEntries(gomock.Any(), gomock.Eq(db.ListSitemapEntriesParams{Limit: sitemapMaxURLs, Offset: 0})).
+					Times(1).
+					Return(entries, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "application/xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+				var got sitemapURLSet
+				require.NoError(t, xml.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, []sitemapURL{
+					{Loc: "https://example.com/posts/hello-world", Lastmod: "2024-03-02T10:00:00Z"},
+					{Loc: "https://example.com/tags/3", Lastmod: "2024-03-02T10:00:00Z"},
+					{Loc: "https://example.com/users/7"},
+				}, got.URLs)
+			},
+		},
+		{
+			name: "Index",
+			url:  "/sitemap.xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountSitemapEntries(gomock.Any()).Times(1).Return(int64(2*sitemapMaxURLs+1), nil)
+				store.EXPECT().ListSitemapEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got sitemapIndex
+				require.NoError(t, xml.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, []sitemapURL{
+					{Loc: "https://example.com/sitemaps/1.xml"},
+					{Loc: "https://example.com/sitemaps/2.xml"},
+					{Loc: "https://example.com/sitemaps/3.xml"},
+				}, got.Sitemaps)
+			},
+		},
+		{
+			name: "Page",
+			url:  "/sitemaps/2.xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListSitemapEntries(gomock.Any(), gomock.Eq(db.ListSitemapEntriesParams{Limit: sitemapMaxURLs, Offset: sitemapMaxURLs})).
+					Times(1).
+					Return(entries[:1], nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got sitemapURLSet
+				require.NoError(t, xml.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got.URLs, 1)
+			},
+		},
+		{
+			name: "PageBeyondLast",
+			url:  "/sitemaps/9.xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListSitemapEntries(gomock.Any(), gomock.Any()).Times(1).Return([]db.ListSitemapEntriesRow{}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidPage",
+			url:  "/sitemaps/first.xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListSitemapEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			url:  "/sitemap.xml",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CountSitemapEntries(gomock.Any()).Times(1).Return(int64(0), sql.ErrConnDone)
+				store.EXPECT().ListSitemapEntries(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server, err := NewServer(store, util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+				SiteURL:           "https://example.com",
+			})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestRobotsTxt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		disallow []string
+		expected string
+	}{
+		{
+			name:     "AllowAll",
+			expected: "User-agent: *\nDisallow:\n\nSitemap: https://example.com/sitemap.xml\n",
+		},
+		{
+			name:     "Disallow",
+			disallow: []string{"/api/", "/drafts/"},
+			expected: "User-agent: *\nDisallow: /api/\nDisallow: /drafts/\n\nSitemap: https://example.com/sitemap.xml\n",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			server, err := NewServer(mockdb.NewMockStore(ctrl), util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+				SiteURL:           "https://example.com",
+				RobotsDisallow:    tc.disallow,
+			})
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/robots.txt", nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+			require.Equal(t, tc.expected, recorder.Body.String())
+		})
+	}
+}
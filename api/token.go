@@ -0,0 +1,101 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+type refreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// renewAccessToken handles issuing a new access token for a live session
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req refreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	session, ok := server.getActiveSession(ctx, req.RefreshToken)
+	if !ok {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, session.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errTokenUserGone)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	accessToken, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, renewAccessTokenResponse{
+		AccessToken: accessToken,
+	})
+}
+
+// logoutUser handles revoking the session behind a refresh token. Access
+// tokens already issued stay valid until they expire.
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req refreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	session, ok := server.getActiveSession(ctx, req.RefreshToken)
+	if !ok {
+		return
+	}
+
+	if err := server.store.RevokeSession(ctx, session.ID); err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// getActiveSession loads the session for a refresh token and writes a 401 if it
+// is unknown, revoked or expired. The bool reports whether the caller may go on.
+func (server *Server) getActiveSession(ctx *gin.Context, refreshToken string) (db.Session, bool) {
+	session, err := server.store.GetSessionByRefreshTokenHash(ctx, util.HashOpaqueToken(refreshToken))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(newAPIError(http.StatusUnauthorized, "auth.invalid_refresh_token", "invalid refresh token"))
+			return db.Session{}, false
+		}
+		ctx.Error(err)
+		return db.Session{}, false
+	}
+
+	if session.RevokedAt.Valid {
+		ctx.Error(newAPIError(http.StatusUnauthorized, "auth.session_revoked", "session has been revoked"))
+		return db.Session{}, false
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		ctx.Error(newAPIError(http.StatusUnauthorized, "auth.session_expired", "session has expired"))
+		return db.Session{}, false
+	}
+
+	return session, true
+}
@@ -0,0 +1,305 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginUserCreatesSession(t *testing.T) {
+	password := "secret123"
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user := db.User{ID: 1, Username: "testuser1", PasswordHash: hashedPassword}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+
+	var sessionArg db.CreateSessionParams
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+			sessionArg = arg
+			return db.Session{ID: 1, UserID: arg.UserID, ExpiresAt: arg.ExpiresAt}, nil
+		})
+
+	config := util.Config{
+		TokenSymmetricKey:    "12345678901234567890123456789012",
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+
+	server, err := NewServer(store, config)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got loginUserResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &got)
+	require.NoError(t, err)
+	require.NotEmpty(t, got.AccessToken)
+	require.NotEmpty(t, got.RefreshToken)
+
+	// Only the hash of the refresh token is stored
+	require.Equal(t, user.ID, sessionArg.UserID)
+	require.Equal(t, util.HashOpaqueToken(got.RefreshToken), sessionArg.RefreshTokenHash)
+	require.NotEqual(t, got.RefreshToken, sessionArg.RefreshTokenHash)
+	require.WithinDuration(t, time.Now().Add(config.RefreshTokenDuration), got.RefreshTokenExpiresAt, time.Second)
+	// The expiry column has no time zone, so it is written in UTC
+	require.Equal(t, time.UTC, sessionArg.ExpiresAt.Location())
+}
+
+func TestLoginUserTruncatesUserAgent(t *testing.T) {
+	password := "secret123"
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user := db.User{ID: 1, Username: "testuser1", PasswordHash: hashedPassword}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserByUsername(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+
+	var sessionArg db.CreateSessionParams
+	store.EXPECT().
+		CreateSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateSessionParams) (db.Session, error) {
+			sessionArg = arg
+			return db.Session{ID: 1, UserID: arg.UserID, ExpiresAt: arg.ExpiresAt}, nil
+		})
+
+	config := util.Config{
+		TokenSymmetricKey:    "12345678901234567890123456789012",
+		AccessTokenDuration:  time.Minute,
+		RefreshTokenDuration: time.Hour,
+	}
+
+	server, err := NewServer(store, config)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"username": user.Username, "password": password})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewReader(data))
+	require.NoError(t, err)
+	userAgent := strings.Repeat("é", 300)
+	request.Header.Set("User-Agent", userAgent)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The user agent is cut to the length of its column
+	require.Equal(t, userAgent[:2*maxUserAgentLength], sessionArg.UserAgent)
+}
+
+func TestRenewAccessToken(t *testing.T) {
+	user := db.User{ID: 1, Username: "testuser1"}
+	refreshToken, refreshTokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+
+	session := db.Session{
+		ID:               1,
+		UserID:           user.ID,
+		RefreshTokenHash: refreshTokenHash,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker util.TokenMaker)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), gomock.Eq(refreshTokenHash)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker util.TokenMaker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+
+				payload, err := tokenMaker.VerifyToken(got.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+			},
+		},
+		{
+			name: "UnknownToken",
+			body: gin.H{"refresh_token": "not-a-session"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker util.TokenMaker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RevokedSession",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				revoked := session
+				revoked.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				store.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(revoked, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker util.TokenMaker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			body: gin.H{"refresh_token": refreshToken},
+			buildStubs: func(store *mockdb.MockStore) {
+				expired := session
+				expired.ExpiresAt = time.Now().Add(-time.Minute)
+				store.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(expired, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker util.TokenMaker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingToken",
+			body: gin.H{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetSessionByRefreshTokenHash(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker util.TokenMaker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:   "12345678901234567890123456789012",
+				AccessTokenDuration: time.Minute,
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/api/v1/tokens/renew", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestLogoutUser(t *testing.T) {
+	refreshToken, refreshTokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+
+	session := db.Session{
+		ID:               7,
+		UserID:           1,
+		RefreshTokenHash: refreshTokenHash,
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetSessionByRefreshTokenHash(gomock.Any(), gomock.Eq(refreshTokenHash)).
+		Times(1).
+		Return(session, nil)
+	store.EXPECT().
+		RevokeSession(gomock.Any(), gomock.Eq(session.ID)).
+		Times(1).
+		Return(nil)
+
+	config := util.Config{
+		TokenSymmetricKey: "12345678901234567890123456789012",
+	}
+
+	server, err := NewServer(store, config)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/api/v1/logout", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -2,8 +2,10 @@ package api
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
@@ -31,15 +33,21 @@ type updateUserPasswordRequest struct {
 	Password string `json:"password" binding:"required,min=6"`
 }
 
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin editor author reader"`
+}
+
 type userResponse struct {
-	ID        int32          `json:"id"`
-	Username  string         `json:"username"`
-	Email     string         `json:"email"`
-	FirstName sql.NullString `json:"first_name"`
-	LastName  sql.NullString `json:"last_name"`
-	Bio       sql.NullString `json:"bio"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
+	ID              int32          `json:"id"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	FirstName       sql.NullString `json:"first_name"`
+	LastName        sql.NullString `json:"last_name"`
+	Bio             sql.NullString `json:"bio"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	Role            string         `json:"role,omitempty"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
 
 type loginUserRequest struct {
@@ -48,8 +56,10 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	AccessToken           string       `json:"access_token"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 type listUsersRequest struct {
@@ -62,26 +72,28 @@ type getUserByUsernameRequest struct {
 }
 
 type getUserByUsernameResponse struct {
-	ID        int32          `json:"id"`
-	Username  string         `json:"username"`
-	Email     string         `json:"email"`
-	FirstName sql.NullString `json:"first_name"`
-	LastName  sql.NullString `json:"last_name"`
-	Bio       sql.NullString `json:"bio"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
+	ID              int32          `json:"id"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	FirstName       sql.NullString `json:"first_name"`
+	LastName        sql.NullString `json:"last_name"`
+	Bio             sql.NullString `json:"bio"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	Role            string         `json:"role"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
 
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	passwordHash, err := util.HashPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		ctx.Error(err)
 		return
 	}
 
@@ -105,19 +117,26 @@ func (server *Server) createUser(ctx *gin.Context) {
 
 	user, err := server.store.CreateUser(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
+	// The account exists either way, a lost email can be sent again
+	if err := server.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("cannot send verification email to user %d: %v", user.ID, err)
+	}
+
 	ctx.JSON(http.StatusOK, userResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Bio:       user.Bio,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Bio:             user.Bio,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
 	})
 }
 
@@ -125,19 +144,19 @@ func (server *Server) updateUser(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
 	var req updateUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	authPayload, err := server.getAuthPayload(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		ctx.Error(newAPIError(http.StatusUnauthorized, "auth.required", err.Error()))
 		return
 	}
 
@@ -145,16 +164,16 @@ func (server *Server) updateUser(ctx *gin.Context) {
 	currentUser, err := server.store.GetUser(ctx, int32(id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	// Verify user is updating their own profile
 	if authPayload.Username != currentUser.Username {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "can only update your own profile"})
+		ctx.Error(notOwnerError("can only update your own profile"))
 		return
 	}
 
@@ -162,16 +181,23 @@ func (server *Server) updateUser(ctx *gin.Context) {
 	if req.Username != "" && req.Username != currentUser.Username {
 		_, err := server.store.GetUserByUsername(ctx, req.Username)
 		if err == nil {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "username already exists"})
+			ctx.Error(errUsernameTaken)
 			return
 		}
 	}
 
 	// Check if new email already exists (if changed)
-	if req.Email != "" && req.Email != currentUser.Email {
+	emailChanged := req.Email != "" && req.Email != currentUser.Email
+	if emailChanged {
 		_, err := server.store.GetUserByEmail(ctx, req.Email)
 		if err == nil {
-			ctx.JSON(http.StatusConflict, gin.H{"error": "email already exists"})
+			ctx.Error(errEmailTaken)
+			return
+		}
+
+		// Links sent to the old address must not verify the new one
+		if err := server.store.RevokeEmailVerificationTokens(ctx, currentUser.ID); err != nil {
+			ctx.Error(err)
 			return
 		}
 	}
@@ -194,39 +220,49 @@ func (server *Server) updateUser(ctx *gin.Context) {
 		},
 	}
 
+	// The update also clears email_verified_at when the email changes
 	user, err := server.store.UpdateUser(ctx, arg)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
+	// The new address has to be verified again, a lost email can be sent again
+	if emailChanged {
+		if err := server.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("cannot send verification email to user %d: %v", user.ID, err)
+		}
+	}
+
 	ctx.JSON(http.StatusOK, userResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Bio:       user.Bio,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Bio:             user.Bio,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
 	})
 }
 
 func (server *Server) updateUserPassword(ctx *gin.Context) {
 	authPayload, err := server.getAuthPayload(ctx)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		ctx.Error(newAPIError(http.StatusUnauthorized, "auth.required", err.Error()))
 		return
 	}
 
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
@@ -234,27 +270,27 @@ func (server *Server) updateUserPassword(ctx *gin.Context) {
 	user, err := server.store.GetUser(ctx, int32(id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	if authPayload.Username != user.Username {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "can only update your own password"})
+		ctx.Error(notOwnerError("can only update your own password"))
 		return
 	}
 
 	var req updateUserPasswordRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	passwordHash, err := util.HashPassword(req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+		ctx.Error(err)
 		return
 	}
 
@@ -266,10 +302,10 @@ func (server *Server) updateUserPassword(ctx *gin.Context) {
 	ret, err = server.store.UpdateUserPassword(ctx, arg)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -285,80 +321,117 @@ func (server *Server) getUser(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		ctx.Error(invalidParamError("invalid id"))
 		return
 	}
 
 	user, err := server.store.GetUser(ctx, int32(id))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, userResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Bio:       user.Bio,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Bio:             user.Bio,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
 	})
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	user, err := server.store.GetUserByUsername(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	err = util.CheckPassword(req.Password, user.PasswordHash)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "incorrect password"})
+		ctx.Error(newAPIError(http.StatusUnauthorized, "auth.incorrect_password", "incorrect password"))
+		return
+	}
+
+	accessToken, err := server.tokenMaker.CreateToken(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(user.Username, server.config.AccessTokenDuration)
+	// Start a session the access token can be renewed from
+	refreshToken, refreshTokenHash, err := util.NewOpaqueToken()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
+		return
+	}
+
+	session, err := server.store.CreateSession(ctx, db.CreateSessionParams{
+		UserID:           user.ID,
+		RefreshTokenHash: refreshTokenHash,
+		UserAgent:        truncateUserAgent(ctx.Request.UserAgent()),
+		ClientIp:         ctx.ClientIP(),
+		ExpiresAt:        time.Now().UTC().Add(server.config.RefreshTokenDuration),
+	})
+	if err != nil {
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, loginUserResponse{
-		AccessToken: accessToken,
+		AccessToken:           accessToken,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: session.ExpiresAt,
 		User: userResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Bio:       user.Bio,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
+			ID:              user.ID,
+			Username:        user.Username,
+			Email:           user.Email,
+			FirstName:       user.FirstName,
+			LastName:        user.LastName,
+			Bio:             user.Bio,
+			CreatedAt:       user.CreatedAt,
+			UpdatedAt:       user.UpdatedAt,
+			Role:            user.Role,
+			EmailVerifiedAt: user.EmailVerifiedAt,
 		},
 	})
 }
 
+// maxUserAgentLength is the length of the user_agent column of sessions
+const maxUserAgentLength = 255
+
+// truncateUserAgent shortens a User-Agent header to fit its session column
+func truncateUserAgent(userAgent string) string {
+	runes := []rune(userAgent)
+	if len(runes) <= maxUserAgentLength {
+		return userAgent
+	}
+	return string(runes[:maxUserAgentLength])
+}
+
 func (server *Server) listUsers(ctx *gin.Context) {
 	var req listUsersRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
@@ -369,7 +442,7 @@ func (server *Server) listUsers(ctx *gin.Context) {
 
 	users, err := server.store.ListUsers(ctx, arg)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
@@ -393,28 +466,87 @@ func (server *Server) listUsers(ctx *gin.Context) {
 func (server *Server) getUserByUsername(ctx *gin.Context) {
 	var req getUserByUsernameRequest
 	if err := ctx.ShouldBindQuery(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
 		return
 	}
 
 	user, err := server.store.GetUserByUsername(ctx, req.Username)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			ctx.Error(errUserNotFound)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.Error(err)
 		return
 	}
 
 	ctx.JSON(http.StatusOK, getUserByUsernameResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Bio:       user.Bio,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Bio:             user.Bio,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+	})
+}
+
+// updateUserRole handles an admin assigning a role to a user. The new role is
+// picked up the next time the user logs in or renews their access token.
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*util.Payload)
+
+	idStr := ctx.Param("id")
+	id, err := strconv.ParseInt(idStr, 10, 32)
+	if err != nil {
+		ctx.Error(invalidParamError("invalid id"))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.Error(err).SetType(gin.ErrorTypeBind)
+		return
+	}
+
+	currentUser, err := server.store.GetUser(ctx, int32(id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Error(errUserNotFound)
+			return
+		}
+		ctx.Error(err)
+		return
+	}
+
+	// Keep admins from locking themselves out
+	if currentUser.Username == authPayload.Username {
+		ctx.Error(newAPIError(http.StatusForbidden, "user.cannot_change_own_role", "you cannot change your own role"))
+		return
+	}
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		ID:   currentUser.ID,
+		Role: req.Role,
+	})
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, userResponse{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Bio:             user.Bio,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
 	})
 }
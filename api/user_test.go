@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateUserRole(t *testing.T) {
+	user := db.User{ID: 2, Username: "testuser2", Role: util.RoleAuthor}
+
+	testCases := []struct {
+		name          string
+		userID        int32
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "OK",
+			userID: user.ID,
+			body:   gin.H{"role": util.RoleEditor},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(user, nil)
+
+				updated := user
+				updated.Role = util.RoleEditor
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Eq(db.UpdateUserRoleParams{
+						ID:   user.ID,
+						Role: util.RoleEditor,
+					})).
+					Times(1).
+					Return(updated, nil)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "admin1", util.RoleAdmin)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got userResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &got)
+				require.NoError(t, err)
+				require.Equal(t, util.RoleEditor, got.Role)
+			},
+		},
+		{
+			name:   "InvalidRole",
+			userID: user.ID,
+			body:   gin.H{"role": "superuser"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "admin1", util.RoleAdmin)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "OwnRole",
+			userID: user.ID,
+			body:   gin.H{"role": util.RoleReader},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.ID)).
+					Times(1).
+					Return(db.User{ID: user.ID, Username: "admin1", Role: util.RoleAdmin}, nil)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "admin1", util.RoleAdmin)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "NotAdmin",
+			userID: user.ID,
+			body:   gin.H{"role": util.RoleAdmin},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					UpdateUserRole(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "testuser1", util.RoleEditor)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "UserNotFound",
+			userID: 99,
+			body:   gin.H{"role": util.RoleReader},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(int32(99))).
+					Times(1).
+					Return(db.User{}, sql.ErrNoRows)
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker util.TokenMaker) {
+				token := createTestTokenWithRole(t, tokenMaker, "admin1", util.RoleAdmin)
+				addAuthHeader(request, token)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey: "12345678901234567890123456789012",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/admin/users/%d/role", tc.userID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	user := db.User{
+		ID:              1,
+		Username:        "testuser1",
+		Email:           "old@example.com",
+		Role:            util.RoleAuthor,
+		EmailVerifiedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer)
+	}{
+		{
+			name: "EmailChanged",
+			body: gin.H{"email": "new@example.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq("new@example.com")).Times(1).Return(db.User{}, sql.ErrNoRows)
+				store.EXPECT().RevokeEmailVerificationTokens(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(nil)
+
+				updated := user
+				updated.Email = "new@example.com"
+				updated.EmailVerifiedAt = sql.NullTime{}
+				store.EXPECT().
+					UpdateUser(gomock.Any(), gomock.Eq(db.UpdateUserParams{ID: user.ID, Email: "new@example.com"})).
+					Times(1).
+					Return(updated, nil)
+				store.EXPECT().
+					CreateEmailVerificationToken(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.EmailVerificationToken{ID: 1, UserID: user.ID}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, "new@example.com", got.Email)
+				require.False(t, got.EmailVerifiedAt.Valid)
+
+				require.Len(t, mailer.sent, 1)
+				require.Equal(t, "new@example.com", mailer.sent[0].To)
+			},
+		},
+		{
+			name: "SameEmail",
+			body: gin.H{"email": user.Email, "bio": "Hello"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().RevokeEmailVerificationTokens(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(1).Return(user, nil)
+				store.EXPECT().CreateEmailVerificationToken(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got userResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.True(t, got.EmailVerifiedAt.Valid)
+				require.Empty(t, mailer.sent)
+			},
+		},
+		{
+			name: "EmailTaken",
+			body: gin.H{"email": "taken@example.com"},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.ID)).Times(1).Return(user, nil)
+				store.EXPECT().GetUserByEmail(gomock.Any(), gomock.Eq("taken@example.com")).Times(1).Return(db.User{ID: 2}, nil)
+				store.EXPECT().RevokeEmailVerificationTokens(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, mailer *recordingMailer) {
+				requireErrorEnvelope(t, recorder, http.StatusConflict, "user.email_taken")
+				require.Empty(t, mailer.sent)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			config := util.Config{
+				TokenSymmetricKey:              "12345678901234567890123456789012",
+				EmailVerificationTokenDuration: 24 * time.Hour,
+				AppBaseURL:                     "https://example.com",
+			}
+
+			server, err := NewServer(store, config)
+			require.NoError(t, err)
+			mailer := &recordingMailer{}
+			server.mailer = mailer
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/api/v1/users/%d", user.ID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthHeader(request, createTestToken(t, server.tokenMaker, user.Username))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, mailer)
+		})
+	}
+}
@@ -0,0 +1 @@
+ALTER TABLE "comments" ADD COLUMN "updated_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP);
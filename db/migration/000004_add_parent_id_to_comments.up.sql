@@ -0,0 +1,5 @@
+ALTER TABLE "comments" ADD COLUMN "parent_id" INTEGER;
+
+ALTER TABLE "comments" ADD FOREIGN KEY ("parent_id") REFERENCES "comments" ("id") ON DELETE CASCADE;
+
+CREATE INDEX ON "comments" ("parent_id");
@@ -0,0 +1,12 @@
+CREATE TABLE "post_likes" (
+  "user_id" INTEGER NOT NULL,
+  "post_id" INTEGER NOT NULL,
+  "created_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP),
+  PRIMARY KEY ("user_id", "post_id")
+);
+
+ALTER TABLE "post_likes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "post_likes" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE;
+
+CREATE INDEX ON "post_likes" ("post_id", "created_at");
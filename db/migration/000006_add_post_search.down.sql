@@ -0,0 +1,13 @@
+DROP TRIGGER IF EXISTS "tags_refresh_tag_names" ON "tags";
+
+DROP TRIGGER IF EXISTS "post_tags_refresh_tag_names" ON "post_tags";
+
+DROP FUNCTION IF EXISTS tags_refresh_tag_names();
+
+DROP FUNCTION IF EXISTS post_tags_refresh_tag_names();
+
+DROP FUNCTION IF EXISTS refresh_post_tag_names(INTEGER);
+
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "search_vector";
+
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "tag_names";
@@ -0,0 +1,54 @@
+ALTER TABLE "posts" ADD COLUMN "tag_names" TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE "posts" ADD COLUMN "search_vector" TSVECTOR GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', coalesce("title", '')), 'A') ||
+  setweight(to_tsvector('english', "tag_names"), 'B') ||
+  setweight(to_tsvector('english', coalesce("content", '')), 'C')
+) STORED;
+
+CREATE INDEX ON "posts" USING GIN ("search_vector");
+
+-- Generated columns cannot read other tables, so the tag names a post is
+-- indexed under are copied onto it whenever its tags change.
+CREATE FUNCTION refresh_post_tag_names(target_post_id INTEGER) RETURNS VOID AS $$
+  UPDATE "posts"
+  SET "tag_names" = COALESCE((
+    SELECT string_agg(t."name", ' ' ORDER BY t."name")
+    FROM "post_tags" pt
+    JOIN "tags" t ON pt."tag_id" = t."id"
+    WHERE pt."post_id" = target_post_id
+  ), '')
+  WHERE "id" = target_post_id;
+$$ LANGUAGE sql;
+
+CREATE FUNCTION post_tags_refresh_tag_names() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP IN ('UPDATE', 'DELETE') THEN
+    PERFORM refresh_post_tag_names(OLD."post_id");
+  END IF;
+  IF TG_OP IN ('INSERT', 'UPDATE') THEN
+    PERFORM refresh_post_tag_names(NEW."post_id");
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "post_tags_refresh_tag_names"
+AFTER INSERT OR UPDATE OR DELETE ON "post_tags"
+FOR EACH ROW EXECUTE FUNCTION post_tags_refresh_tag_names();
+
+CREATE FUNCTION tags_refresh_tag_names() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM refresh_post_tag_names(pt."post_id")
+  FROM "post_tags" pt
+  WHERE pt."tag_id" = NEW."id";
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "tags_refresh_tag_names"
+AFTER UPDATE OF "name" ON "tags"
+FOR EACH ROW EXECUTE FUNCTION tags_refresh_tag_names();
+
+-- Backfill posts tagged before this migration
+SELECT refresh_post_tag_names("post_id") FROM "post_tags" GROUP BY "post_id";
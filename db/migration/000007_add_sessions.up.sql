@@ -0,0 +1,14 @@
+CREATE TABLE "sessions" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INTEGER NOT NULL,
+  "refresh_token_hash" VARCHAR(64) UNIQUE NOT NULL,
+  "user_agent" VARCHAR(255) NOT NULL DEFAULT '',
+  "client_ip" VARCHAR(45) NOT NULL DEFAULT '',
+  "expires_at" TIMESTAMP NOT NULL,
+  "revoked_at" TIMESTAMP,
+  "created_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE "sessions" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX ON "sessions" ("user_id");
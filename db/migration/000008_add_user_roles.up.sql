@@ -0,0 +1,6 @@
+ALTER TABLE "users" ADD COLUMN "role" VARCHAR(20) NOT NULL DEFAULT 'author';
+
+ALTER TABLE "users" ADD CONSTRAINT "users_role_check" CHECK ("role" IN ('admin', 'editor', 'author', 'reader'));
+
+-- The site owner registered first, they become the initial admin
+UPDATE "users" SET "role" = 'admin' WHERE "id" = (SELECT MIN("id") FROM "users");
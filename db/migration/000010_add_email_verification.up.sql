@@ -0,0 +1,14 @@
+ALTER TABLE "users" ADD COLUMN "email_verified_at" TIMESTAMP;
+
+CREATE TABLE "email_verification_tokens" (
+  "id" SERIAL PRIMARY KEY,
+  "user_id" INTEGER NOT NULL,
+  "token_hash" VARCHAR(64) UNIQUE NOT NULL,
+  "expires_at" TIMESTAMP NOT NULL,
+  "used_at" TIMESTAMP,
+  "created_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE "email_verification_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+CREATE INDEX ON "email_verification_tokens" ("user_id");
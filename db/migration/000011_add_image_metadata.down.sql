@@ -0,0 +1,3 @@
+ALTER TABLE "images" DROP COLUMN IF EXISTS "checksum";
+ALTER TABLE "images" DROP COLUMN IF EXISTS "mime_type";
+ALTER TABLE "images" DROP COLUMN IF EXISTS "size_bytes";
@@ -0,0 +1,3 @@
+ALTER TABLE "images" ADD COLUMN "size_bytes" BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE "images" ADD COLUMN "mime_type" VARCHAR(100) NOT NULL DEFAULT '';
+ALTER TABLE "images" ADD COLUMN "checksum" VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS image_variants;
+
+ALTER TABLE "images" DROP COLUMN IF EXISTS "height";
+ALTER TABLE "images" DROP COLUMN IF EXISTS "width";
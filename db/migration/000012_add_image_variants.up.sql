@@ -0,0 +1,17 @@
+ALTER TABLE "images" ADD COLUMN "width" INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE "images" ADD COLUMN "height" INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE "image_variants" (
+  "id" SERIAL PRIMARY KEY,
+  "image_id" INTEGER NOT NULL,
+  "name" VARCHAR(20) NOT NULL,
+  "file_path" VARCHAR(255) NOT NULL,
+  "mime_type" VARCHAR(100) NOT NULL,
+  "width" INTEGER NOT NULL,
+  "height" INTEGER NOT NULL,
+  "size_bytes" BIGINT NOT NULL,
+  "created_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP),
+  UNIQUE ("image_id", "name")
+);
+
+ALTER TABLE "image_variants" ADD FOREIGN KEY ("image_id") REFERENCES "images" ("id") ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+-- Set once EXIF, GPS and other metadata has been stripped from the stored
+-- blob. Images uploaded before this was done stay NULL.
+ALTER TABLE "images" ADD COLUMN "sanitized_at" TIMESTAMP;
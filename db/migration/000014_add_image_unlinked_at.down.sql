@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS "post_images_mark_unlinked" ON "post_images";
+
+DROP FUNCTION IF EXISTS post_images_mark_unlinked();
+
+DROP INDEX IF EXISTS "post_images_image_id_idx";
+
+ALTER TABLE "images" DROP COLUMN IF EXISTS "unlinked_at";
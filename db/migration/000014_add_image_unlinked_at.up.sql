@@ -0,0 +1,18 @@
+ALTER TABLE "images" ADD COLUMN "unlinked_at" TIMESTAMP;
+
+CREATE INDEX ON "post_images" ("image_id");
+
+-- Removing an image from a post, directly or through the cascade when the
+-- post is deleted, starts the grace period before it may be collected.
+CREATE FUNCTION post_images_mark_unlinked() RETURNS TRIGGER AS $$
+BEGIN
+  UPDATE "images"
+  SET "unlinked_at" = CURRENT_TIMESTAMP
+  WHERE "id" = OLD."image_id";
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "post_images_mark_unlinked"
+AFTER DELETE ON "post_images"
+FOR EACH ROW EXECUTE FUNCTION post_images_mark_unlinked();
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "images_user_id_checksum_idx";
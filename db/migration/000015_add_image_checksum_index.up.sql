@@ -0,0 +1 @@
+CREATE INDEX ON "images" ("user_id", "checksum") WHERE "checksum" <> '';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS "posts_publish_at_idx";
+
+ALTER TABLE "posts" DROP CONSTRAINT IF EXISTS "posts_publish_at_check";
+ALTER TABLE "posts" DROP CONSTRAINT IF EXISTS "posts_status_check";
+
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "publish_at";
@@ -0,0 +1,14 @@
+-- Statuses used to be free-form. Anything that is not a known status becomes
+-- a draft so it stays private until its author publishes it again.
+UPDATE "posts" SET "status" = lower(trim("status")) WHERE "status" IS NOT NULL;
+UPDATE "posts" SET "status" = 'draft'
+WHERE "status" IS NULL OR "status" NOT IN ('draft', 'published', 'unlisted', 'archived');
+
+-- When a scheduled post goes live, for published posts when they went live
+ALTER TABLE "posts" ADD COLUMN "publish_at" TIMESTAMP;
+UPDATE "posts" SET "publish_at" = "created_at" WHERE "status" IN ('published', 'unlisted');
+
+ALTER TABLE "posts" ADD CONSTRAINT "posts_status_check" CHECK ("status" IN ('draft', 'scheduled', 'published', 'unlisted', 'archived'));
+ALTER TABLE "posts" ADD CONSTRAINT "posts_publish_at_check" CHECK ("status" <> 'scheduled' OR "publish_at" IS NOT NULL);
+
+CREATE INDEX ON "posts" ("publish_at") WHERE "status" = 'scheduled';
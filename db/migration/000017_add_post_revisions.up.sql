@@ -0,0 +1,16 @@
+-- Each row keeps a version of a post as it was before an edit replaced it
+CREATE TABLE "post_revisions" (
+  "id" SERIAL PRIMARY KEY,
+  "post_id" INTEGER NOT NULL,
+  "revision" INTEGER NOT NULL,
+  "title" VARCHAR(255) NOT NULL,
+  "content" TEXT NOT NULL,
+  "type" VARCHAR(50) NOT NULL,
+  "edited_by" INTEGER,
+  "created_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP),
+  UNIQUE ("post_id", "revision")
+);
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "post_revisions" ADD FOREIGN KEY ("edited_by") REFERENCES "users" ("id") ON DELETE SET NULL;
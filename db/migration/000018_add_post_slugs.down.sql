@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS "post_slug_redirects";
+
+ALTER TABLE "posts" DROP CONSTRAINT IF EXISTS "posts_slug_key";
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "slug";
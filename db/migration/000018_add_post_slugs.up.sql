@@ -0,0 +1,41 @@
+-- Slugs for existing posts come from their titles. Accented letters lose
+-- their marks, titles with nothing left get "post" and later posts repeating a
+-- slug get the first free -2, -3, ... suffix, as new posts do. This only
+-- approximates util.Slugify, the server renames the posts whose slug differs
+-- from what Slugify makes of their title when it starts.
+ALTER TABLE "posts" ADD COLUMN "slug" VARCHAR(255);
+
+UPDATE "posts" SET "slug" = trim(BOTH '-' FROM regexp_replace(
+  translate(lower("title"), 'àáâãäåçèéêëìíîïñòóôõöøùúûüýÿ', 'aaaaaaceeeeiiiinoooooouuuuyy'),
+  '[^a-z0-9]+', '-', 'g'
+));
+UPDATE "posts" SET "slug" = 'post' WHERE "slug" = '';
+DO $$
+DECLARE
+  dup RECORD;
+  n INTEGER;
+BEGIN
+  FOR dup IN
+    SELECT "id", "slug" FROM "posts"
+    WHERE "id" NOT IN (SELECT min("id") FROM "posts" GROUP BY "slug")
+    ORDER BY "id"
+  LOOP
+    n := 2;
+    WHILE EXISTS (SELECT 1 FROM "posts" WHERE "slug" = dup."slug" || '-' || n) LOOP
+      n := n + 1;
+    END LOOP;
+    UPDATE "posts" SET "slug" = dup."slug" || '-' || n WHERE "id" = dup."id";
+  END LOOP;
+END $$;
+
+ALTER TABLE "posts" ALTER COLUMN "slug" SET NOT NULL;
+ALTER TABLE "posts" ADD CONSTRAINT "posts_slug_key" UNIQUE ("slug");
+
+-- Slugs a post had before its title changed, so links to them keep working
+CREATE TABLE "post_slug_redirects" (
+  "slug" VARCHAR(255) PRIMARY KEY,
+  "post_id" INTEGER NOT NULL,
+  "created_at" TIMESTAMP DEFAULT (CURRENT_TIMESTAMP)
+);
+
+ALTER TABLE "post_slug_redirects" ADD FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON DELETE CASCADE;
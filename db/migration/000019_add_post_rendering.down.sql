@@ -0,0 +1,5 @@
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "toc";
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "content_html";
+ALTER TABLE "post_revisions" DROP COLUMN IF EXISTS "format";
+ALTER TABLE "posts" DROP CONSTRAINT IF EXISTS "posts_format_check";
+ALTER TABLE "posts" DROP COLUMN IF EXISTS "format";
@@ -0,0 +1,11 @@
+-- The format content is written in, existing posts are Markdown
+ALTER TABLE "posts" ADD COLUMN "format" VARCHAR(20) NOT NULL DEFAULT 'markdown';
+ALTER TABLE "posts" ADD CONSTRAINT "posts_format_check" CHECK ("format" IN ('markdown', 'html', 'plain'));
+
+-- Revisions keep the format their content was written in
+ALTER TABLE "post_revisions" ADD COLUMN "format" VARCHAR(20) NOT NULL DEFAULT 'markdown';
+
+-- Sanitized HTML and table of contents rendered from the content. NULL means
+-- the post was not rendered since its content last changed.
+ALTER TABLE "posts" ADD COLUMN "content_html" TEXT;
+ALTER TABLE "posts" ADD COLUMN "toc" JSONB;
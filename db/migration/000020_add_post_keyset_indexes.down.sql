@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "posts_likes_id_idx";
+DROP INDEX IF EXISTS "posts_created_at_id_idx";
@@ -0,0 +1,4 @@
+-- Post lists page through posts newest first or most liked first, with the
+-- id breaking ties, by seeking past the last post of the previous page
+CREATE INDEX ON "posts" ("created_at" DESC, "id" DESC);
+CREATE INDEX ON "posts" ("likes" DESC, "id" DESC);
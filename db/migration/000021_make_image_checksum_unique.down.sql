@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "images_user_id_checksum_key";
+CREATE INDEX ON "images" ("user_id", "checksum") WHERE "checksum" <> '';
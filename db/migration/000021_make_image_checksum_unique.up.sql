@@ -0,0 +1,12 @@
+-- An upload racing an identical one of the same user must find its image
+-- instead of adding a second copy. Duplicates uploaded before images were
+-- deduplicated keep their files but leave deduplication to the oldest copy.
+-- Their recorded type still marks them as uploads, so their blobs are deleted
+-- with them.
+UPDATE "images" SET "checksum" = ''
+WHERE "checksum" <> '' AND "id" NOT IN (
+  SELECT min("id") FROM "images" WHERE "checksum" <> '' GROUP BY "user_id", "checksum"
+);
+
+DROP INDEX IF EXISTS "images_user_id_checksum_idx";
+CREATE UNIQUE INDEX "images_user_id_checksum_key" ON "images" ("user_id", "checksum") WHERE "checksum" <> '';
@@ -8,6 +8,7 @@ import (
 	context "context"
 	sql "database/sql"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
@@ -79,6 +80,21 @@ func (mr *MockStoreMockRecorder) AddPostTagTx(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPostTagTx", reflect.TypeOf((*MockStore)(nil).AddPostTagTx), arg0, arg1)
 }
 
+// BackfillPostSlugs mocks base method.
+func (m *MockStore) BackfillPostSlugs(arg0 context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BackfillPostSlugs", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BackfillPostSlugs indicates an expected call of BackfillPostSlugs.
+func (mr *MockStoreMockRecorder) BackfillPostSlugs(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BackfillPostSlugs", reflect.TypeOf((*MockStore)(nil).BackfillPostSlugs), arg0)
+}
+
 // BatchAddPostTagsTx mocks base method.
 func (m *MockStore) BatchAddPostTagsTx(arg0 context.Context, arg1 db.BatchAddPostTagsParams) ([]db.PostTag, error) {
 	m.ctrl.T.Helper()
@@ -94,6 +110,66 @@ func (mr *MockStoreMockRecorder) BatchAddPostTagsTx(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchAddPostTagsTx", reflect.TypeOf((*MockStore)(nil).BatchAddPostTagsTx), arg0, arg1)
 }
 
+// CountFilteredPosts mocks base method.
+func (m *MockStore) CountFilteredPosts(arg0 context.Context, arg1 db.FilterParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFilteredPosts", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFilteredPosts indicates an expected call of CountFilteredPosts.
+func (mr *MockStoreMockRecorder) CountFilteredPosts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFilteredPosts", reflect.TypeOf((*MockStore)(nil).CountFilteredPosts), arg0, arg1)
+}
+
+// CountPosts mocks base method.
+func (m *MockStore) CountPosts(arg0 context.Context, arg1 db.CountPostsParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPosts", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPosts indicates an expected call of CountPosts.
+func (mr *MockStoreMockRecorder) CountPosts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPosts", reflect.TypeOf((*MockStore)(nil).CountPosts), arg0, arg1)
+}
+
+// CountSearchPosts mocks base method.
+func (m *MockStore) CountSearchPosts(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountSearchPosts", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountSearchPosts indicates an expected call of CountSearchPosts.
+func (mr *MockStoreMockRecorder) CountSearchPosts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountSearchPosts", reflect.TypeOf((*MockStore)(nil).CountSearchPosts), arg0, arg1)
+}
+
+// CountSitemapEntries mocks base method.
+func (m *MockStore) CountSitemapEntries(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountSitemapEntries", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountSitemapEntries indicates an expected call of CountSitemapEntries.
+func (mr *MockStoreMockRecorder) CountSitemapEntries(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountSitemapEntries", reflect.TypeOf((*MockStore)(nil).CountSitemapEntries), arg0)
+}
+
 // CreateComment mocks base method.
 func (m *MockStore) CreateComment(arg0 context.Context, arg1 db.CreateCommentParams) (db.Comment, error) {
 	m.ctrl.T.Helper()
@@ -109,6 +185,21 @@ func (mr *MockStoreMockRecorder) CreateComment(arg0, arg1 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateComment", reflect.TypeOf((*MockStore)(nil).CreateComment), arg0, arg1)
 }
 
+// CreateEmailVerificationToken mocks base method.
+func (m *MockStore) CreateEmailVerificationToken(arg0 context.Context, arg1 db.CreateEmailVerificationTokenParams) (db.EmailVerificationToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEmailVerificationToken", arg0, arg1)
+	ret0, _ := ret[0].(db.EmailVerificationToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEmailVerificationToken indicates an expected call of CreateEmailVerificationToken.
+func (mr *MockStoreMockRecorder) CreateEmailVerificationToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmailVerificationToken", reflect.TypeOf((*MockStore)(nil).CreateEmailVerificationToken), arg0, arg1)
+}
+
 // CreateImage mocks base method.
 func (m *MockStore) CreateImage(arg0 context.Context, arg1 db.CreateImageParams) (db.Image, error) {
 	m.ctrl.T.Helper()
@@ -124,6 +215,36 @@ func (mr *MockStoreMockRecorder) CreateImage(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImage", reflect.TypeOf((*MockStore)(nil).CreateImage), arg0, arg1)
 }
 
+// CreateImageVariant mocks base method.
+func (m *MockStore) CreateImageVariant(arg0 context.Context, arg1 db.CreateImageVariantParams) (db.ImageVariant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImageVariant", arg0, arg1)
+	ret0, _ := ret[0].(db.ImageVariant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateImageVariant indicates an expected call of CreateImageVariant.
+func (mr *MockStoreMockRecorder) CreateImageVariant(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImageVariant", reflect.TypeOf((*MockStore)(nil).CreateImageVariant), arg0, arg1)
+}
+
+// CreatePasswordResetToken mocks base method.
+func (m *MockStore) CreatePasswordResetToken(arg0 context.Context, arg1 db.CreatePasswordResetTokenParams) (db.PasswordResetToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordResetToken", arg0, arg1)
+	ret0, _ := ret[0].(db.PasswordResetToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePasswordResetToken indicates an expected call of CreatePasswordResetToken.
+func (mr *MockStoreMockRecorder) CreatePasswordResetToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordResetToken", reflect.TypeOf((*MockStore)(nil).CreatePasswordResetToken), arg0, arg1)
+}
+
 // CreatePost mocks base method.
 func (m *MockStore) CreatePost(arg0 context.Context, arg1 db.CreatePostParams) (db.Post, error) {
 	m.ctrl.T.Helper()
@@ -139,6 +260,50 @@ func (mr *MockStoreMockRecorder) CreatePost(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePost", reflect.TypeOf((*MockStore)(nil).CreatePost), arg0, arg1)
 }
 
+// CreatePostLike mocks base method.
+func (m *MockStore) CreatePostLike(arg0 context.Context, arg1 db.CreatePostLikeParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostLike", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePostLike indicates an expected call of CreatePostLike.
+func (mr *MockStoreMockRecorder) CreatePostLike(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostLike", reflect.TypeOf((*MockStore)(nil).CreatePostLike), arg0, arg1)
+}
+
+// CreatePostRevision mocks base method.
+func (m *MockStore) CreatePostRevision(arg0 context.Context, arg1 db.CreatePostRevisionParams) (db.PostRevision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostRevision", arg0, arg1)
+	ret0, _ := ret[0].(db.PostRevision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePostRevision indicates an expected call of CreatePostRevision.
+func (mr *MockStoreMockRecorder) CreatePostRevision(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostRevision", reflect.TypeOf((*MockStore)(nil).CreatePostRevision), arg0, arg1)
+}
+
+// CreatePostSlugRedirect mocks base method.
+func (m *MockStore) CreatePostSlugRedirect(arg0 context.Context, arg1 db.CreatePostSlugRedirectParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostSlugRedirect", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePostSlugRedirect indicates an expected call of CreatePostSlugRedirect.
+func (mr *MockStoreMockRecorder) CreatePostSlugRedirect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostSlugRedirect", reflect.TypeOf((*MockStore)(nil).CreatePostSlugRedirect), arg0, arg1)
+}
+
 // CreatePostTag mocks base method.
 func (m *MockStore) CreatePostTag(arg0 context.Context, arg1 db.CreatePostTagParams) (db.PostTag, error) {
 	m.ctrl.T.Helper()
@@ -169,6 +334,36 @@ func (mr *MockStoreMockRecorder) CreatePostTx(arg0, arg1 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostTx", reflect.TypeOf((*MockStore)(nil).CreatePostTx), arg0, arg1)
 }
 
+// CreatePostWithSlugTx mocks base method.
+func (m *MockStore) CreatePostWithSlugTx(arg0 context.Context, arg1 db.CreatePostParams) (db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePostWithSlugTx", arg0, arg1)
+	ret0, _ := ret[0].(db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePostWithSlugTx indicates an expected call of CreatePostWithSlugTx.
+func (mr *MockStoreMockRecorder) CreatePostWithSlugTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePostWithSlugTx", reflect.TypeOf((*MockStore)(nil).CreatePostWithSlugTx), arg0, arg1)
+}
+
+// CreateSession mocks base method.
+func (m *MockStore) CreateSession(arg0 context.Context, arg1 db.CreateSessionParams) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockStoreMockRecorder) CreateSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), arg0, arg1)
+}
+
 // CreateTag mocks base method.
 func (m *MockStore) CreateTag(arg0 context.Context, arg1 string) (db.Tag, error) {
 	m.ctrl.T.Helper()
@@ -199,6 +394,35 @@ func (mr *MockStoreMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), arg0, arg1)
 }
 
+// DecrementPostLikes mocks base method.
+func (m *MockStore) DecrementPostLikes(arg0 context.Context, arg1 int32) (db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DecrementPostLikes", arg0, arg1)
+	ret0, _ := ret[0].(db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DecrementPostLikes indicates an expected call of DecrementPostLikes.
+func (mr *MockStoreMockRecorder) DecrementPostLikes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DecrementPostLikes", reflect.TypeOf((*MockStore)(nil).DecrementPostLikes), arg0, arg1)
+}
+
+// DeleteComment mocks base method.
+func (m *MockStore) DeleteComment(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteComment", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteComment indicates an expected call of DeleteComment.
+func (mr *MockStoreMockRecorder) DeleteComment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteComment", reflect.TypeOf((*MockStore)(nil).DeleteComment), arg0, arg1)
+}
+
 // DeleteImage mocks base method.
 func (m *MockStore) DeleteImage(arg0 context.Context, arg1 int32) error {
 	m.ctrl.T.Helper()
@@ -213,6 +437,36 @@ func (mr *MockStoreMockRecorder) DeleteImage(arg0, arg1 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockStore)(nil).DeleteImage), arg0, arg1)
 }
 
+// DeleteOrphanedImage mocks base method.
+func (m *MockStore) DeleteOrphanedImage(arg0 context.Context, arg1 int32) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrphanedImage", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOrphanedImage indicates an expected call of DeleteOrphanedImage.
+func (mr *MockStoreMockRecorder) DeleteOrphanedImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrphanedImage", reflect.TypeOf((*MockStore)(nil).DeleteOrphanedImage), arg0, arg1)
+}
+
+// DeleteOrphanedImageTx mocks base method.
+func (m *MockStore) DeleteOrphanedImageTx(arg0 context.Context, arg1 int32) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrphanedImageTx", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOrphanedImageTx indicates an expected call of DeleteOrphanedImageTx.
+func (mr *MockStoreMockRecorder) DeleteOrphanedImageTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrphanedImageTx", reflect.TypeOf((*MockStore)(nil).DeleteOrphanedImageTx), arg0, arg1)
+}
+
 // DeletePost mocks base method.
 func (m *MockStore) DeletePost(arg0 context.Context, arg1 int32) error {
 	m.ctrl.T.Helper()
@@ -227,6 +481,35 @@ func (mr *MockStoreMockRecorder) DeletePost(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePost", reflect.TypeOf((*MockStore)(nil).DeletePost), arg0, arg1)
 }
 
+// DeletePostLike mocks base method.
+func (m *MockStore) DeletePostLike(arg0 context.Context, arg1 db.DeletePostLikeParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePostLike", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeletePostLike indicates an expected call of DeletePostLike.
+func (mr *MockStoreMockRecorder) DeletePostLike(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePostLike", reflect.TypeOf((*MockStore)(nil).DeletePostLike), arg0, arg1)
+}
+
+// DeletePostSlugRedirect mocks base method.
+func (m *MockStore) DeletePostSlugRedirect(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePostSlugRedirect", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePostSlugRedirect indicates an expected call of DeletePostSlugRedirect.
+func (mr *MockStoreMockRecorder) DeletePostSlugRedirect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePostSlugRedirect", reflect.TypeOf((*MockStore)(nil).DeletePostSlugRedirect), arg0, arg1)
+}
+
 // DeletePostTag mocks base method.
 func (m *MockStore) DeletePostTag(arg0 context.Context, arg1 db.DeletePostTagParams) error {
 	m.ctrl.T.Helper()
@@ -283,190 +566,640 @@ func (mr *MockStoreMockRecorder) DeleteTagFromPosts(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTagFromPosts", reflect.TypeOf((*MockStore)(nil).DeleteTagFromPosts), arg0, arg1)
 }
 
-// GetImage mocks base method.
-func (m *MockStore) GetImage(arg0 context.Context, arg1 int32) (db.Image, error) {
+// EditPostTx mocks base method.
+func (m *MockStore) EditPostTx(arg0 context.Context, arg1 db.EditPostTxParams) (db.EditPostTxResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetImage", arg0, arg1)
-	ret0, _ := ret[0].(db.Image)
+	ret := m.ctrl.Call(m, "EditPostTx", arg0, arg1)
+	ret0, _ := ret[0].(db.EditPostTxResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetImage indicates an expected call of GetImage.
-func (mr *MockStoreMockRecorder) GetImage(arg0, arg1 interface{}) *gomock.Call {
+// EditPostTx indicates an expected call of EditPostTx.
+func (mr *MockStoreMockRecorder) EditPostTx(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockStore)(nil).GetImage), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EditPostTx", reflect.TypeOf((*MockStore)(nil).EditPostTx), arg0, arg1)
 }
 
-// GetPost mocks base method.
-func (m *MockStore) GetPost(arg0 context.Context, arg1 int32) (db.GetPostRow, error) {
+// FilterPosts mocks base method.
+func (m *MockStore) FilterPosts(arg0 context.Context, arg1 db.FilterParams) ([]db.FilteredPost, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPost", arg0, arg1)
-	ret0, _ := ret[0].(db.GetPostRow)
+	ret := m.ctrl.Call(m, "FilterPosts", arg0, arg1)
+	ret0, _ := ret[0].([]db.FilteredPost)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPost indicates an expected call of GetPost.
-func (mr *MockStoreMockRecorder) GetPost(arg0, arg1 interface{}) *gomock.Call {
+// FilterPosts indicates an expected call of FilterPosts.
+func (mr *MockStoreMockRecorder) FilterPosts(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPost", reflect.TypeOf((*MockStore)(nil).GetPost), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FilterPosts", reflect.TypeOf((*MockStore)(nil).FilterPosts), arg0, arg1)
 }
 
-// GetPostTag mocks base method.
-func (m *MockStore) GetPostTag(arg0 context.Context, arg1 db.GetPostTagParams) (db.PostTag, error) {
+// GetComment mocks base method.
+func (m *MockStore) GetComment(arg0 context.Context, arg1 int32) (db.Comment, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetPostTag", arg0, arg1)
-	ret0, _ := ret[0].(db.PostTag)
+	ret := m.ctrl.Call(m, "GetComment", arg0, arg1)
+	ret0, _ := ret[0].(db.Comment)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetPostTag indicates an expected call of GetPostTag.
-func (mr *MockStoreMockRecorder) GetPostTag(arg0, arg1 interface{}) *gomock.Call {
+// GetComment indicates an expected call of GetComment.
+func (mr *MockStoreMockRecorder) GetComment(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostTag", reflect.TypeOf((*MockStore)(nil).GetPostTag), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetComment", reflect.TypeOf((*MockStore)(nil).GetComment), arg0, arg1)
 }
 
-// GetTag mocks base method.
-func (m *MockStore) GetTag(arg0 context.Context, arg1 int32) (db.Tag, error) {
+// GetEmailVerificationTokenByHash mocks base method.
+func (m *MockStore) GetEmailVerificationTokenByHash(arg0 context.Context, arg1 string) (db.EmailVerificationToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTag", arg0, arg1)
-	ret0, _ := ret[0].(db.Tag)
+	ret := m.ctrl.Call(m, "GetEmailVerificationTokenByHash", arg0, arg1)
+	ret0, _ := ret[0].(db.EmailVerificationToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTag indicates an expected call of GetTag.
-func (mr *MockStoreMockRecorder) GetTag(arg0, arg1 interface{}) *gomock.Call {
+// GetEmailVerificationTokenByHash indicates an expected call of GetEmailVerificationTokenByHash.
+func (mr *MockStoreMockRecorder) GetEmailVerificationTokenByHash(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTag", reflect.TypeOf((*MockStore)(nil).GetTag), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEmailVerificationTokenByHash", reflect.TypeOf((*MockStore)(nil).GetEmailVerificationTokenByHash), arg0, arg1)
 }
 
-// GetUser mocks base method.
-func (m *MockStore) GetUser(arg0 context.Context, arg1 int32) (db.User, error) {
+// GetImage mocks base method.
+func (m *MockStore) GetImage(arg0 context.Context, arg1 int32) (db.Image, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "GetImage", arg0, arg1)
+	ret0, _ := ret[0].(db.Image)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUser indicates an expected call of GetUser.
-func (mr *MockStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+// GetImage indicates an expected call of GetImage.
+func (mr *MockStoreMockRecorder) GetImage(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStore)(nil).GetUser), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImage", reflect.TypeOf((*MockStore)(nil).GetImage), arg0, arg1)
 }
 
-// GetUserByEmail mocks base method.
-func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (db.User, error) {
+// GetNextPostImageOrder mocks base method.
+func (m *MockStore) GetNextPostImageOrder(arg0 context.Context, arg1 int32) (int32, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "GetNextPostImageOrder", arg0, arg1)
+	ret0, _ := ret[0].(int32)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByEmail indicates an expected call of GetUserByEmail.
-func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 interface{}) *gomock.Call {
+// GetNextPostImageOrder indicates an expected call of GetNextPostImageOrder.
+func (mr *MockStoreMockRecorder) GetNextPostImageOrder(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNextPostImageOrder", reflect.TypeOf((*MockStore)(nil).GetNextPostImageOrder), arg0, arg1)
 }
 
-// GetUserByUsername mocks base method.
-func (m *MockStore) GetUserByUsername(arg0 context.Context, arg1 string) (db.User, error) {
+// GetPasswordResetTokenByHash mocks base method.
+func (m *MockStore) GetPasswordResetTokenByHash(arg0 context.Context, arg1 string) (db.PasswordResetToken, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUserByUsername", arg0, arg1)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "GetPasswordResetTokenByHash", arg0, arg1)
+	ret0, _ := ret[0].(db.PasswordResetToken)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUserByUsername indicates an expected call of GetUserByUsername.
-func (mr *MockStoreMockRecorder) GetUserByUsername(arg0, arg1 interface{}) *gomock.Call {
+// GetPasswordResetTokenByHash indicates an expected call of GetPasswordResetTokenByHash.
+func (mr *MockStoreMockRecorder) GetPasswordResetTokenByHash(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockStore)(nil).GetUserByUsername), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordResetTokenByHash", reflect.TypeOf((*MockStore)(nil).GetPasswordResetTokenByHash), arg0, arg1)
 }
 
-// ListPostComments mocks base method.
-func (m *MockStore) ListPostComments(arg0 context.Context, arg1 sql.NullInt32) ([]db.ListPostCommentsRow, error) {
+// GetPost mocks base method.
+func (m *MockStore) GetPost(arg0 context.Context, arg1 int32) (db.GetPostRow, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListPostComments", arg0, arg1)
-	ret0, _ := ret[0].([]db.ListPostCommentsRow)
+	ret := m.ctrl.Call(m, "GetPost", arg0, arg1)
+	ret0, _ := ret[0].(db.GetPostRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListPostComments indicates an expected call of ListPostComments.
-func (mr *MockStoreMockRecorder) ListPostComments(arg0, arg1 interface{}) *gomock.Call {
+// GetPost indicates an expected call of GetPost.
+func (mr *MockStoreMockRecorder) GetPost(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostComments", reflect.TypeOf((*MockStore)(nil).ListPostComments), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPost", reflect.TypeOf((*MockStore)(nil).GetPost), arg0, arg1)
 }
 
-// ListPostTags mocks base method.
-func (m *MockStore) ListPostTags(arg0 context.Context, arg1 int32) ([]db.Tag, error) {
+// GetPostForUpdate mocks base method.
+func (m *MockStore) GetPostForUpdate(arg0 context.Context, arg1 int32) (db.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListPostTags", arg0, arg1)
-	ret0, _ := ret[0].([]db.Tag)
+	ret := m.ctrl.Call(m, "GetPostForUpdate", arg0, arg1)
+	ret0, _ := ret[0].(db.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListPostTags indicates an expected call of ListPostTags.
-func (mr *MockStoreMockRecorder) ListPostTags(arg0, arg1 interface{}) *gomock.Call {
+// GetPostForUpdate indicates an expected call of GetPostForUpdate.
+func (mr *MockStoreMockRecorder) GetPostForUpdate(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostTags", reflect.TypeOf((*MockStore)(nil).ListPostTags), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostForUpdate", reflect.TypeOf((*MockStore)(nil).GetPostForUpdate), arg0, arg1)
 }
 
-// ListPosts mocks base method.
-func (m *MockStore) ListPosts(arg0 context.Context, arg1 db.ListPostsParams) ([]db.ListPostsRow, error) {
+// GetPostIDBySlug mocks base method.
+func (m *MockStore) GetPostIDBySlug(arg0 context.Context, arg1 string) (int32, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListPosts", arg0, arg1)
-	ret0, _ := ret[0].([]db.ListPostsRow)
+	ret := m.ctrl.Call(m, "GetPostIDBySlug", arg0, arg1)
+	ret0, _ := ret[0].(int32)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListPosts indicates an expected call of ListPosts.
-func (mr *MockStoreMockRecorder) ListPosts(arg0, arg1 interface{}) *gomock.Call {
+// GetPostIDBySlug indicates an expected call of GetPostIDBySlug.
+func (mr *MockStoreMockRecorder) GetPostIDBySlug(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPosts", reflect.TypeOf((*MockStore)(nil).ListPosts), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostIDBySlug", reflect.TypeOf((*MockStore)(nil).GetPostIDBySlug), arg0, arg1)
 }
 
-// ListTags mocks base method.
-func (m *MockStore) ListTags(arg0 context.Context) ([]db.Tag, error) {
+// GetPostLike mocks base method.
+func (m *MockStore) GetPostLike(arg0 context.Context, arg1 db.GetPostLikeParams) (db.PostLike, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListTags", arg0)
-	ret0, _ := ret[0].([]db.Tag)
+	ret := m.ctrl.Call(m, "GetPostLike", arg0, arg1)
+	ret0, _ := ret[0].(db.PostLike)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListTags indicates an expected call of ListTags.
-func (mr *MockStoreMockRecorder) ListTags(arg0 interface{}) *gomock.Call {
+// GetPostLike indicates an expected call of GetPostLike.
+func (mr *MockStoreMockRecorder) GetPostLike(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockStore)(nil).ListTags), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostLike", reflect.TypeOf((*MockStore)(nil).GetPostLike), arg0, arg1)
 }
 
-// ListUserImages mocks base method.
-func (m *MockStore) ListUserImages(arg0 context.Context, arg1 db.ListUserImagesParams) ([]db.Image, error) {
+// GetPostRevision mocks base method.
+func (m *MockStore) GetPostRevision(arg0 context.Context, arg1 db.GetPostRevisionParams) (db.PostRevision, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListUserImages", arg0, arg1)
-	ret0, _ := ret[0].([]db.Image)
+	ret := m.ctrl.Call(m, "GetPostRevision", arg0, arg1)
+	ret0, _ := ret[0].(db.PostRevision)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListUserImages indicates an expected call of ListUserImages.
-func (mr *MockStoreMockRecorder) ListUserImages(arg0, arg1 interface{}) *gomock.Call {
+// GetPostRevision indicates an expected call of GetPostRevision.
+func (mr *MockStoreMockRecorder) GetPostRevision(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserImages", reflect.TypeOf((*MockStore)(nil).ListUserImages), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostRevision", reflect.TypeOf((*MockStore)(nil).GetPostRevision), arg0, arg1)
 }
 
-// ListUsers mocks base method.
-func (m *MockStore) ListUsers(arg0 context.Context, arg1 db.ListUsersParams) ([]db.ListUsersRow, error) {
+// GetPostSlugRedirect mocks base method.
+func (m *MockStore) GetPostSlugRedirect(arg0 context.Context, arg1 string) (db.PostSlugRedirect, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListUsers", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetPostSlugRedirect", arg0, arg1)
+	ret0, _ := ret[0].(db.PostSlugRedirect)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPostSlugRedirect indicates an expected call of GetPostSlugRedirect.
+func (mr *MockStoreMockRecorder) GetPostSlugRedirect(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostSlugRedirect", reflect.TypeOf((*MockStore)(nil).GetPostSlugRedirect), arg0, arg1)
+}
+
+// GetPostTag mocks base method.
+func (m *MockStore) GetPostTag(arg0 context.Context, arg1 db.GetPostTagParams) (db.PostTag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPostTag", arg0, arg1)
+	ret0, _ := ret[0].(db.PostTag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPostTag indicates an expected call of GetPostTag.
+func (mr *MockStoreMockRecorder) GetPostTag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostTag", reflect.TypeOf((*MockStore)(nil).GetPostTag), arg0, arg1)
+}
+
+// GetPostsByTagID mocks base method.
+func (m *MockStore) GetPostsByTagID(arg0 context.Context, arg1 int32) ([]db.GetPostsByTagIDRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPostsByTagID", arg0, arg1)
+	ret0, _ := ret[0].([]db.GetPostsByTagIDRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPostsByTagID indicates an expected call of GetPostsByTagID.
+func (mr *MockStoreMockRecorder) GetPostsByTagID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostsByTagID", reflect.TypeOf((*MockStore)(nil).GetPostsByTagID), arg0, arg1)
+}
+
+// GetSessionByRefreshTokenHash mocks base method.
+func (m *MockStore) GetSessionByRefreshTokenHash(arg0 context.Context, arg1 string) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionByRefreshTokenHash", arg0, arg1)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionByRefreshTokenHash indicates an expected call of GetSessionByRefreshTokenHash.
+func (mr *MockStoreMockRecorder) GetSessionByRefreshTokenHash(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionByRefreshTokenHash", reflect.TypeOf((*MockStore)(nil).GetSessionByRefreshTokenHash), arg0, arg1)
+}
+
+// GetTag mocks base method.
+func (m *MockStore) GetTag(arg0 context.Context, arg1 int32) (db.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTag", arg0, arg1)
+	ret0, _ := ret[0].(db.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTag indicates an expected call of GetTag.
+func (mr *MockStoreMockRecorder) GetTag(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTag", reflect.TypeOf((*MockStore)(nil).GetTag), arg0, arg1)
+}
+
+// GetUser mocks base method.
+func (m *MockStore) GetUser(arg0 context.Context, arg1 int32) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockStoreMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStore)(nil).GetUser), arg0, arg1)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
+}
+
+// GetUserByUsername mocks base method.
+func (m *MockStore) GetUserByUsername(arg0 context.Context, arg1 string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUsername", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUsername indicates an expected call of GetUserByUsername.
+func (mr *MockStoreMockRecorder) GetUserByUsername(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUsername", reflect.TypeOf((*MockStore)(nil).GetUserByUsername), arg0, arg1)
+}
+
+// GetUserImageByChecksum mocks base method.
+func (m *MockStore) GetUserImageByChecksum(arg0 context.Context, arg1 db.GetUserImageByChecksumParams) (db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserImageByChecksum", arg0, arg1)
+	ret0, _ := ret[0].(db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserImageByChecksum indicates an expected call of GetUserImageByChecksum.
+func (mr *MockStoreMockRecorder) GetUserImageByChecksum(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserImageByChecksum", reflect.TypeOf((*MockStore)(nil).GetUserImageByChecksum), arg0, arg1)
+}
+
+// GetUserStorageUsage mocks base method.
+func (m *MockStore) GetUserStorageUsage(arg0 context.Context, arg1 sql.NullInt32) (db.GetUserStorageUsageRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserStorageUsage", arg0, arg1)
+	ret0, _ := ret[0].(db.GetUserStorageUsageRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserStorageUsage indicates an expected call of GetUserStorageUsage.
+func (mr *MockStoreMockRecorder) GetUserStorageUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserStorageUsage", reflect.TypeOf((*MockStore)(nil).GetUserStorageUsage), arg0, arg1)
+}
+
+// IncrementPostLikes mocks base method.
+func (m *MockStore) IncrementPostLikes(arg0 context.Context, arg1 int32) (db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementPostLikes", arg0, arg1)
+	ret0, _ := ret[0].(db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementPostLikes indicates an expected call of IncrementPostLikes.
+func (mr *MockStoreMockRecorder) IncrementPostLikes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementPostLikes", reflect.TypeOf((*MockStore)(nil).IncrementPostLikes), arg0, arg1)
+}
+
+// IsSlugTaken mocks base method.
+func (m *MockStore) IsSlugTaken(arg0 context.Context, arg1 db.IsSlugTakenParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsSlugTaken", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsSlugTaken indicates an expected call of IsSlugTaken.
+func (mr *MockStoreMockRecorder) IsSlugTaken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsSlugTaken", reflect.TypeOf((*MockStore)(nil).IsSlugTaken), arg0, arg1)
+}
+
+// LikePostTx mocks base method.
+func (m *MockStore) LikePostTx(arg0 context.Context, arg1 db.PostLikeTxParams) (db.PostLikeTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LikePostTx", arg0, arg1)
+	ret0, _ := ret[0].(db.PostLikeTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LikePostTx indicates an expected call of LikePostTx.
+func (mr *MockStoreMockRecorder) LikePostTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LikePostTx", reflect.TypeOf((*MockStore)(nil).LikePostTx), arg0, arg1)
+}
+
+// ListCommentThreads mocks base method.
+func (m *MockStore) ListCommentThreads(arg0 context.Context, arg1 db.ListCommentThreadsParams) ([]db.CommentThreadRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCommentThreads", arg0, arg1)
+	ret0, _ := ret[0].([]db.CommentThreadRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCommentThreads indicates an expected call of ListCommentThreads.
+func (mr *MockStoreMockRecorder) ListCommentThreads(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCommentThreads", reflect.TypeOf((*MockStore)(nil).ListCommentThreads), arg0, arg1)
+}
+
+// ListImageVariants mocks base method.
+func (m *MockStore) ListImageVariants(arg0 context.Context, arg1 int32) ([]db.ImageVariant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageVariants", arg0, arg1)
+	ret0, _ := ret[0].([]db.ImageVariant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageVariants indicates an expected call of ListImageVariants.
+func (mr *MockStoreMockRecorder) ListImageVariants(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageVariants", reflect.TypeOf((*MockStore)(nil).ListImageVariants), arg0, arg1)
+}
+
+// ListImageVariantsByPost mocks base method.
+func (m *MockStore) ListImageVariantsByPost(arg0 context.Context, arg1 int32) ([]db.ImageVariant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageVariantsByPost", arg0, arg1)
+	ret0, _ := ret[0].([]db.ImageVariant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageVariantsByPost indicates an expected call of ListImageVariantsByPost.
+func (mr *MockStoreMockRecorder) ListImageVariantsByPost(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageVariantsByPost", reflect.TypeOf((*MockStore)(nil).ListImageVariantsByPost), arg0, arg1)
+}
+
+// ListLikedPostIDs mocks base method.
+func (m *MockStore) ListLikedPostIDs(arg0 context.Context, arg1 db.ListLikedPostIDsParams) ([]int32, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLikedPostIDs", arg0, arg1)
+	ret0, _ := ret[0].([]int32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLikedPostIDs indicates an expected call of ListLikedPostIDs.
+func (mr *MockStoreMockRecorder) ListLikedPostIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLikedPostIDs", reflect.TypeOf((*MockStore)(nil).ListLikedPostIDs), arg0, arg1)
+}
+
+// ListOrphanedImages mocks base method.
+func (m *MockStore) ListOrphanedImages(arg0 context.Context, arg1 db.ListOrphanedImagesParams) ([]db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrphanedImages", arg0, arg1)
+	ret0, _ := ret[0].([]db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrphanedImages indicates an expected call of ListOrphanedImages.
+func (mr *MockStoreMockRecorder) ListOrphanedImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrphanedImages", reflect.TypeOf((*MockStore)(nil).ListOrphanedImages), arg0, arg1)
+}
+
+// ListPostComments mocks base method.
+func (m *MockStore) ListPostComments(arg0 context.Context, arg1 db.ListPostCommentsParams) ([]db.ListPostCommentsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostComments", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostCommentsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostComments indicates an expected call of ListPostComments.
+func (mr *MockStoreMockRecorder) ListPostComments(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostComments", reflect.TypeOf((*MockStore)(nil).ListPostComments), arg0, arg1)
+}
+
+// ListPostImages mocks base method.
+func (m *MockStore) ListPostImages(arg0 context.Context, arg1 int32) ([]db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostImages", arg0, arg1)
+	ret0, _ := ret[0].([]db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostImages indicates an expected call of ListPostImages.
+func (mr *MockStoreMockRecorder) ListPostImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostImages", reflect.TypeOf((*MockStore)(nil).ListPostImages), arg0, arg1)
+}
+
+// ListPostLikers mocks base method.
+func (m *MockStore) ListPostLikers(arg0 context.Context, arg1 db.ListPostLikersParams) ([]db.ListPostLikersRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostLikers", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostLikersRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostLikers indicates an expected call of ListPostLikers.
+func (mr *MockStoreMockRecorder) ListPostLikers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostLikers", reflect.TypeOf((*MockStore)(nil).ListPostLikers), arg0, arg1)
+}
+
+// ListPostRevisions mocks base method.
+func (m *MockStore) ListPostRevisions(arg0 context.Context, arg1 db.ListPostRevisionsParams) ([]db.ListPostRevisionsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostRevisions", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostRevisionsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostRevisions indicates an expected call of ListPostRevisions.
+func (mr *MockStoreMockRecorder) ListPostRevisions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostRevisions", reflect.TypeOf((*MockStore)(nil).ListPostRevisions), arg0, arg1)
+}
+
+// ListPostSlugs mocks base method.
+func (m *MockStore) ListPostSlugs(arg0 context.Context, arg1 db.ListPostSlugsParams) ([]db.ListPostSlugsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostSlugs", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostSlugsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostSlugs indicates an expected call of ListPostSlugs.
+func (mr *MockStoreMockRecorder) ListPostSlugs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostSlugs", reflect.TypeOf((*MockStore)(nil).ListPostSlugs), arg0, arg1)
+}
+
+// ListPostTags mocks base method.
+func (m *MockStore) ListPostTags(arg0 context.Context, arg1 int32) ([]db.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostTags", arg0, arg1)
+	ret0, _ := ret[0].([]db.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostTags indicates an expected call of ListPostTags.
+func (mr *MockStoreMockRecorder) ListPostTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostTags", reflect.TypeOf((*MockStore)(nil).ListPostTags), arg0, arg1)
+}
+
+// ListPosts mocks base method.
+func (m *MockStore) ListPosts(arg0 context.Context, arg1 db.ListPostsParams) ([]db.ListPostsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPosts", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPosts indicates an expected call of ListPosts.
+func (mr *MockStoreMockRecorder) ListPosts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPosts", reflect.TypeOf((*MockStore)(nil).ListPosts), arg0, arg1)
+}
+
+// ListPostsByUser mocks base method.
+func (m *MockStore) ListPostsByUser(arg0 context.Context, arg1 db.ListPostsByUserParams) ([]db.ListPostsByUserRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostsByUserRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostsByUser indicates an expected call of ListPostsByUser.
+func (mr *MockStoreMockRecorder) ListPostsByUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostsByUser", reflect.TypeOf((*MockStore)(nil).ListPostsByUser), arg0, arg1)
+}
+
+// ListPostsOrderByLikes mocks base method.
+func (m *MockStore) ListPostsOrderByLikes(arg0 context.Context, arg1 db.ListPostsOrderByLikesParams) ([]db.ListPostsOrderByLikesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPostsOrderByLikes", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListPostsOrderByLikesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPostsOrderByLikes indicates an expected call of ListPostsOrderByLikes.
+func (mr *MockStoreMockRecorder) ListPostsOrderByLikes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPostsOrderByLikes", reflect.TypeOf((*MockStore)(nil).ListPostsOrderByLikes), arg0, arg1)
+}
+
+// ListSitemapEntries mocks base method.
+func (m *MockStore) ListSitemapEntries(arg0 context.Context, arg1 db.ListSitemapEntriesParams) ([]db.ListSitemapEntriesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSitemapEntries", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListSitemapEntriesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSitemapEntries indicates an expected call of ListSitemapEntries.
+func (mr *MockStoreMockRecorder) ListSitemapEntries(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSitemapEntries", reflect.TypeOf((*MockStore)(nil).ListSitemapEntries), arg0, arg1)
+}
+
+// ListTags mocks base method.
+func (m *MockStore) ListTags(arg0 context.Context) ([]db.Tag, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTags", arg0)
+	ret0, _ := ret[0].([]db.Tag)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTags indicates an expected call of ListTags.
+func (mr *MockStoreMockRecorder) ListTags(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTags", reflect.TypeOf((*MockStore)(nil).ListTags), arg0)
+}
+
+// ListUserImages mocks base method.
+func (m *MockStore) ListUserImages(arg0 context.Context, arg1 db.ListUserImagesParams) ([]db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUserImages", arg0, arg1)
+	ret0, _ := ret[0].([]db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUserImages indicates an expected call of ListUserImages.
+func (mr *MockStoreMockRecorder) ListUserImages(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUserImages", reflect.TypeOf((*MockStore)(nil).ListUserImages), arg0, arg1)
+}
+
+// ListUsers mocks base method.
+func (m *MockStore) ListUsers(arg0 context.Context, arg1 db.ListUsersParams) ([]db.ListUsersRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", arg0, arg1)
 	ret0, _ := ret[0].([]db.ListUsersRow)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
@@ -478,6 +1211,254 @@ func (mr *MockStoreMockRecorder) ListUsers(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockStore)(nil).ListUsers), arg0, arg1)
 }
 
+// ListUsersOrderByPostLikes mocks base method.
+func (m *MockStore) ListUsersOrderByPostLikes(arg0 context.Context, arg1 db.ListUsersOrderByPostLikesParams) ([]db.ListUsersOrderByPostLikesRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsersOrderByPostLikes", arg0, arg1)
+	ret0, _ := ret[0].([]db.ListUsersOrderByPostLikesRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsersOrderByPostLikes indicates an expected call of ListUsersOrderByPostLikes.
+func (mr *MockStoreMockRecorder) ListUsersOrderByPostLikes(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsersOrderByPostLikes", reflect.TypeOf((*MockStore)(nil).ListUsersOrderByPostLikes), arg0, arg1)
+}
+
+// LockImage mocks base method.
+func (m *MockStore) LockImage(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockImage", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockImage indicates an expected call of LockImage.
+func (mr *MockStoreMockRecorder) LockImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockImage", reflect.TypeOf((*MockStore)(nil).LockImage), arg0, arg1)
+}
+
+// LockUser mocks base method.
+func (m *MockStore) LockUser(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockUser indicates an expected call of LockUser.
+func (mr *MockStoreMockRecorder) LockUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUser", reflect.TypeOf((*MockStore)(nil).LockUser), arg0, arg1)
+}
+
+// LockUserImageByChecksum mocks base method.
+func (m *MockStore) LockUserImageByChecksum(arg0 context.Context, arg1 db.LockUserImageByChecksumParams) (db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockUserImageByChecksum", arg0, arg1)
+	ret0, _ := ret[0].(db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockUserImageByChecksum indicates an expected call of LockUserImageByChecksum.
+func (mr *MockStoreMockRecorder) LockUserImageByChecksum(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockUserImageByChecksum", reflect.TypeOf((*MockStore)(nil).LockUserImageByChecksum), arg0, arg1)
+}
+
+// MarkUserEmailVerified mocks base method.
+func (m *MockStore) MarkUserEmailVerified(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUserEmailVerified", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkUserEmailVerified indicates an expected call of MarkUserEmailVerified.
+func (mr *MockStoreMockRecorder) MarkUserEmailVerified(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUserEmailVerified", reflect.TypeOf((*MockStore)(nil).MarkUserEmailVerified), arg0, arg1)
+}
+
+// PublishScheduledPosts mocks base method.
+func (m *MockStore) PublishScheduledPosts(arg0 context.Context, arg1 time.Time) ([]db.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PublishScheduledPosts", arg0, arg1)
+	ret0, _ := ret[0].([]db.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PublishScheduledPosts indicates an expected call of PublishScheduledPosts.
+func (mr *MockStoreMockRecorder) PublishScheduledPosts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PublishScheduledPosts", reflect.TypeOf((*MockStore)(nil).PublishScheduledPosts), arg0, arg1)
+}
+
+// RemovePostImage mocks base method.
+func (m *MockStore) RemovePostImage(arg0 context.Context, arg1 db.RemovePostImageParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePostImage", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemovePostImage indicates an expected call of RemovePostImage.
+func (mr *MockStoreMockRecorder) RemovePostImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePostImage", reflect.TypeOf((*MockStore)(nil).RemovePostImage), arg0, arg1)
+}
+
+// ReorderPostImagesTx mocks base method.
+func (m *MockStore) ReorderPostImagesTx(arg0 context.Context, arg1 db.ReorderPostImagesTxParams) ([]db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReorderPostImagesTx", arg0, arg1)
+	ret0, _ := ret[0].([]db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReorderPostImagesTx indicates an expected call of ReorderPostImagesTx.
+func (mr *MockStoreMockRecorder) ReorderPostImagesTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReorderPostImagesTx", reflect.TypeOf((*MockStore)(nil).ReorderPostImagesTx), arg0, arg1)
+}
+
+// ResetPasswordTx mocks base method.
+func (m *MockStore) ResetPasswordTx(arg0 context.Context, arg1 db.ResetPasswordTxParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetPasswordTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetPasswordTx indicates an expected call of ResetPasswordTx.
+func (mr *MockStoreMockRecorder) ResetPasswordTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPasswordTx", reflect.TypeOf((*MockStore)(nil).ResetPasswordTx), arg0, arg1)
+}
+
+// RestorePostRevisionTx mocks base method.
+func (m *MockStore) RestorePostRevisionTx(arg0 context.Context, arg1 db.RestorePostRevisionTxParams) (db.EditPostTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestorePostRevisionTx", arg0, arg1)
+	ret0, _ := ret[0].(db.EditPostTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestorePostRevisionTx indicates an expected call of RestorePostRevisionTx.
+func (mr *MockStoreMockRecorder) RestorePostRevisionTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestorePostRevisionTx", reflect.TypeOf((*MockStore)(nil).RestorePostRevisionTx), arg0, arg1)
+}
+
+// RevokeEmailVerificationTokens mocks base method.
+func (m *MockStore) RevokeEmailVerificationTokens(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeEmailVerificationTokens", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeEmailVerificationTokens indicates an expected call of RevokeEmailVerificationTokens.
+func (mr *MockStoreMockRecorder) RevokeEmailVerificationTokens(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeEmailVerificationTokens", reflect.TypeOf((*MockStore)(nil).RevokeEmailVerificationTokens), arg0, arg1)
+}
+
+// RevokeSession mocks base method.
+func (m *MockStore) RevokeSession(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockStoreMockRecorder) RevokeSession(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockStore)(nil).RevokeSession), arg0, arg1)
+}
+
+// RevokeUserSessions mocks base method.
+func (m *MockStore) RevokeUserSessions(arg0 context.Context, arg1 int32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeUserSessions", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeUserSessions indicates an expected call of RevokeUserSessions.
+func (mr *MockStoreMockRecorder) RevokeUserSessions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeUserSessions", reflect.TypeOf((*MockStore)(nil).RevokeUserSessions), arg0, arg1)
+}
+
+// SearchPosts mocks base method.
+func (m *MockStore) SearchPosts(arg0 context.Context, arg1 db.SearchPostsParams) ([]db.SearchPostRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchPosts", arg0, arg1)
+	ret0, _ := ret[0].([]db.SearchPostRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchPosts indicates an expected call of SearchPosts.
+func (mr *MockStoreMockRecorder) SearchPosts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchPosts", reflect.TypeOf((*MockStore)(nil).SearchPosts), arg0, arg1)
+}
+
+// UnlikePostTx mocks base method.
+func (m *MockStore) UnlikePostTx(arg0 context.Context, arg1 db.PostLikeTxParams) (db.PostLikeTxResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlikePostTx", arg0, arg1)
+	ret0, _ := ret[0].(db.PostLikeTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlikePostTx indicates an expected call of UnlikePostTx.
+func (mr *MockStoreMockRecorder) UnlikePostTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlikePostTx", reflect.TypeOf((*MockStore)(nil).UnlikePostTx), arg0, arg1)
+}
+
+// UpdateComment mocks base method.
+func (m *MockStore) UpdateComment(arg0 context.Context, arg1 db.UpdateCommentParams) (db.Comment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateComment", arg0, arg1)
+	ret0, _ := ret[0].(db.Comment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateComment indicates an expected call of UpdateComment.
+func (mr *MockStoreMockRecorder) UpdateComment(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateComment", reflect.TypeOf((*MockStore)(nil).UpdateComment), arg0, arg1)
+}
+
+// UpdateImageAltText mocks base method.
+func (m *MockStore) UpdateImageAltText(arg0 context.Context, arg1 db.UpdateImageAltTextParams) (db.Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateImageAltText", arg0, arg1)
+	ret0, _ := ret[0].(db.Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateImageAltText indicates an expected call of UpdateImageAltText.
+func (mr *MockStoreMockRecorder) UpdateImageAltText(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateImageAltText", reflect.TypeOf((*MockStore)(nil).UpdateImageAltText), arg0, arg1)
+}
+
 // UpdatePost mocks base method.
 func (m *MockStore) UpdatePost(arg0 context.Context, arg1 db.UpdatePostParams) (db.Post, error) {
 	m.ctrl.T.Helper()
@@ -493,6 +1474,49 @@ func (mr *MockStoreMockRecorder) UpdatePost(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePost", reflect.TypeOf((*MockStore)(nil).UpdatePost), arg0, arg1)
 }
 
+// UpdatePostImageOrder mocks base method.
+func (m *MockStore) UpdatePostImageOrder(arg0 context.Context, arg1 db.UpdatePostImageOrderParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePostImageOrder", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePostImageOrder indicates an expected call of UpdatePostImageOrder.
+func (mr *MockStoreMockRecorder) UpdatePostImageOrder(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePostImageOrder", reflect.TypeOf((*MockStore)(nil).UpdatePostImageOrder), arg0, arg1)
+}
+
+// UpdatePostRendering mocks base method.
+func (m *MockStore) UpdatePostRendering(arg0 context.Context, arg1 db.UpdatePostRenderingParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePostRendering", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePostRendering indicates an expected call of UpdatePostRendering.
+func (mr *MockStoreMockRecorder) UpdatePostRendering(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePostRendering", reflect.TypeOf((*MockStore)(nil).UpdatePostRendering), arg0, arg1)
+}
+
+// UpdatePostSlug mocks base method.
+func (m *MockStore) UpdatePostSlug(arg0 context.Context, arg1 db.UpdatePostSlugParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePostSlug", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePostSlug indicates an expected call of UpdatePostSlug.
+func (mr *MockStoreMockRecorder) UpdatePostSlug(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePostSlug", reflect.TypeOf((*MockStore)(nil).UpdatePostSlug), arg0, arg1)
+}
+
 // UpdatePostTx mocks base method.
 func (m *MockStore) UpdatePostTx(arg0 context.Context, arg1 db.UpdatePostTxParams) (db.UpdatePostTxResult, error) {
 	m.ctrl.T.Helper()
@@ -538,12 +1562,28 @@ func (mr *MockStoreMockRecorder) UpdateUserPassword(arg0, arg1 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockStore)(nil).UpdateUserPassword), arg0, arg1)
 }
 
+// UpdateUserRole mocks base method.
+func (m *MockStore) UpdateUserRole(arg0 context.Context, arg1 db.UpdateUserRoleParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRole", arg0, arg1)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockStoreMockRecorder) UpdateUserRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockStore)(nil).UpdateUserRole), arg0, arg1)
+}
+
 // UploadPostImageTx mocks base method.
-func (m *MockStore) UploadPostImageTx(arg0 context.Context, arg1 db.UploadPostImageTxParams) error {
+func (m *MockStore) UploadPostImageTx(arg0 context.Context, arg1 db.UploadPostImageTxParams) (db.UploadPostImageTxResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UploadPostImageTx", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(db.UploadPostImageTxResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // UploadPostImageTx indicates an expected call of UploadPostImageTx.
@@ -552,17 +1592,46 @@ func (mr *MockStoreMockRecorder) UploadPostImageTx(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadPostImageTx", reflect.TypeOf((*MockStore)(nil).UploadPostImageTx), arg0, arg1)
 }
 
-// GetPostsByTagID mocks base method.
-func (m *MockStore) GetPostsByTagID(arg0 context.Context, arg1 int32) ([]db.GetPostsByTagIDRow, error) {
-    m.ctrl.T.Helper()
-    ret := m.ctrl.Call(m, "GetPostsByTagID", arg0, arg1)
-    ret0, _ := ret[0].([]db.GetPostsByTagIDRow)
-    ret1, _ := ret[1].(error)
-    return ret0, ret1
+// UseEmailVerificationToken mocks base method.
+func (m *MockStore) UseEmailVerificationToken(arg0 context.Context, arg1 int32) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseEmailVerificationToken", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// GetPostsByTagID indicates an expected call of GetPostsByTagID.
-func (mr *MockStoreMockRecorder) GetPostsByTagID(arg0, arg1 interface{}) *gomock.Call {
-    mr.mock.ctrl.T.Helper()
-    return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPostsByTagID", reflect.TypeOf((*MockStore)(nil).GetPostsByTagID), arg0, arg1)
-}
\ No newline at end of file
+// UseEmailVerificationToken indicates an expected call of UseEmailVerificationToken.
+func (mr *MockStoreMockRecorder) UseEmailVerificationToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseEmailVerificationToken", reflect.TypeOf((*MockStore)(nil).UseEmailVerificationToken), arg0, arg1)
+}
+
+// UsePasswordResetToken mocks base method.
+func (m *MockStore) UsePasswordResetToken(arg0 context.Context, arg1 int32) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UsePasswordResetToken", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UsePasswordResetToken indicates an expected call of UsePasswordResetToken.
+func (mr *MockStoreMockRecorder) UsePasswordResetToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UsePasswordResetToken", reflect.TypeOf((*MockStore)(nil).UsePasswordResetToken), arg0, arg1)
+}
+
+// VerifyEmailTx mocks base method.
+func (m *MockStore) VerifyEmailTx(arg0 context.Context, arg1 db.VerifyEmailTxParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmailTx", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyEmailTx indicates an expected call of VerifyEmailTx.
+func (mr *MockStoreMockRecorder) VerifyEmailTx(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmailTx", reflect.TypeOf((*MockStore)(nil).VerifyEmailTx), arg0, arg1)
+}
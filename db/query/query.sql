@@ -22,6 +22,11 @@ WHERE username = $1 LIMIT 1;
 SELECT * FROM users
 WHERE email = $1 LIMIT 1;
 
+-- name: LockUser :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE;
+
 -- name: ListUsers :many
 SELECT id, username, email, first_name, last_name, bio, created_at, updated_at
 FROM users
@@ -31,11 +36,14 @@ LIMIT $1 OFFSET $2;
 -- name: UpdateUser :one
 UPDATE users
 SET 
-  username = COALESCE($2, username),
-  email = COALESCE($3, email),
+  username = COALESCE(NULLIF($2, ''), username),
+  email = COALESCE(NULLIF($3, ''), email),
   first_name = COALESCE($4, first_name),
   last_name = COALESCE($5, last_name),
   bio = COALESCE($6, bio),
+  email_verified_at = CASE
+    WHEN COALESCE(NULLIF($3, ''), email) = email THEN email_verified_at
+  END,
   updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
 RETURNING *;
@@ -48,15 +56,28 @@ SET
 WHERE id = $1
 RETURNING id, email, username, updated_at;
 
+-- name: UpdateUserRole :one
+UPDATE users
+SET
+  role = $2,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
 -- name: CreatePost :one
 INSERT INTO posts (
   user_id,
   title,
   content,
   type,
-  status
+  status,
+  publish_at,
+  slug,
+  format,
+  content_html,
+  toc
 ) VALUES (
-  $1, $2, $3, $4, $5
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 ) RETURNING *;
 
 -- name: GetPost :one
@@ -89,10 +110,25 @@ LEFT JOIN users u ON p.user_id = u.id
 LEFT JOIN comments c ON p.id = c.post_id
 LEFT JOIN post_tags pt ON p.id = pt.post_id
 LEFT JOIN tags t ON pt.tag_id = t.id
-WHERE ($1::text IS NULL OR p.status = $1)
+WHERE p.status = sqlc.arg(status)::text
+  AND (p.status = 'published' OR sqlc.arg(all_statuses)::bool OR p.user_id = sqlc.narg(viewer_id))
+  AND (sqlc.narg(author_id)::int IS NULL OR p.user_id = sqlc.narg(author_id))
+  AND (sqlc.narg(tag_id)::int IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags ft WHERE ft.post_id = p.id AND ft.tag_id = sqlc.narg(tag_id)
+  ))
+  AND (sqlc.narg(after_id)::int IS NULL OR (p.created_at, p.id) < (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::int))
 GROUP BY p.id, u.id
-ORDER BY p.created_at DESC
-LIMIT $2 OFFSET $3;
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountPosts :one
+SELECT COUNT(*) FROM posts p
+WHERE p.status = sqlc.arg(status)::text
+  AND (p.status = 'published' OR sqlc.arg(all_statuses)::bool OR p.user_id = sqlc.narg(viewer_id))
+  AND (sqlc.narg(author_id)::int IS NULL OR p.user_id = sqlc.narg(author_id))
+  AND (sqlc.narg(tag_id)::int IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags ft WHERE ft.post_id = p.id AND ft.tag_id = sqlc.narg(tag_id)
+  ));
 
 -- name: UpdatePost :one
 UPDATE posts
@@ -101,10 +137,28 @@ SET
   content = COALESCE($3, content),
   type = COALESCE($4, type),
   status = COALESCE($5, status),
+  publish_at = COALESCE($6, publish_at),
+  format = COALESCE($7, format),
+  content_html = $8,
+  toc = $9,
   updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdatePostRendering :exec
+UPDATE posts
+SET
+  content_html = $2,
+  toc = $3
+WHERE id = $1 AND content = $4 AND format = $5;
+
+-- name: PublishScheduledPosts :many
+UPDATE posts
+SET status = 'published',
+  updated_at = CURRENT_TIMESTAMP
+WHERE status = 'scheduled' AND publish_at <= sqlc.arg(due_before)::timestamp
+RETURNING *;
+
 -- name: IncrementPostLikes :one
 UPDATE posts
 SET likes = likes + 1
@@ -117,6 +171,103 @@ SET likes = GREATEST(likes - 1, 0)
 WHERE id = $1
 RETURNING *;
 
+-- name: GetPostForUpdate :one
+SELECT * FROM posts
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: GetPostIDBySlug :one
+SELECT id FROM posts
+WHERE slug = $1 LIMIT 1;
+
+-- name: IsSlugTaken :one
+SELECT EXISTS (
+  SELECT 1 FROM posts WHERE slug = sqlc.arg(slug) AND id <> sqlc.arg(post_id)
+) OR EXISTS (
+  SELECT 1 FROM post_slug_redirects WHERE slug = sqlc.arg(slug) AND post_id <> sqlc.arg(post_id)
+) AS taken;
+
+-- name: UpdatePostSlug :exec
+UPDATE posts
+SET slug = $2
+WHERE id = $1;
+
+-- name: CreatePostSlugRedirect :exec
+INSERT INTO post_slug_redirects (slug, post_id)
+VALUES ($1, $2)
+ON CONFLICT (slug) DO UPDATE SET post_id = EXCLUDED.post_id;
+
+-- name: GetPostSlugRedirect :one
+SELECT * FROM post_slug_redirects
+WHERE slug = $1 LIMIT 1;
+
+-- name: DeletePostSlugRedirect :exec
+DELETE FROM post_slug_redirects
+WHERE slug = $1;
+
+-- name: ListPostSlugs :many
+SELECT id, title, slug FROM posts
+WHERE id > sqlc.arg(after_id)
+ORDER BY id
+LIMIT sqlc.arg(batch_size);
+
+-- name: CreatePostRevision :one
+INSERT INTO post_revisions (
+  post_id,
+  revision,
+  title,
+  content,
+  type,
+  edited_by,
+  format
+) VALUES (
+  $1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM post_revisions WHERE post_id = $1), $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetPostRevision :one
+SELECT * FROM post_revisions
+WHERE post_id = $1 AND revision = $2 LIMIT 1;
+
+-- name: ListPostRevisions :many
+SELECT 
+  r.*,
+  u.username AS edited_by_username
+FROM post_revisions r
+LEFT JOIN users u ON r.edited_by = u.id
+WHERE r.post_id = $1
+ORDER BY r.revision DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CreatePostLike :execrows
+INSERT INTO post_likes (user_id, post_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DeletePostLike :execrows
+DELETE FROM post_likes
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetPostLike :one
+SELECT * FROM post_likes
+WHERE user_id = $1 AND post_id = $2 LIMIT 1;
+
+-- name: ListLikedPostIDs :many
+SELECT post_id FROM post_likes
+WHERE user_id = $1 AND post_id = ANY($2::int[]);
+
+-- name: ListPostLikers :many
+SELECT 
+  u.id,
+  u.username,
+  u.first_name,
+  u.last_name,
+  pl.created_at as liked_at
+FROM post_likes pl
+JOIN users u ON pl.user_id = u.id
+WHERE pl.post_id = $1
+ORDER BY pl.created_at DESC
+LIMIT $2 OFFSET $3;
+
 -- name: DeletePost :exec
 DELETE FROM posts WHERE id = $1;
 
@@ -124,9 +275,10 @@ DELETE FROM posts WHERE id = $1;
 INSERT INTO comments (
   post_id,
   user_id,
-  content
+  content,
+  parent_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: ListPostComments :many
@@ -138,7 +290,23 @@ SELECT
 FROM comments c
 JOIN users u ON c.user_id = u.id
 WHERE c.post_id = $1
-ORDER BY c.created_at DESC;
+ORDER BY c.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetComment :one
+SELECT * FROM comments
+WHERE id = $1 LIMIT 1;
+
+-- name: UpdateComment :one
+UPDATE comments
+SET 
+  content = $2,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteComment :exec
+DELETE FROM comments WHERE id = $1;
 
 -- name: AddPostImage :exec
 INSERT INTO post_images (
@@ -147,7 +315,8 @@ INSERT INTO post_images (
   display_order
 ) VALUES (
   $1, $2, $3
-);
+)
+ON CONFLICT (post_id, image_id) DO NOTHING;
 
 -- name: CreateTag :one
 INSERT INTO tags (name)
@@ -166,24 +335,133 @@ INSERT INTO images (
   user_id,
   file_path,
   alt_text,
+  size_bytes,
+  mime_type,
+  checksum,
+  width,
+  height,
+  sanitized_at,
   uploaded_at
 ) VALUES (
-  $1, $2, $3, CURRENT_TIMESTAMP
-) RETURNING *;
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP
+)
+ON CONFLICT (user_id, checksum) WHERE checksum <> '' DO NOTHING
+RETURNING *;
 
 -- name: GetImage :one
 SELECT * FROM images
 WHERE id = $1 LIMIT 1;
 
+-- name: GetUserImageByChecksum :one
+SELECT * FROM images
+WHERE user_id = $1 AND checksum = $2
+ORDER BY id
+LIMIT 1;
+
+-- name: LockUserImageByChecksum :one
+SELECT * FROM images
+WHERE user_id = $1 AND checksum = $2
+ORDER BY id
+LIMIT 1
+FOR UPDATE;
+
+-- name: GetUserStorageUsage :one
+SELECT
+  COUNT(*)::int AS image_count,
+  COALESCE(SUM(i.size_bytes), 0)::bigint AS image_bytes,
+  COALESCE(SUM(v.variant_bytes), 0)::bigint AS variant_bytes
+FROM images i
+LEFT JOIN (
+  SELECT image_id, SUM(size_bytes) AS variant_bytes
+  FROM image_variants
+  GROUP BY image_id
+) v ON v.image_id = i.id
+WHERE i.user_id = $1;
+
 -- name: ListUserImages :many
 SELECT * FROM images
 WHERE user_id = $1
 ORDER BY uploaded_at DESC
 LIMIT $2 OFFSET $3;
 
+-- name: ListPostImages :many
+SELECT i.*
+FROM images i
+JOIN post_images pi ON i.id = pi.image_id
+WHERE pi.post_id = $1
+ORDER BY pi.display_order, i.id;
+
+-- name: GetNextPostImageOrder :one
+SELECT (COALESCE(MAX(display_order), 0) + 1)::int AS next_order
+FROM post_images
+WHERE post_id = $1;
+
+-- name: UpdatePostImageOrder :execrows
+UPDATE post_images
+SET display_order = $3
+WHERE post_id = $1 AND image_id = $2;
+
+-- name: RemovePostImage :execrows
+DELETE FROM post_images
+WHERE post_id = $1 AND image_id = $2;
+
+-- name: UpdateImageAltText :one
+UPDATE images
+SET alt_text = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateImageVariant :one
+INSERT INTO image_variants (
+  image_id,
+  name,
+  file_path,
+  mime_type,
+  width,
+  height,
+  size_bytes
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListImageVariants :many
+SELECT * FROM image_variants
+WHERE image_id = $1
+ORDER BY width;
+
+-- name: ListImageVariantsByPost :many
+SELECT v.*
+FROM image_variants v
+JOIN post_images pi ON v.image_id = pi.image_id
+WHERE pi.post_id = $1
+ORDER BY v.image_id, v.width;
+
 -- name: DeleteImage :exec
 DELETE FROM images WHERE id = $1;
 
+-- name: ListOrphanedImages :many
+SELECT i.*
+FROM images i
+WHERE COALESCE(i.unlinked_at, i.uploaded_at) < sqlc.arg(cutoff)::timestamp
+  AND i.id > sqlc.arg(after_id)
+  AND NOT EXISTS (
+    SELECT 1 FROM post_images pi WHERE pi.image_id = i.id
+  )
+ORDER BY i.id
+LIMIT sqlc.arg(batch_size);
+
+-- name: LockImage :exec
+SELECT id FROM images
+WHERE id = $1
+FOR UPDATE;
+
+-- name: DeleteOrphanedImage :execrows
+DELETE FROM images i
+WHERE i.id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM post_images pi WHERE pi.image_id = i.id
+  );
+
 -- name: DeleteTag :exec
 DELETE FROM tags WHERE id = $1;
 
@@ -247,15 +525,17 @@ LEFT JOIN users u ON p.user_id = u.id
 LEFT JOIN comments c ON p.id = c.post_id
 LEFT JOIN post_tags pt ON p.id = pt.post_id
 LEFT JOIN tags t ON pt.tag_id = t.id
-WHERE p.user_id = $1
-    AND ($2::text IS NULL OR p.status = $2)
+WHERE p.user_id = sqlc.arg(user_id)
+    AND p.status = sqlc.arg(status)::text
+    AND (p.status = 'published' OR sqlc.arg(all_statuses)::bool OR p.user_id = sqlc.narg(viewer_id))
+    AND (sqlc.narg(after_id)::int IS NULL OR (p.created_at, p.id) < (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::int))
 GROUP BY p.id, u.id
-ORDER BY p.created_at DESC
-LIMIT $3 OFFSET $4;
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: ListPostsOrderByLikes :many
 SELECT 
-  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes,
+  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes, p.publish_at, p.slug,
   u.username,
   COUNT(DISTINCT c.id) as comment_count,
   COALESCE(array_agg(DISTINCT t.name) FILTER (WHERE t.name IS NOT NULL), ARRAY[]::text[]) as tags
@@ -264,10 +544,12 @@ LEFT JOIN users u ON p.user_id = u.id
 LEFT JOIN comments c ON p.id = c.post_id
 LEFT JOIN post_tags pt ON p.id = pt.post_id
 LEFT JOIN tags t ON pt.tag_id = t.id
-WHERE ($1::text IS NULL OR p.status = $1)
+WHERE p.status = sqlc.arg(status)::text
+  AND (p.status = 'published' OR sqlc.arg(all_statuses)::bool OR p.user_id = sqlc.narg(viewer_id))
+  AND (sqlc.narg(after_id)::int IS NULL OR (p.likes, p.id) < (sqlc.narg(after_likes)::int, sqlc.narg(after_id)::int))
 GROUP BY p.id, u.id
-ORDER BY p.likes DESC, p.created_at DESC
-LIMIT $2 OFFSET $3;
+ORDER BY p.likes DESC, p.id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: ListUsersOrderByPostLikes :many
 SELECT 
@@ -278,4 +560,100 @@ FROM users u
 LEFT JOIN posts p ON u.id = p.user_id
 GROUP BY u.id
 ORDER BY total_likes DESC
-LIMIT $1 OFFSET $2;
\ No newline at end of file
+LIMIT $1 OFFSET $2;
+
+-- name: CreateSession :one
+INSERT INTO sessions (
+  user_id,
+  refresh_token_hash,
+  user_agent,
+  client_ip,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetSessionByRefreshTokenHash :one
+SELECT * FROM sessions
+WHERE refresh_token_hash = $1 LIMIT 1;
+
+-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+  user_id,
+  token_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetPasswordResetTokenByHash :one
+SELECT * FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: UsePasswordResetToken :execrows
+UPDATE password_reset_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND email_verified_at IS NULL;
+
+-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens (
+  user_id,
+  token_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetEmailVerificationTokenByHash :one
+SELECT * FROM email_verification_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: UseEmailVerificationToken :execrows
+UPDATE email_verification_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: RevokeEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: CountSitemapEntries :one
+SELECT (
+  (SELECT COUNT(*) FROM posts WHERE status = 'published')
+  + (SELECT COUNT(DISTINCT user_id) FROM posts WHERE status = 'published')
+  + (SELECT COUNT(DISTINCT pt.tag_id) FROM post_tags pt JOIN posts p ON p.id = pt.post_id WHERE p.status = 'published')
+)::bigint AS total;
+
+-- name: ListSitemapEntries :many
+SELECT 'post'::text AS kind, p.id, p.slug, p.updated_at AS lastmod
+FROM posts p
+WHERE p.status = 'published'
+UNION ALL
+SELECT 'user'::text, u.id, '', GREATEST(u.updated_at, MAX(p.updated_at))
+FROM users u
+JOIN posts p ON p.user_id = u.id AND p.status = 'published'
+GROUP BY u.id
+UNION ALL
+SELECT 'tag'::text, t.id, '', MAX(p.updated_at)
+FROM tags t
+JOIN post_tags pt ON pt.tag_id = t.id
+JOIN posts p ON p.id = pt.post_id AND p.status = 'published'
+GROUP BY t.id
+ORDER BY kind, id
+LIMIT $1 OFFSET $2;
@@ -0,0 +1,146 @@
+// db/sqlc/comment_test.go
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomComment(t *testing.T, user User, post Post) Comment {
+	arg := CreateCommentParams{
+		PostID: sql.NullInt32{
+			Int32: post.ID,
+			Valid: true,
+		},
+		UserID: sql.NullInt32{
+			Int32: user.ID,
+			Valid: true,
+		},
+		Content: "test comment " + util.RandomString(10),
+	}
+
+	comment, err := testQueries.CreateComment(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, comment)
+
+	require.Equal(t, arg.PostID, comment.PostID)
+	require.Equal(t, arg.UserID, comment.UserID)
+	require.Equal(t, arg.Content, comment.Content)
+	require.NotZero(t, comment.ID)
+	require.NotZero(t, comment.CreatedAt)
+
+	return comment
+}
+
+func TestListPostComments(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	for i := 0; i < 6; i++ {
+		createRandomComment(t, user, post)
+	}
+
+	comments, err := testQueries.ListPostComments(context.Background(), ListPostCommentsParams{
+		PostID: sql.NullInt32{Int32: post.ID, Valid: true},
+		Limit:  4,
+		Offset: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, comments, 4)
+
+	for _, comment := range comments {
+		require.Equal(t, post.ID, comment.PostID.Int32)
+		require.Equal(t, user.Username, comment.Username)
+	}
+}
+
+func TestUpdateComment(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	comment := createRandomComment(t, user, post)
+
+	arg := UpdateCommentParams{
+		ID:      comment.ID,
+		Content: "edited " + util.RandomString(10),
+	}
+
+	updated, err := testQueries.UpdateComment(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, comment.ID, updated.ID)
+	require.Equal(t, arg.Content, updated.Content)
+	require.Equal(t, comment.CreatedAt, updated.CreatedAt)
+	require.True(t, updated.UpdatedAt.Valid)
+}
+
+func TestDeleteComment(t *testing.T) {
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	comment := createRandomComment(t, user, post)
+
+	err := testQueries.DeleteComment(context.Background(), comment.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetComment(context.Background(), comment.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListCommentThreads(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	root := createRandomComment(t, user, post)
+	reply, err := testQueries.CreateComment(context.Background(), CreateCommentParams{
+		PostID:   root.PostID,
+		UserID:   root.UserID,
+		Content:  "reply",
+		ParentID: sql.NullInt32{Int32: root.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	nested, err := testQueries.CreateComment(context.Background(), CreateCommentParams{
+		PostID:   root.PostID,
+		UserID:   root.UserID,
+		Content:  "nested reply",
+		ParentID: sql.NullInt32{Int32: reply.ID, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// Full depth returns the thread parent-ordered
+	rows, err := store.ListCommentThreads(context.Background(), ListCommentThreadsParams{
+		PostID:   post.ID,
+		MaxDepth: 5,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, []int32{root.ID, reply.ID, nested.ID}, []int32{rows[0].ID, rows[1].ID, rows[2].ID})
+	require.Equal(t, []int32{0, 1, 2}, []int32{rows[0].Depth, rows[1].Depth, rows[2].Depth})
+	require.Equal(t, int64(1), rows[0].ReplyCount)
+
+	// Depth limit cuts the thread but keeps the reply count
+	rows, err = store.ListCommentThreads(context.Background(), ListCommentThreadsParams{
+		PostID:   post.ID,
+		MaxDepth: 1,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, int64(1), rows[1].ReplyCount)
+
+	// Listing below a comment starts at its direct replies
+	rows, err = store.ListCommentThreads(context.Background(), ListCommentThreadsParams{
+		PostID:   post.ID,
+		ParentID: &reply.ID,
+		MaxDepth: 5,
+		Limit:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	require.Equal(t, nested.ID, rows[0].ID)
+	require.Equal(t, int32(0), rows[0].Depth)
+}
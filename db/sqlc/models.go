@@ -6,6 +6,8 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 )
 
 type Comment struct {
@@ -14,26 +16,72 @@ type Comment struct {
 	UserID    sql.NullInt32 `json:"user_id"`
 	Content   string        `json:"content"`
 	CreatedAt sql.NullTime  `json:"created_at"`
+	UpdatedAt sql.NullTime  `json:"updated_at"`
+	ParentID  sql.NullInt32 `json:"parent_id"`
+}
+
+type EmailVerificationToken struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
 }
 
 type Image struct {
-	ID         int32          `json:"id"`
-	UserID     sql.NullInt32  `json:"user_id"`
-	FilePath   string         `json:"file_path"`
-	AltText    sql.NullString `json:"alt_text"`
-	UploadedAt sql.NullTime   `json:"uploaded_at"`
+	ID          int32          `json:"id"`
+	UserID      sql.NullInt32  `json:"user_id"`
+	FilePath    string         `json:"file_path"`
+	AltText     sql.NullString `json:"alt_text"`
+	UploadedAt  sql.NullTime   `json:"uploaded_at"`
+	SizeBytes   int64          `json:"size_bytes"`
+	MimeType    string         `json:"mime_type"`
+	Checksum    string         `json:"checksum"`
+	Width       int32          `json:"width"`
+	Height      int32          `json:"height"`
+	SanitizedAt sql.NullTime   `json:"sanitized_at"`
+	UnlinkedAt  sql.NullTime   `json:"unlinked_at"`
+}
+
+type ImageVariant struct {
+	ID        int32        `json:"id"`
+	ImageID   int32        `json:"image_id"`
+	Name      string       `json:"name"`
+	FilePath  string       `json:"file_path"`
+	MimeType  string       `json:"mime_type"`
+	Width     int32        `json:"width"`
+	Height    int32        `json:"height"`
+	SizeBytes int64        `json:"size_bytes"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        int32        `json:"id"`
+	UserID    int32        `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
 }
 
 type Post struct {
-	ID        int32          `json:"id"`
-	UserID    sql.NullInt32  `json:"user_id"`
-	Title     string         `json:"title"`
-	Content   string         `json:"content"`
-	Type      string         `json:"type"`
-	Status    sql.NullString `json:"status"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
-	Likes     int32          `json:"likes"`
+	ID           int32           `json:"id"`
+	UserID       sql.NullInt32   `json:"user_id"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	Type         string          `json:"type"`
+	Status       sql.NullString  `json:"status"`
+	CreatedAt    sql.NullTime    `json:"created_at"`
+	UpdatedAt    sql.NullTime    `json:"updated_at"`
+	Likes        int32           `json:"likes"`
+	TagNames     string          `json:"tag_names"`
+	SearchVector interface{}     `json:"-"`
+	PublishAt    sql.NullTime    `json:"publish_at"`
+	Slug         string          `json:"slug"`
+	Format       string          `json:"format"`
+	ContentHtml  sql.NullString  `json:"content_html"`
+	Toc          json.RawMessage `json:"toc"`
 }
 
 type PostImage struct {
@@ -42,24 +90,61 @@ type PostImage struct {
 	DisplayOrder sql.NullInt32 `json:"display_order"`
 }
 
+type PostLike struct {
+	UserID    int32        `json:"user_id"`
+	PostID    int32        `json:"post_id"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type PostRevision struct {
+	ID        int32         `json:"id"`
+	PostID    int32         `json:"post_id"`
+	Revision  int32         `json:"revision"`
+	Title     string        `json:"title"`
+	Content   string        `json:"content"`
+	Type      string        `json:"type"`
+	EditedBy  sql.NullInt32 `json:"edited_by"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	Format    string        `json:"format"`
+}
+
+type PostSlugRedirect struct {
+	Slug      string       `json:"slug"`
+	PostID    int32        `json:"post_id"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
 type PostTag struct {
 	PostID int32 `json:"post_id"`
 	TagID  int32 `json:"tag_id"`
 }
 
+type Session struct {
+	ID               int32        `json:"id"`
+	UserID           int32        `json:"user_id"`
+	RefreshTokenHash string       `json:"refresh_token_hash"`
+	UserAgent        string       `json:"user_agent"`
+	ClientIp         string       `json:"client_ip"`
+	ExpiresAt        time.Time    `json:"expires_at"`
+	RevokedAt        sql.NullTime `json:"revoked_at"`
+	CreatedAt        sql.NullTime `json:"created_at"`
+}
+
 type Tag struct {
 	ID   int32  `json:"id"`
 	Name string `json:"name"`
 }
 
 type User struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	FirstName    sql.NullString `json:"first_name"`
-	LastName     sql.NullString `json:"last_name"`
-	Bio          sql.NullString `json:"bio"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID              int32          `json:"id"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	PasswordHash    string         `json:"password_hash"`
+	FirstName       sql.NullString `json:"first_name"`
+	LastName        sql.NullString `json:"last_name"`
+	Bio             sql.NullString `json:"bio"`
+	CreatedAt       sql.NullTime   `json:"created_at"`
+	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	Role            string         `json:"role"`
+	EmailVerifiedAt sql.NullTime   `json:"email_verified_at"`
 }
@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/haotianxu2021/newPortfolio/util"
 	"github.com/stretchr/testify/require"
@@ -20,6 +21,8 @@ func createRandomPost(t *testing.T, user User) Post {
 		Title:   "test post " + util.RandomString(5),
 		Content: "test content " + util.RandomString(10),
 		Type:    "blog",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
 		Status: sql.NullString{
 			String: "published",
 			Valid:  true,
@@ -51,9 +54,8 @@ func TestListPosts(t *testing.T) {
 	}
 
 	arg := ListPostsParams{
-		Column1: "published",
-		Limit:   5,
-		Offset:  0,
+		Status: "published",
+		Limit:  5,
 	}
 
 	posts, err := testQueries.ListPosts(context.Background(), arg)
@@ -66,3 +68,354 @@ func TestListPosts(t *testing.T) {
 		require.NotEmpty(t, post.Username)
 	}
 }
+
+func TestListPostsByAuthorAndTag(t *testing.T) {
+	author := createRandomUser(t)
+	other := createRandomUser(t)
+	tag := createRandomTag(t)
+
+	tagged := createRandomPost(t, author)
+	untagged := createRandomPost(t, author)
+	createRandomPost(t, other)
+	_, err := testQueries.CreatePostTag(context.Background(), CreatePostTagParams{PostID: tagged.ID, TagID: tag.ID})
+	require.NoError(t, err)
+
+	list := func(authorID, tagID sql.NullInt32) []int32 {
+		posts, err := testQueries.ListPosts(context.Background(), ListPostsParams{
+			Status:   "published",
+			AuthorID: authorID,
+			TagID:    tagID,
+			Limit:    10,
+		})
+		require.NoError(t, err)
+
+		ids := make([]int32, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		return ids
+	}
+
+	require.ElementsMatch(t, []int32{tagged.ID, untagged.ID}, list(sql.NullInt32{Int32: author.ID, Valid: true}, sql.NullInt32{}))
+	require.Equal(t, []int32{tagged.ID}, list(sql.NullInt32{}, sql.NullInt32{Int32: tag.ID, Valid: true}))
+	require.Empty(t, list(sql.NullInt32{Int32: other.ID, Valid: true}, sql.NullInt32{Int32: tag.ID, Valid: true}))
+}
+
+func TestListPostsAfterCursor(t *testing.T) {
+	author := createRandomUser(t)
+	for i := 0; i < 5; i++ {
+		createRandomPost(t, author)
+	}
+	authorID := sql.NullInt32{Int32: author.ID, Valid: true}
+
+	total, err := testQueries.CountPosts(context.Background(), CountPostsParams{
+		Status:   "published",
+		AuthorID: authorID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(5), total)
+
+	// Walking the pages lists every post once, newest first
+	var ids []int32
+	arg := ListPostsParams{Status: "published", AuthorID: authorID, Limit: 2}
+	for {
+		posts, err := testQueries.ListPosts(context.Background(), arg)
+		require.NoError(t, err)
+		if len(posts) == 0 {
+			break
+		}
+		for _, post := range posts {
+			ids = append(ids, post.ID)
+		}
+		last := posts[len(posts)-1]
+		arg.AfterID = sql.NullInt32{Int32: last.ID, Valid: true}
+		arg.AfterCreatedAt = last.CreatedAt
+	}
+
+	require.Len(t, ids, 5)
+	for i := 1; i < len(ids); i++ {
+		require.Greater(t, ids[i-1], ids[i])
+	}
+}
+
+func TestListPostsOrderByLikesAfterCursor(t *testing.T) {
+	author := createRandomUser(t)
+	posts := make([]Post, 3)
+	for i := range posts {
+		posts[i] = createRandomPost(t, author)
+	}
+	liked, err := testQueries.IncrementPostLikes(context.Background(), posts[0].ID)
+	require.NoError(t, err)
+
+	// Posts with as many likes are ordered by id, the cursor seeks past them
+	page, err := testQueries.ListPostsOrderByLikes(context.Background(), ListPostsOrderByLikesParams{
+		Status:     "published",
+		AfterID:    sql.NullInt32{Int32: posts[2].ID, Valid: true},
+		AfterLikes: sql.NullInt32{Int32: 0, Valid: true},
+		Limit:      1,
+	})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	require.Equal(t, posts[1].ID, page[0].ID)
+
+	page, err = testQueries.ListPostsOrderByLikes(context.Background(), ListPostsOrderByLikesParams{
+		Status:     "published",
+		AfterID:    sql.NullInt32{Int32: liked.ID, Valid: true},
+		AfterLikes: sql.NullInt32{Int32: liked.Likes, Valid: true},
+		Limit:      100,
+	})
+	require.NoError(t, err)
+	for _, post := range page {
+		require.NotEqual(t, liked.ID, post.ID)
+		require.LessOrEqual(t, post.Likes, liked.Likes)
+	}
+}
+
+func TestListSitemapEntries(t *testing.T) {
+	author := createRandomUser(t)
+	tag := createRandomTag(t)
+	post := createRandomPost(t, author)
+	_, err := testQueries.CreatePostTag(context.Background(), CreatePostTagParams{PostID: post.ID, TagID: tag.ID})
+	require.NoError(t, err)
+
+	total, err := testQueries.CountSitemapEntries(context.Background())
+	require.NoError(t, err)
+
+	entries, err := testQueries.ListSitemapEntries(context.Background(), ListSitemapEntriesParams{
+		Limit:  int32(total),
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, int(total))
+
+	found := map[string]ListSitemapEntriesRow{}
+	for _, entry := range entries {
+		if (entry.Kind == "post" && entry.ID == post.ID) ||
+			(entry.Kind == "user" && entry.ID == author.ID) ||
+			(entry.Kind == "tag" && entry.ID == tag.ID) {
+			found[entry.Kind] = entry
+		}
+	}
+	require.Len(t, found, 3)
+	require.Equal(t, post.Slug, found["post"].Slug)
+	require.True(t, found["tag"].Lastmod.Valid)
+}
+
+func TestListPostsHidesUnpublished(t *testing.T) {
+	author := createRandomUser(t)
+	reader := createRandomUser(t)
+
+	draft, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: author.ID, Valid: true},
+		Title:   "draft " + util.RandomString(6),
+		Content: "not ready yet",
+		Type:    "blog",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
+		Status:  sql.NullString{String: "draft", Valid: true},
+	})
+	require.NoError(t, err)
+
+	listDrafts := func(viewerID sql.NullInt32, allStatuses bool) []int32 {
+		posts, err := testQueries.ListPostsByUser(context.Background(), ListPostsByUserParams{
+			UserID:      sql.NullInt32{Int32: author.ID, Valid: true},
+			Status:      "draft",
+			AllStatuses: allStatuses,
+			ViewerID:    viewerID,
+			Limit:       10,
+		})
+		require.NoError(t, err)
+
+		ids := make([]int32, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		return ids
+	}
+
+	require.Empty(t, listDrafts(sql.NullInt32{}, false))
+	require.Empty(t, listDrafts(sql.NullInt32{Int32: reader.ID, Valid: true}, false))
+	require.Equal(t, []int32{draft.ID}, listDrafts(sql.NullInt32{Int32: author.ID, Valid: true}, false))
+	require.Equal(t, []int32{draft.ID}, listDrafts(sql.NullInt32{Int32: reader.ID, Valid: true}, true))
+}
+
+func TestPublishScheduledPosts(t *testing.T) {
+	user := createRandomUser(t)
+	now := time.Now().UTC()
+
+	createScheduled := func(publishAt time.Time) Post {
+		post, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+			UserID:    sql.NullInt32{Int32: user.ID, Valid: true},
+			Title:     "scheduled " + util.RandomString(6),
+			Content:   "coming soon",
+			Type:      "blog",
+			Format:    "markdown",
+			Slug:      util.RandomString(12),
+			Status:    sql.NullString{String: "scheduled", Valid: true},
+			PublishAt: sql.NullTime{Time: publishAt, Valid: true},
+		})
+		require.NoError(t, err)
+		return post
+	}
+	due := createScheduled(now.Add(-time.Minute))
+	later := createScheduled(now.Add(time.Hour))
+
+	published, err := testQueries.PublishScheduledPosts(context.Background(), now)
+	require.NoError(t, err)
+
+	var publishedIDs []int32
+	for _, post := range published {
+		require.Equal(t, "published", post.Status.String)
+		if post.ID == due.ID {
+			require.True(t, post.UpdatedAt.Time.After(due.UpdatedAt.Time))
+		}
+		publishedIDs = append(publishedIDs, post.ID)
+	}
+	require.Contains(t, publishedIDs, due.ID)
+	require.NotContains(t, publishedIDs, later.ID)
+
+	post, err := testQueries.GetPostForUpdate(context.Background(), later.ID)
+	require.NoError(t, err)
+	require.Equal(t, "scheduled", post.Status.String)
+
+	// A scheduled post without a publish_at is rejected by the database
+	_, err = testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   "never",
+		Content: "no date",
+		Type:    "blog",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
+		Status:  sql.NullString{String: "scheduled", Valid: true},
+	})
+	require.Error(t, err)
+}
+
+func TestBuildTSQuery(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{"golang api", "golang & api"},
+		{"post*", "post:*"},
+		{`"rest api" golang`, "(rest <-> api) & golang"},
+		{`portfolio "unterminated phrase`, "portfolio & (unterminated <-> phrase)"},
+		{"c++ & ') | !", "c"},
+		{"   ", ""},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.want, BuildTSQuery(tc.input), tc.input)
+	}
+}
+
+func TestSearchPosts(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	// A made up word keeps other test data out of the results
+	word := "zq" + util.RandomString(8)
+	inTitle, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   word + " project write-up",
+		Content: "an old project",
+		Type:    "project",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
+		Status:  sql.NullString{String: "published", Valid: true},
+	})
+	require.NoError(t, err)
+	inContent, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   "another post",
+		Content: "this one only mentions " + word + " in passing",
+		Type:    "blog",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
+		Status:  sql.NullString{String: "published", Valid: true},
+	})
+	require.NoError(t, err)
+
+	// Tagged posts are found by their tag names too
+	tag, err := testQueries.CreateTag(context.Background(), word+"tag")
+	require.NoError(t, err)
+	_, err = store.AddPostTagTx(context.Background(), PostTagTxParams{PostID: inContent.ID, TagID: tag.ID})
+	require.NoError(t, err)
+
+	results, err := store.SearchPosts(context.Background(), SearchPostsParams{
+		TSQuery: BuildTSQuery(word),
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, inTitle.ID, results[0].ID) // title matches rank higher
+	require.Contains(t, results[0].TitleHighlight, "<mark>")
+	require.Contains(t, results[1].Snippet, "<mark>"+word+"</mark>")
+
+	// The next page starts after the last match of the previous one
+	first := results[0]
+	results, err = store.SearchPosts(context.Background(), SearchPostsParams{
+		TSQuery: BuildTSQuery(word),
+		After:   &SearchCursor{ID: first.ID, Rank: first.Rank, CreatedAt: first.CreatedAt.Time},
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, inContent.ID, results[0].ID)
+
+	count, err := store.CountSearchPosts(context.Background(), BuildTSQuery(word))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	results, err = store.SearchPosts(context.Background(), SearchPostsParams{
+		TSQuery: BuildTSQuery(word[:6] + "*"),
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	results, err = store.SearchPosts(context.Background(), SearchPostsParams{
+		TSQuery: BuildTSQuery(tag.Name),
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, inContent.ID, results[0].ID)
+
+	results, err = store.SearchPosts(context.Background(), SearchPostsParams{
+		TSQuery: BuildTSQuery(`"` + word + ` project"`),
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, inTitle.ID, results[0].ID)
+}
+
+func TestSearchPostsEscapesHighlights(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	word := "zq" + util.RandomString(8)
+	_, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   `<img src=x onerror="alert(1)"> ` + word,
+		Content: "<script>alert(1)</script> " + word + " & more",
+		Type:    "blog",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
+		Status:  sql.NullString{String: "published", Valid: true},
+	})
+	require.NoError(t, err)
+
+	results, err := store.SearchPosts(context.Background(), SearchPostsParams{
+		TSQuery: BuildTSQuery(word),
+		Limit:   10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// Markup in the post is text, only the match is marked up
+	require.Equal(t, `&lt;img src=x onerror=&quot;alert(1)&quot;&gt; <mark>`+word+`</mark>`, results[0].TitleHighlight)
+	require.NotContains(t, results[0].Snippet, "<script>")
+	require.Contains(t, results[0].Snippet, "<mark>"+word+"</mark>")
+}
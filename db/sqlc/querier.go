@@ -7,45 +7,98 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 type Querier interface {
 	AddPostImage(ctx context.Context, arg AddPostImageParams) error
 	AddPostTag(ctx context.Context, arg AddPostTagParams) error
+	CountPosts(ctx context.Context, arg CountPostsParams) (int64, error)
+	CountSitemapEntries(ctx context.Context) (int64, error)
 	CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error)
+	CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error)
 	CreateImage(ctx context.Context, arg CreateImageParams) (Image, error)
+	CreateImageVariant(ctx context.Context, arg CreateImageVariantParams) (ImageVariant, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error)
 	CreatePost(ctx context.Context, arg CreatePostParams) (Post, error)
+	CreatePostLike(ctx context.Context, arg CreatePostLikeParams) (int64, error)
+	CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) (PostRevision, error)
+	CreatePostSlugRedirect(ctx context.Context, arg CreatePostSlugRedirectParams) error
 	CreatePostTag(ctx context.Context, arg CreatePostTagParams) (PostTag, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTag(ctx context.Context, name string) (Tag, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
 	DecrementPostLikes(ctx context.Context, id int32) (Post, error)
+	DeleteComment(ctx context.Context, id int32) error
 	DeleteImage(ctx context.Context, id int32) error
+	DeleteOrphanedImage(ctx context.Context, id int32) (int64, error)
 	DeletePost(ctx context.Context, id int32) error
+	DeletePostLike(ctx context.Context, arg DeletePostLikeParams) (int64, error)
+	DeletePostSlugRedirect(ctx context.Context, slug string) error
 	DeletePostTag(ctx context.Context, arg DeletePostTagParams) error
 	DeletePostTags(ctx context.Context, postID int32) error
 	DeleteTag(ctx context.Context, id int32) error
 	DeleteTagFromPosts(ctx context.Context, tagID int32) error
+	GetComment(ctx context.Context, id int32) (Comment, error)
+	GetEmailVerificationTokenByHash(ctx context.Context, tokenHash string) (EmailVerificationToken, error)
 	GetImage(ctx context.Context, id int32) (Image, error)
+	GetNextPostImageOrder(ctx context.Context, postID int32) (int32, error)
+	GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (PasswordResetToken, error)
 	GetPost(ctx context.Context, id int32) (GetPostRow, error)
+	GetPostForUpdate(ctx context.Context, id int32) (Post, error)
+	GetPostIDBySlug(ctx context.Context, slug string) (int32, error)
+	GetPostLike(ctx context.Context, arg GetPostLikeParams) (PostLike, error)
+	GetPostRevision(ctx context.Context, arg GetPostRevisionParams) (PostRevision, error)
+	GetPostSlugRedirect(ctx context.Context, slug string) (PostSlugRedirect, error)
 	GetPostTag(ctx context.Context, arg GetPostTagParams) (PostTag, error)
 	GetPostsByTagID(ctx context.Context, tagID int32) ([]GetPostsByTagIDRow, error)
+	GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (Session, error)
 	GetTag(ctx context.Context, id int32) (Tag, error)
 	GetUser(ctx context.Context, id int32) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
+	GetUserImageByChecksum(ctx context.Context, arg GetUserImageByChecksumParams) (Image, error)
+	GetUserStorageUsage(ctx context.Context, userID sql.NullInt32) (GetUserStorageUsageRow, error)
 	IncrementPostLikes(ctx context.Context, id int32) (Post, error)
-	ListPostComments(ctx context.Context, postID sql.NullInt32) ([]ListPostCommentsRow, error)
+	IsSlugTaken(ctx context.Context, arg IsSlugTakenParams) (bool, error)
+	ListImageVariants(ctx context.Context, imageID int32) ([]ImageVariant, error)
+	ListImageVariantsByPost(ctx context.Context, postID int32) ([]ImageVariant, error)
+	ListLikedPostIDs(ctx context.Context, arg ListLikedPostIDsParams) ([]int32, error)
+	ListOrphanedImages(ctx context.Context, arg ListOrphanedImagesParams) ([]Image, error)
+	ListPostComments(ctx context.Context, arg ListPostCommentsParams) ([]ListPostCommentsRow, error)
+	ListPostImages(ctx context.Context, postID int32) ([]Image, error)
+	ListPostLikers(ctx context.Context, arg ListPostLikersParams) ([]ListPostLikersRow, error)
+	ListPostRevisions(ctx context.Context, arg ListPostRevisionsParams) ([]ListPostRevisionsRow, error)
+	ListPostSlugs(ctx context.Context, arg ListPostSlugsParams) ([]ListPostSlugsRow, error)
 	ListPostTags(ctx context.Context, postID int32) ([]Tag, error)
 	ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPostsRow, error)
 	ListPostsByUser(ctx context.Context, arg ListPostsByUserParams) ([]ListPostsByUserRow, error)
 	ListPostsOrderByLikes(ctx context.Context, arg ListPostsOrderByLikesParams) ([]ListPostsOrderByLikesRow, error)
+	ListSitemapEntries(ctx context.Context, arg ListSitemapEntriesParams) ([]ListSitemapEntriesRow, error)
 	ListTags(ctx context.Context) ([]Tag, error)
 	ListUserImages(ctx context.Context, arg ListUserImagesParams) ([]Image, error)
 	ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error)
 	ListUsersOrderByPostLikes(ctx context.Context, arg ListUsersOrderByPostLikesParams) ([]ListUsersOrderByPostLikesRow, error)
+	LockImage(ctx context.Context, id int32) error
+	LockUser(ctx context.Context, id int32) error
+	LockUserImageByChecksum(ctx context.Context, arg LockUserImageByChecksumParams) (Image, error)
+	MarkUserEmailVerified(ctx context.Context, id int32) error
+	PublishScheduledPosts(ctx context.Context, dueBefore time.Time) ([]Post, error)
+	RemovePostImage(ctx context.Context, arg RemovePostImageParams) (int64, error)
+	RevokeEmailVerificationTokens(ctx context.Context, userID int32) error
+	RevokeSession(ctx context.Context, id int32) error
+	RevokeUserSessions(ctx context.Context, userID int32) error
+	UpdateComment(ctx context.Context, arg UpdateCommentParams) (Comment, error)
+	UpdateImageAltText(ctx context.Context, arg UpdateImageAltTextParams) (Image, error)
 	UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error)
+	UpdatePostImageOrder(ctx context.Context, arg UpdatePostImageOrderParams) (int64, error)
+	UpdatePostRendering(ctx context.Context, arg UpdatePostRenderingParams) error
+	UpdatePostSlug(ctx context.Context, arg UpdatePostSlugParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (UpdateUserPasswordRow, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UseEmailVerificationToken(ctx context.Context, id int32) (int64, error)
+	UsePasswordResetToken(ctx context.Context, id int32) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -8,6 +8,10 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 const addPostImage = `-- name: AddPostImage :exec
@@ -18,6 +22,7 @@ INSERT INTO post_images (
 ) VALUES (
   $1, $2, $3
 )
+ON CONFLICT (post_id, image_id) DO NOTHING
 `
 
 type AddPostImageParams struct {
@@ -47,24 +52,77 @@ func (q *Queries) AddPostTag(ctx context.Context, arg AddPostTagParams) error {
 	return err
 }
 
+const countPosts = `-- name: CountPosts :one
+SELECT COUNT(*) FROM posts p
+WHERE p.status = $1::text
+  AND (p.status = 'published' OR $2::bool OR p.user_id = $3)
+  AND ($4::int IS NULL OR p.user_id = $4)
+  AND ($5::int IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags ft WHERE ft.post_id = p.id AND ft.tag_id = $5
+  ))
+`
+
+type CountPostsParams struct {
+	Status      string        `json:"status"`
+	AllStatuses bool          `json:"all_statuses"`
+	ViewerID    sql.NullInt32 `json:"viewer_id"`
+	AuthorID    sql.NullInt32 `json:"author_id"`
+	TagID       sql.NullInt32 `json:"tag_id"`
+}
+
+func (q *Queries) CountPosts(ctx context.Context, arg CountPostsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPosts,
+		arg.Status,
+		arg.AllStatuses,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.TagID,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSitemapEntries = `-- name: CountSitemapEntries :one
+SELECT (
+  (SELECT COUNT(*) FROM posts WHERE status = 'published')
+  + (SELECT COUNT(DISTINCT user_id) FROM posts WHERE status = 'published')
+  + (SELECT COUNT(DISTINCT pt.tag_id) FROM post_tags pt JOIN posts p ON p.id = pt.post_id WHERE p.status = 'published')
+)::bigint AS total
+`
+
+func (q *Queries) CountSitemapEntries(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSitemapEntries)
+	var total int64
+	err := row.Scan(&total)
+	return total, err
+}
+
 const createComment = `-- name: CreateComment :one
 INSERT INTO comments (
   post_id,
   user_id,
-  content
+  content,
+  parent_id
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, post_id, user_id, content, created_at
+  $1, $2, $3, $4
+) RETURNING id, post_id, user_id, content, created_at, updated_at, parent_id
 `
 
 type CreateCommentParams struct {
-	PostID  sql.NullInt32 `json:"post_id"`
-	UserID  sql.NullInt32 `json:"user_id"`
-	Content string        `json:"content"`
+	PostID   sql.NullInt32 `json:"post_id"`
+	UserID   sql.NullInt32 `json:"user_id"`
+	Content  string        `json:"content"`
+	ParentID sql.NullInt32 `json:"parent_id"`
 }
 
 func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (Comment, error) {
-	row := q.db.QueryRowContext(ctx, createComment, arg.PostID, arg.UserID, arg.Content)
+	row := q.db.QueryRowContext(ctx, createComment,
+		arg.PostID,
+		arg.UserID,
+		arg.Content,
+		arg.ParentID,
+	)
 	var i Comment
 	err := row.Scan(
 		&i.ID,
@@ -72,6 +130,38 @@ func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (C
 		&i.UserID,
 		&i.Content,
 		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const createEmailVerificationToken = `-- name: CreateEmailVerificationToken :one
+INSERT INTO email_verification_tokens (
+  user_id,
+  token_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+`
+
+type CreateEmailVerificationTokenParams struct {
+	UserID    int32     `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateEmailVerificationToken(ctx context.Context, arg CreateEmailVerificationTokenParams) (EmailVerificationToken, error) {
+	row := q.db.QueryRowContext(ctx, createEmailVerificationToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	var i EmailVerificationToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
@@ -81,20 +171,44 @@ INSERT INTO images (
   user_id,
   file_path,
   alt_text,
+  size_bytes,
+  mime_type,
+  checksum,
+  width,
+  height,
+  sanitized_at,
   uploaded_at
 ) VALUES (
-  $1, $2, $3, CURRENT_TIMESTAMP
-) RETURNING id, user_id, file_path, alt_text, uploaded_at
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP
+)
+ON CONFLICT (user_id, checksum) WHERE checksum <> '' DO NOTHING
+RETURNING id, user_id, file_path, alt_text, uploaded_at, size_bytes, mime_type, checksum, width, height, sanitized_at, unlinked_at
 `
 
 type CreateImageParams struct {
-	UserID   sql.NullInt32  `json:"user_id"`
-	FilePath string         `json:"file_path"`
-	AltText  sql.NullString `json:"alt_text"`
+	UserID      sql.NullInt32  `json:"user_id"`
+	FilePath    string         `json:"file_path"`
+	AltText     sql.NullString `json:"alt_text"`
+	SizeBytes   int64          `json:"size_bytes"`
+	MimeType    string         `json:"mime_type"`
+	Checksum    string         `json:"checksum"`
+	Width       int32          `json:"width"`
+	Height      int32          `json:"height"`
+	SanitizedAt sql.NullTime   `json:"sanitized_at"`
 }
 
 func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image, error) {
-	row := q.db.QueryRowContext(ctx, createImage, arg.UserID, arg.FilePath, arg.AltText)
+	row := q.db.QueryRowContext(ctx, createImage,
+		arg.UserID,
+		arg.FilePath,
+		arg.AltText,
+		arg.SizeBytes,
+		arg.MimeType,
+		arg.Checksum,
+		arg.Width,
+		arg.Height,
+		arg.SanitizedAt,
+	)
 	var i Image
 	err := row.Scan(
 		&i.ID,
@@ -102,6 +216,92 @@ func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image
 		&i.FilePath,
 		&i.AltText,
 		&i.UploadedAt,
+		&i.SizeBytes,
+		&i.MimeType,
+		&i.Checksum,
+		&i.Width,
+		&i.Height,
+		&i.SanitizedAt,
+		&i.UnlinkedAt,
+	)
+	return i, err
+}
+
+const createImageVariant = `-- name: CreateImageVariant :one
+INSERT INTO image_variants (
+  image_id,
+  name,
+  file_path,
+  mime_type,
+  width,
+  height,
+  size_bytes
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, image_id, name, file_path, mime_type, width, height, size_bytes, created_at
+`
+
+type CreateImageVariantParams struct {
+	ImageID   int32  `json:"image_id"`
+	Name      string `json:"name"`
+	FilePath  string `json:"file_path"`
+	MimeType  string `json:"mime_type"`
+	Width     int32  `json:"width"`
+	Height    int32  `json:"height"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+func (q *Queries) CreateImageVariant(ctx context.Context, arg CreateImageVariantParams) (ImageVariant, error) {
+	row := q.db.QueryRowContext(ctx, createImageVariant,
+		arg.ImageID,
+		arg.Name,
+		arg.FilePath,
+		arg.MimeType,
+		arg.Width,
+		arg.Height,
+		arg.SizeBytes,
+	)
+	var i ImageVariant
+	err := row.Scan(
+		&i.ID,
+		&i.ImageID,
+		&i.Name,
+		&i.FilePath,
+		&i.MimeType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :one
+INSERT INTO password_reset_tokens (
+  user_id,
+  token_hash,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+`
+
+type CreatePasswordResetTokenParams struct {
+	UserID    int32     `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordResetToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
@@ -112,18 +312,28 @@ INSERT INTO posts (
   title,
   content,
   type,
-  status
+  status,
+  publish_at,
+  slug,
+  format,
+  content_html,
+  toc
 ) VALUES (
-  $1, $2, $3, $4, $5
-) RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes, tag_names, search_vector, publish_at, slug, format, content_html, toc
 `
 
 type CreatePostParams struct {
-	UserID  sql.NullInt32  `json:"user_id"`
-	Title   string         `json:"title"`
-	Content string         `json:"content"`
-	Type    string         `json:"type"`
-	Status  sql.NullString `json:"status"`
+	UserID      sql.NullInt32   `json:"user_id"`
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Type        string          `json:"type"`
+	Status      sql.NullString  `json:"status"`
+	PublishAt   sql.NullTime    `json:"publish_at"`
+	Slug        string          `json:"slug"`
+	Format      string          `json:"format"`
+	ContentHtml sql.NullString  `json:"content_html"`
+	Toc         json.RawMessage `json:"toc"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -133,6 +343,11 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Content,
 		arg.Type,
 		arg.Status,
+		arg.PublishAt,
+		arg.Slug,
+		arg.Format,
+		arg.ContentHtml,
+		arg.Toc,
 	)
 	var i Post
 	err := row.Scan(
@@ -145,10 +360,99 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Likes,
+		&i.TagNames,
+		&i.SearchVector,
+		&i.PublishAt,
+		&i.Slug,
+		&i.Format,
+		&i.ContentHtml,
+		&i.Toc,
+	)
+	return i, err
+}
+
+const createPostLike = `-- name: CreatePostLike :execrows
+INSERT INTO post_likes (user_id, post_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreatePostLikeParams struct {
+	UserID int32 `json:"user_id"`
+	PostID int32 `json:"post_id"`
+}
+
+func (q *Queries) CreatePostLike(ctx context.Context, arg CreatePostLikeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPostLike, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createPostRevision = `-- name: CreatePostRevision :one
+INSERT INTO post_revisions (
+  post_id,
+  revision,
+  title,
+  content,
+  type,
+  edited_by,
+  format
+) VALUES (
+  $1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM post_revisions WHERE post_id = $1), $2, $3, $4, $5, $6
+) RETURNING id, post_id, revision, title, content, type, edited_by, created_at, format
+`
+
+type CreatePostRevisionParams struct {
+	PostID   int32         `json:"post_id"`
+	Title    string        `json:"title"`
+	Content  string        `json:"content"`
+	Type     string        `json:"type"`
+	EditedBy sql.NullInt32 `json:"edited_by"`
+	Format   string        `json:"format"`
+}
+
+func (q *Queries) CreatePostRevision(ctx context.Context, arg CreatePostRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, createPostRevision,
+		arg.PostID,
+		arg.Title,
+		arg.Content,
+		arg.Type,
+		arg.EditedBy,
+		arg.Format,
+	)
+	var i PostRevision
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.Revision,
+		&i.Title,
+		&i.Content,
+		&i.Type,
+		&i.EditedBy,
+		&i.CreatedAt,
+		&i.Format,
 	)
 	return i, err
 }
 
+const createPostSlugRedirect = `-- name: CreatePostSlugRedirect :exec
+INSERT INTO post_slug_redirects (slug, post_id)
+VALUES ($1, $2)
+ON CONFLICT (slug) DO UPDATE SET post_id = EXCLUDED.post_id
+`
+
+type CreatePostSlugRedirectParams struct {
+	Slug   string `json:"slug"`
+	PostID int32  `json:"post_id"`
+}
+
+func (q *Queries) CreatePostSlugRedirect(ctx context.Context, arg CreatePostSlugRedirectParams) error {
+	_, err := q.db.ExecContext(ctx, createPostSlugRedirect, arg.Slug, arg.PostID)
+	return err
+}
+
 const createPostTag = `-- name: CreatePostTag :one
 INSERT INTO post_tags (
     post_id,
@@ -170,6 +474,48 @@ func (q *Queries) CreatePostTag(ctx context.Context, arg CreatePostTagParams) (P
 	return i, err
 }
 
+const createSession = `-- name: CreateSession :one
+INSERT INTO sessions (
+  user_id,
+  refresh_token_hash,
+  user_agent,
+  client_ip,
+  expires_at
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING id, user_id, refresh_token_hash, user_agent, client_ip, expires_at, revoked_at, created_at
+`
+
+type CreateSessionParams struct {
+	UserID           int32     `json:"user_id"`
+	RefreshTokenHash string    `json:"refresh_token_hash"`
+	UserAgent        string    `json:"user_agent"`
+	ClientIp         string    `json:"client_ip"`
+	ExpiresAt        time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.UserID,
+		arg.RefreshTokenHash,
+		arg.UserAgent,
+		arg.ClientIp,
+		arg.ExpiresAt,
+	)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RefreshTokenHash,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createTag = `-- name: CreateTag :one
 INSERT INTO tags (name)
 VALUES ($1)
@@ -195,7 +541,7 @@ INSERT INTO users (
   bio
 ) VALUES (
   $1, $2, $3, $4, $5, $6
-) RETURNING id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at
+) RETURNING id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at, role, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -227,6 +573,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -235,7 +583,7 @@ const decrementPostLikes = `-- name: DecrementPostLikes :one
 UPDATE posts
 SET likes = GREATEST(likes - 1, 0)
 WHERE id = $1
-RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes
+RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes, tag_names, search_vector, publish_at, slug, format, content_html, toc
 `
 
 func (q *Queries) DecrementPostLikes(ctx context.Context, id int32) (Post, error) {
@@ -251,10 +599,26 @@ func (q *Queries) DecrementPostLikes(ctx context.Context, id int32) (Post, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Likes,
+		&i.TagNames,
+		&i.SearchVector,
+		&i.PublishAt,
+		&i.Slug,
+		&i.Format,
+		&i.ContentHtml,
+		&i.Toc,
 	)
 	return i, err
 }
 
+const deleteComment = `-- name: DeleteComment :exec
+DELETE FROM comments WHERE id = $1
+`
+
+func (q *Queries) DeleteComment(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, deleteComment, id)
+	return err
+}
+
 const deleteImage = `-- name: DeleteImage :exec
 DELETE FROM images WHERE id = $1
 `
@@ -264,6 +628,22 @@ func (q *Queries) DeleteImage(ctx context.Context, id int32) error {
 	return err
 }
 
+const deleteOrphanedImage = `-- name: DeleteOrphanedImage :execrows
+DELETE FROM images i
+WHERE i.id = $1
+  AND NOT EXISTS (
+    SELECT 1 FROM post_images pi WHERE pi.image_id = i.id
+  )
+`
+
+func (q *Queries) DeleteOrphanedImage(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOrphanedImage, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deletePost = `-- name: DeletePost :exec
 DELETE FROM posts WHERE id = $1
 `
@@ -273,6 +653,34 @@ func (q *Queries) DeletePost(ctx context.Context, id int32) error {
 	return err
 }
 
+const deletePostLike = `-- name: DeletePostLike :execrows
+DELETE FROM post_likes
+WHERE user_id = $1 AND post_id = $2
+`
+
+type DeletePostLikeParams struct {
+	UserID int32 `json:"user_id"`
+	PostID int32 `json:"post_id"`
+}
+
+func (q *Queries) DeletePostLike(ctx context.Context, arg DeletePostLikeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePostLike, arg.UserID, arg.PostID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePostSlugRedirect = `-- name: DeletePostSlugRedirect :exec
+DELETE FROM post_slug_redirects
+WHERE slug = $1
+`
+
+func (q *Queries) DeletePostSlugRedirect(ctx context.Context, slug string) error {
+	_, err := q.db.ExecContext(ctx, deletePostSlugRedirect, slug)
+	return err
+}
+
 const deletePostTag = `-- name: DeletePostTag :exec
 DELETE FROM post_tags 
 WHERE post_id = $1 AND tag_id = $2
@@ -316,8 +724,47 @@ func (q *Queries) DeleteTagFromPosts(ctx context.Context, tagID int32) error {
 	return err
 }
 
+const getComment = `-- name: GetComment :one
+SELECT id, post_id, user_id, content, created_at, updated_at, parent_id FROM comments
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetComment(ctx context.Context, id int32) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, getComment, id)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const getEmailVerificationTokenByHash = `-- name: GetEmailVerificationTokenByHash :one
+SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM email_verification_tokens
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetEmailVerificationTokenByHash(ctx context.Context, tokenHash string) (EmailVerificationToken, error) {
+	row := q.db.QueryRowContext(ctx, getEmailVerificationTokenByHash, tokenHash)
+	var i EmailVerificationToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getImage = `-- name: GetImage :one
-SELECT id, user_id, file_path, alt_text, uploaded_at FROM images
+SELECT id, user_id, file_path, alt_text, uploaded_at, size_bytes, mime_type, checksum, width, height, sanitized_at, unlinked_at FROM images
 WHERE id = $1 LIMIT 1
 `
 
@@ -330,13 +777,52 @@ func (q *Queries) GetImage(ctx context.Context, id int32) (Image, error) {
 		&i.FilePath,
 		&i.AltText,
 		&i.UploadedAt,
+		&i.SizeBytes,
+		&i.MimeType,
+		&i.Checksum,
+		&i.Width,
+		&i.Height,
+		&i.SanitizedAt,
+		&i.UnlinkedAt,
+	)
+	return i, err
+}
+
+const getNextPostImageOrder = `-- name: GetNextPostImageOrder :one
+SELECT (COALESCE(MAX(display_order), 0) + 1)::int AS next_order
+FROM post_images
+WHERE post_id = $1
+`
+
+func (q *Queries) GetNextPostImageOrder(ctx context.Context, postID int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getNextPostImageOrder, postID)
+	var next_order int32
+	err := row.Scan(&next_order)
+	return next_order, err
+}
+
+const getPasswordResetTokenByHash = `-- name: GetPasswordResetTokenByHash :one
+SELECT id, user_id, token_hash, expires_at, used_at, created_at FROM password_reset_tokens
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetPasswordResetTokenByHash(ctx context.Context, tokenHash string) (PasswordResetToken, error) {
+	row := q.db.QueryRowContext(ctx, getPasswordResetTokenByHash, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getPost = `-- name: GetPost :one
 SELECT 
-  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes,
+  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes, p.tag_names, p.search_vector, p.publish_at, p.slug, p.format, p.content_html, p.toc,
   u.username,
   u.first_name,
   u.last_name,
@@ -354,21 +840,28 @@ GROUP BY p.id, u.id
 `
 
 type GetPostRow struct {
-	ID        int32          `json:"id"`
-	UserID    sql.NullInt32  `json:"user_id"`
-	Title     string         `json:"title"`
-	Content   string         `json:"content"`
-	Type      string         `json:"type"`
-	Status    sql.NullString `json:"status"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
-	Likes     int32          `json:"likes"`
-	Username  sql.NullString `json:"username"`
-	FirstName sql.NullString `json:"first_name"`
-	LastName  sql.NullString `json:"last_name"`
-	Tags      interface{}    `json:"tags"`
-	Images    interface{}    `json:"images"`
-	Likes_2   int32          `json:"likes_2"`
+	ID           int32           `json:"id"`
+	UserID       sql.NullInt32   `json:"user_id"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	Type         string          `json:"type"`
+	Status       sql.NullString  `json:"status"`
+	CreatedAt    sql.NullTime    `json:"created_at"`
+	UpdatedAt    sql.NullTime    `json:"updated_at"`
+	Likes        int32           `json:"likes"`
+	TagNames     string          `json:"tag_names"`
+	SearchVector interface{}     `json:"-"`
+	PublishAt    sql.NullTime    `json:"publish_at"`
+	Slug         string          `json:"slug"`
+	Format       string          `json:"format"`
+	ContentHtml  sql.NullString  `json:"content_html"`
+	Toc          json.RawMessage `json:"toc"`
+	Username     sql.NullString  `json:"username"`
+	FirstName    sql.NullString  `json:"first_name"`
+	LastName     sql.NullString  `json:"last_name"`
+	Tags         interface{}     `json:"tags"`
+	Images       interface{}     `json:"images"`
+	Likes_2      int32           `json:"likes_2"`
 }
 
 func (q *Queries) GetPost(ctx context.Context, id int32) (GetPostRow, error) {
@@ -384,6 +877,13 @@ func (q *Queries) GetPost(ctx context.Context, id int32) (GetPostRow, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Likes,
+		&i.TagNames,
+		&i.SearchVector,
+		&i.PublishAt,
+		&i.Slug,
+		&i.Format,
+		&i.ContentHtml,
+		&i.Toc,
 		&i.Username,
 		&i.FirstName,
 		&i.LastName,
@@ -394,6 +894,104 @@ func (q *Queries) GetPost(ctx context.Context, id int32) (GetPostRow, error) {
 	return i, err
 }
 
+const getPostForUpdate = `-- name: GetPostForUpdate :one
+SELECT id, user_id, title, content, type, status, created_at, updated_at, likes, tag_names, search_vector, publish_at, slug, format, content_html, toc FROM posts
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetPostForUpdate(ctx context.Context, id int32) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUpdate, id)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.Type,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Likes,
+		&i.TagNames,
+		&i.SearchVector,
+		&i.PublishAt,
+		&i.Slug,
+		&i.Format,
+		&i.ContentHtml,
+		&i.Toc,
+	)
+	return i, err
+}
+
+const getPostIDBySlug = `-- name: GetPostIDBySlug :one
+SELECT id FROM posts
+WHERE slug = $1 LIMIT 1
+`
+
+func (q *Queries) GetPostIDBySlug(ctx context.Context, slug string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getPostIDBySlug, slug)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getPostLike = `-- name: GetPostLike :one
+SELECT user_id, post_id, created_at FROM post_likes
+WHERE user_id = $1 AND post_id = $2 LIMIT 1
+`
+
+type GetPostLikeParams struct {
+	UserID int32 `json:"user_id"`
+	PostID int32 `json:"post_id"`
+}
+
+func (q *Queries) GetPostLike(ctx context.Context, arg GetPostLikeParams) (PostLike, error) {
+	row := q.db.QueryRowContext(ctx, getPostLike, arg.UserID, arg.PostID)
+	var i PostLike
+	err := row.Scan(&i.UserID, &i.PostID, &i.CreatedAt)
+	return i, err
+}
+
+const getPostRevision = `-- name: GetPostRevision :one
+SELECT id, post_id, revision, title, content, type, edited_by, created_at, format FROM post_revisions
+WHERE post_id = $1 AND revision = $2 LIMIT 1
+`
+
+type GetPostRevisionParams struct {
+	PostID   int32 `json:"post_id"`
+	Revision int32 `json:"revision"`
+}
+
+func (q *Queries) GetPostRevision(ctx context.Context, arg GetPostRevisionParams) (PostRevision, error) {
+	row := q.db.QueryRowContext(ctx, getPostRevision, arg.PostID, arg.Revision)
+	var i PostRevision
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.Revision,
+		&i.Title,
+		&i.Content,
+		&i.Type,
+		&i.EditedBy,
+		&i.CreatedAt,
+		&i.Format,
+	)
+	return i, err
+}
+
+const getPostSlugRedirect = `-- name: GetPostSlugRedirect :one
+SELECT slug, post_id, created_at FROM post_slug_redirects
+WHERE slug = $1 LIMIT 1
+`
+
+func (q *Queries) GetPostSlugRedirect(ctx context.Context, slug string) (PostSlugRedirect, error) {
+	row := q.db.QueryRowContext(ctx, getPostSlugRedirect, slug)
+	var i PostSlugRedirect
+	err := row.Scan(&i.Slug, &i.PostID, &i.CreatedAt)
+	return i, err
+}
+
 const getPostTag = `-- name: GetPostTag :one
 SELECT post_id, tag_id FROM post_tags
 WHERE post_id = $1 AND tag_id = $2 LIMIT 1
@@ -413,7 +1011,7 @@ func (q *Queries) GetPostTag(ctx context.Context, arg GetPostTagParams) (PostTag
 
 const getPostsByTagID = `-- name: GetPostsByTagID :many
 SELECT 
-  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes,
+  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes, p.tag_names, p.search_vector, p.publish_at, p.slug, p.format, p.content_html, p.toc,
   u.username,
   u.first_name,
   u.last_name
@@ -424,18 +1022,25 @@ WHERE pt.tag_id = $1
 `
 
 type GetPostsByTagIDRow struct {
-	ID        int32          `json:"id"`
-	UserID    sql.NullInt32  `json:"user_id"`
-	Title     string         `json:"title"`
-	Content   string         `json:"content"`
-	Type      string         `json:"type"`
-	Status    sql.NullString `json:"status"`
-	CreatedAt sql.NullTime   `json:"created_at"`
-	UpdatedAt sql.NullTime   `json:"updated_at"`
-	Likes     int32          `json:"likes"`
-	Username  string         `json:"username"`
-	FirstName sql.NullString `json:"first_name"`
-	LastName  sql.NullString `json:"last_name"`
+	ID           int32           `json:"id"`
+	UserID       sql.NullInt32   `json:"user_id"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	Type         string          `json:"type"`
+	Status       sql.NullString  `json:"status"`
+	CreatedAt    sql.NullTime    `json:"created_at"`
+	UpdatedAt    sql.NullTime    `json:"updated_at"`
+	Likes        int32           `json:"likes"`
+	TagNames     string          `json:"tag_names"`
+	SearchVector interface{}     `json:"-"`
+	PublishAt    sql.NullTime    `json:"publish_at"`
+	Slug         string          `json:"slug"`
+	Format       string          `json:"format"`
+	ContentHtml  sql.NullString  `json:"content_html"`
+	Toc          json.RawMessage `json:"toc"`
+	Username     string          `json:"username"`
+	FirstName    sql.NullString  `json:"first_name"`
+	LastName     sql.NullString  `json:"last_name"`
 }
 
 func (q *Queries) GetPostsByTagID(ctx context.Context, tagID int32) ([]GetPostsByTagIDRow, error) {
@@ -457,6 +1062,13 @@ func (q *Queries) GetPostsByTagID(ctx context.Context, tagID int32) ([]GetPostsB
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Likes,
+			&i.TagNames,
+			&i.SearchVector,
+			&i.PublishAt,
+			&i.Slug,
+			&i.Format,
+			&i.ContentHtml,
+			&i.Toc,
 			&i.Username,
 			&i.FirstName,
 			&i.LastName,
@@ -474,6 +1086,27 @@ func (q *Queries) GetPostsByTagID(ctx context.Context, tagID int32) ([]GetPostsB
 	return items, nil
 }
 
+const getSessionByRefreshTokenHash = `-- name: GetSessionByRefreshTokenHash :one
+SELECT id, user_id, refresh_token_hash, user_agent, client_ip, expires_at, revoked_at, created_at FROM sessions
+WHERE refresh_token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetSessionByRefreshTokenHash(ctx context.Context, refreshTokenHash string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getSessionByRefreshTokenHash, refreshTokenHash)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RefreshTokenHash,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const getTag = `-- name: GetTag :one
 SELECT id, name FROM tags WHERE id = $1 LIMIT 1
 `
@@ -486,7 +1119,7 @@ func (q *Queries) GetTag(ctx context.Context, id int32) (Tag, error) {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at, role, email_verified_at FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -503,12 +1136,14 @@ func (q *Queries) GetUser(ctx context.Context, id int32) (User, error) {
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at, role, email_verified_at FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -525,12 +1160,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByUsername = `-- name: GetUserByUsername :one
-SELECT id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at FROM users
+SELECT id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at, role, email_verified_at FROM users
 WHERE username = $1 LIMIT 1
 `
 
@@ -547,15 +1184,76 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (User,
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
+const getUserImageByChecksum = `-- name: GetUserImageByChecksum :one
+SELECT id, user_id, file_path, alt_text, uploaded_at, size_bytes, mime_type, checksum, width, height, sanitized_at, unlinked_at FROM images
+WHERE user_id = $1 AND checksum = $2
+ORDER BY id
+LIMIT 1
+`
+
+type GetUserImageByChecksumParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	Checksum string        `json:"checksum"`
+}
+
+func (q *Queries) GetUserImageByChecksum(ctx context.Context, arg GetUserImageByChecksumParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, getUserImageByChecksum, arg.UserID, arg.Checksum)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FilePath,
+		&i.AltText,
+		&i.UploadedAt,
+		&i.SizeBytes,
+		&i.MimeType,
+		&i.Checksum,
+		&i.Width,
+		&i.Height,
+		&i.SanitizedAt,
+		&i.UnlinkedAt,
+	)
+	return i, err
+}
+
+const getUserStorageUsage = `-- name: GetUserStorageUsage :one
+SELECT
+  COUNT(*)::int AS image_count,
+  COALESCE(SUM(i.size_bytes), 0)::bigint AS image_bytes,
+  COALESCE(SUM(v.variant_bytes), 0)::bigint AS variant_bytes
+FROM images i
+LEFT JOIN (
+  SELECT image_id, SUM(size_bytes) AS variant_bytes
+  FROM image_variants
+  GROUP BY image_id
+) v ON v.image_id = i.id
+WHERE i.user_id = $1
+`
+
+type GetUserStorageUsageRow struct {
+	ImageCount   int32 `json:"image_count"`
+	ImageBytes   int64 `json:"image_bytes"`
+	VariantBytes int64 `json:"variant_bytes"`
+}
+
+func (q *Queries) GetUserStorageUsage(ctx context.Context, userID sql.NullInt32) (GetUserStorageUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStorageUsage, userID)
+	var i GetUserStorageUsageRow
+	err := row.Scan(&i.ImageCount, &i.ImageBytes, &i.VariantBytes)
+	return i, err
+}
+
 const incrementPostLikes = `-- name: IncrementPostLikes :one
 UPDATE posts
 SET likes = likes + 1
 WHERE id = $1
-RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes
+RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes, tag_names, search_vector, publish_at, slug, format, content_html, toc
 `
 
 func (q *Queries) IncrementPostLikes(ctx context.Context, id int32) (Post, error) {
@@ -571,13 +1269,207 @@ func (q *Queries) IncrementPostLikes(ctx context.Context, id int32) (Post, error
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Likes,
+		&i.TagNames,
+		&i.SearchVector,
+		&i.PublishAt,
+		&i.Slug,
+		&i.Format,
+		&i.ContentHtml,
+		&i.Toc,
 	)
 	return i, err
 }
 
+const isSlugTaken = `-- name: IsSlugTaken :one
+SELECT EXISTS (
+  SELECT 1 FROM posts WHERE slug = $1 AND id <> $2
+) OR EXISTS (
+  SELECT 1 FROM post_slug_redirects WHERE slug = $1 AND post_id <> $2
+) AS taken
+`
+
+type IsSlugTakenParams struct {
+	Slug   string `json:"slug"`
+	PostID int32  `json:"post_id"`
+}
+
+func (q *Queries) IsSlugTaken(ctx context.Context, arg IsSlugTakenParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isSlugTaken, arg.Slug, arg.PostID)
+	var taken bool
+	err := row.Scan(&taken)
+	return taken, err
+}
+
+const listImageVariants = `-- name: ListImageVariants :many
+SELECT id, image_id, name, file_path, mime_type, width, height, size_bytes, created_at FROM image_variants
+WHERE image_id = $1
+ORDER BY width
+`
+
+func (q *Queries) ListImageVariants(ctx context.Context, imageID int32) ([]ImageVariant, error) {
+	rows, err := q.db.QueryContext(ctx, listImageVariants, imageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ImageVariant{}
+	for rows.Next() {
+		var i ImageVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageID,
+			&i.Name,
+			&i.FilePath,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImageVariantsByPost = `-- name: ListImageVariantsByPost :many
+SELECT v.id, v.image_id, v.name, v.file_path, v.mime_type, v.width, v.height, v.size_bytes, v.created_at
+FROM image_variants v
+JOIN post_images pi ON v.image_id = pi.image_id
+WHERE pi.post_id = $1
+ORDER BY v.image_id, v.width
+`
+
+func (q *Queries) ListImageVariantsByPost(ctx context.Context, postID int32) ([]ImageVariant, error) {
+	rows, err := q.db.QueryContext(ctx, listImageVariantsByPost, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ImageVariant{}
+	for rows.Next() {
+		var i ImageVariant
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImageID,
+			&i.Name,
+			&i.FilePath,
+			&i.MimeType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLikedPostIDs = `-- name: ListLikedPostIDs :many
+SELECT post_id FROM post_likes
+WHERE user_id = $1 AND post_id = ANY($2::int[])
+`
+
+type ListLikedPostIDsParams struct {
+	UserID  int32   `json:"user_id"`
+	Column2 []int32 `json:"column_2"`
+}
+
+func (q *Queries) ListLikedPostIDs(ctx context.Context, arg ListLikedPostIDsParams) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, listLikedPostIDs, arg.UserID, pq.Array(arg.Column2))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int32{}
+	for rows.Next() {
+		var post_id int32
+		if err := rows.Scan(&post_id); err != nil {
+			return nil, err
+		}
+		items = append(items, post_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanedImages = `-- name: ListOrphanedImages :many
+SELECT i.id, i.user_id, i.file_path, i.alt_text, i.uploaded_at, i.size_bytes, i.mime_type, i.checksum, i.width, i.height, i.sanitized_at, i.unlinked_at
+FROM images i
+WHERE COALESCE(i.unlinked_at, i.uploaded_at) < $1::timestamp
+  AND i.id > $2
+  AND NOT EXISTS (
+    SELECT 1 FROM post_images pi WHERE pi.image_id = i.id
+  )
+ORDER BY i.id
+LIMIT $3
+`
+
+type ListOrphanedImagesParams struct {
+	Cutoff    time.Time `json:"cutoff"`
+	AfterID   int32     `json:"after_id"`
+	BatchSize int32     `json:"batch_size"`
+}
+
+func (q *Queries) ListOrphanedImages(ctx context.Context, arg ListOrphanedImagesParams) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listOrphanedImages, arg.Cutoff, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Image{}
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FilePath,
+			&i.AltText,
+			&i.UploadedAt,
+			&i.SizeBytes,
+			&i.MimeType,
+			&i.Checksum,
+			&i.Width,
+			&i.Height,
+			&i.SanitizedAt,
+			&i.UnlinkedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPostComments = `-- name: ListPostComments :many
 SELECT 
-  c.id, c.post_id, c.user_id, c.content, c.created_at,
+  c.id, c.post_id, c.user_id, c.content, c.created_at, c.updated_at, c.parent_id,
   u.username,
   u.first_name,
   u.last_name
@@ -585,21 +1477,30 @@ FROM comments c
 JOIN users u ON c.user_id = u.id
 WHERE c.post_id = $1
 ORDER BY c.created_at DESC
+LIMIT $2 OFFSET $3
 `
 
+type ListPostCommentsParams struct {
+	PostID sql.NullInt32 `json:"post_id"`
+	Limit  int32         `json:"limit"`
+	Offset int32         `json:"offset"`
+}
+
 type ListPostCommentsRow struct {
 	ID        int32          `json:"id"`
 	PostID    sql.NullInt32  `json:"post_id"`
 	UserID    sql.NullInt32  `json:"user_id"`
 	Content   string         `json:"content"`
 	CreatedAt sql.NullTime   `json:"created_at"`
+	UpdatedAt sql.NullTime   `json:"updated_at"`
+	ParentID  sql.NullInt32  `json:"parent_id"`
 	Username  string         `json:"username"`
 	FirstName sql.NullString `json:"first_name"`
 	LastName  sql.NullString `json:"last_name"`
 }
 
-func (q *Queries) ListPostComments(ctx context.Context, postID sql.NullInt32) ([]ListPostCommentsRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPostComments, postID)
+func (q *Queries) ListPostComments(ctx context.Context, arg ListPostCommentsParams) ([]ListPostCommentsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostComments, arg.PostID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -613,6 +1514,8 @@ func (q *Queries) ListPostComments(ctx context.Context, postID sql.NullInt32) ([
 			&i.UserID,
 			&i.Content,
 			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ParentID,
 			&i.Username,
 			&i.FirstName,
 			&i.LastName,
@@ -630,6 +1533,212 @@ func (q *Queries) ListPostComments(ctx context.Context, postID sql.NullInt32) ([
 	return items, nil
 }
 
+const listPostImages = `-- name: ListPostImages :many
+SELECT i.id, i.user_id, i.file_path, i.alt_text, i.uploaded_at, i.size_bytes, i.mime_type, i.checksum, i.width, i.height, i.sanitized_at, i.unlinked_at
+FROM images i
+JOIN post_images pi ON i.id = pi.image_id
+WHERE pi.post_id = $1
+ORDER BY pi.display_order, i.id
+`
+
+func (q *Queries) ListPostImages(ctx context.Context, postID int32) ([]Image, error) {
+	rows, err := q.db.QueryContext(ctx, listPostImages, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Image{}
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.FilePath,
+			&i.AltText,
+			&i.UploadedAt,
+			&i.SizeBytes,
+			&i.MimeType,
+			&i.Checksum,
+			&i.Width,
+			&i.Height,
+			&i.SanitizedAt,
+			&i.UnlinkedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostLikers = `-- name: ListPostLikers :many
+SELECT 
+  u.id,
+  u.username,
+  u.first_name,
+  u.last_name,
+  pl.created_at as liked_at
+FROM post_likes pl
+JOIN users u ON pl.user_id = u.id
+WHERE pl.post_id = $1
+ORDER BY pl.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListPostLikersParams struct {
+	PostID int32 `json:"post_id"`
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+type ListPostLikersRow struct {
+	ID        int32          `json:"id"`
+	Username  string         `json:"username"`
+	FirstName sql.NullString `json:"first_name"`
+	LastName  sql.NullString `json:"last_name"`
+	LikedAt   sql.NullTime   `json:"liked_at"`
+}
+
+func (q *Queries) ListPostLikers(ctx context.Context, arg ListPostLikersParams) ([]ListPostLikersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostLikers, arg.PostID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostLikersRow{}
+	for rows.Next() {
+		var i ListPostLikersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.FirstName,
+			&i.LastName,
+			&i.LikedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostRevisions = `-- name: ListPostRevisions :many
+SELECT 
+  r.id, r.post_id, r.revision, r.title, r.content, r.type, r.edited_by, r.created_at, r.format,
+  u.username AS edited_by_username
+FROM post_revisions r
+LEFT JOIN users u ON r.edited_by = u.id
+WHERE r.post_id = $1
+ORDER BY r.revision DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListPostRevisionsParams struct {
+	PostID int32 `json:"post_id"`
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+type ListPostRevisionsRow struct {
+	ID               int32          `json:"id"`
+	PostID           int32          `json:"post_id"`
+	Revision         int32          `json:"revision"`
+	Title            string         `json:"title"`
+	Content          string         `json:"content"`
+	Type             string         `json:"type"`
+	EditedBy         sql.NullInt32  `json:"edited_by"`
+	CreatedAt        sql.NullTime   `json:"created_at"`
+	Format           string         `json:"format"`
+	EditedByUsername sql.NullString `json:"edited_by_username"`
+}
+
+func (q *Queries) ListPostRevisions(ctx context.Context, arg ListPostRevisionsParams) ([]ListPostRevisionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostRevisions, arg.PostID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostRevisionsRow{}
+	for rows.Next() {
+		var i ListPostRevisionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.PostID,
+			&i.Revision,
+			&i.Title,
+			&i.Content,
+			&i.Type,
+			&i.EditedBy,
+			&i.CreatedAt,
+			&i.Format,
+			&i.EditedByUsername,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPostSlugs = `-- name: ListPostSlugs :many
+SELECT id, title, slug FROM posts
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListPostSlugsParams struct {
+	AfterID   int32 `json:"after_id"`
+	BatchSize int32 `json:"batch_size"`
+}
+
+type ListPostSlugsRow struct {
+	ID    int32  `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+func (q *Queries) ListPostSlugs(ctx context.Context, arg ListPostSlugsParams) ([]ListPostSlugsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPostSlugs, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListPostSlugsRow{}
+	for rows.Next() {
+		var i ListPostSlugsRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.Slug); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPostTags = `-- name: ListPostTags :many
 SELECT t.id, t.name 
 FROM tags t
@@ -662,46 +1771,73 @@ func (q *Queries) ListPostTags(ctx context.Context, postID int32) ([]Tag, error)
 
 const listPosts = `-- name: ListPosts :many
 SELECT 
-  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes,
+  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes, p.tag_names, p.search_vector, p.publish_at, p.slug, p.format, p.content_html, p.toc,
   u.username,
   COUNT(DISTINCT c.id) as comment_count,
   COALESCE(array_agg(DISTINCT t.name) FILTER (WHERE t.name IS NOT NULL), ARRAY[]::text[]) as tags,
   p.likes
 FROM posts p
-LEFT JOIN users u ON p.user_id = u.id
-LEFT JOIN comments c ON p.id = c.post_id
-LEFT JOIN post_tags pt ON p.id = pt.post_id
-LEFT JOIN tags t ON pt.tag_id = t.id
-WHERE ($1::text IS NULL OR p.status = $1)
-GROUP BY p.id, u.id
-ORDER BY p.created_at DESC
-LIMIT $2 OFFSET $3
-`
-
-type ListPostsParams struct {
-	Column1 string `json:"column_1"`
-	Limit   int32  `json:"limit"`
-	Offset  int32  `json:"offset"`
-}
-
-type ListPostsRow struct {
-	ID           int32          `json:"id"`
-	UserID       sql.NullInt32  `json:"user_id"`
-	Title        string         `json:"title"`
-	Content      string         `json:"content"`
-	Type         string         `json:"type"`
-	Status       sql.NullString `json:"status"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
-	Likes        int32          `json:"likes"`
-	Username     sql.NullString `json:"username"`
-	CommentCount int64          `json:"comment_count"`
-	Tags         interface{}    `json:"tags"`
-	Likes_2      int32          `json:"likes_2"`
+LEFT JOIN users u ON p.user_id = u.id
+LEFT JOIN comments c ON p.id = c.post_id
+LEFT JOIN post_tags pt ON p.id = pt.post_id
+LEFT JOIN tags t ON pt.tag_id = t.id
+WHERE p.status = $1::text
+  AND (p.status = 'published' OR $2::bool OR p.user_id = $3)
+  AND ($4::int IS NULL OR p.user_id = $4)
+  AND ($5::int IS NULL OR EXISTS (
+    SELECT 1 FROM post_tags ft WHERE ft.post_id = p.id AND ft.tag_id = $5
+  ))
+  AND ($6::int IS NULL OR (p.created_at, p.id) < ($7::timestamp, $6::int))
+GROUP BY p.id, u.id
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $8
+`
+
+type ListPostsParams struct {
+	Status         string        `json:"status"`
+	AllStatuses    bool          `json:"all_statuses"`
+	ViewerID       sql.NullInt32 `json:"viewer_id"`
+	AuthorID       sql.NullInt32 `json:"author_id"`
+	TagID          sql.NullInt32 `json:"tag_id"`
+	AfterID        sql.NullInt32 `json:"after_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	Limit          int32         `json:"limit"`
+}
+
+type ListPostsRow struct {
+	ID           int32           `json:"id"`
+	UserID       sql.NullInt32   `json:"user_id"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	Type         string          `json:"type"`
+	Status       sql.NullString  `json:"status"`
+	CreatedAt    sql.NullTime    `json:"created_at"`
+	UpdatedAt    sql.NullTime    `json:"updated_at"`
+	Likes        int32           `json:"likes"`
+	TagNames     string          `json:"tag_names"`
+	SearchVector interface{}     `json:"-"`
+	PublishAt    sql.NullTime    `json:"publish_at"`
+	Slug         string          `json:"slug"`
+	Format       string          `json:"format"`
+	ContentHtml  sql.NullString  `json:"content_html"`
+	Toc          json.RawMessage `json:"toc"`
+	Username     sql.NullString  `json:"username"`
+	CommentCount int64           `json:"comment_count"`
+	Tags         interface{}     `json:"tags"`
+	Likes_2      int32           `json:"likes_2"`
 }
 
 func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPostsRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPosts, arg.Column1, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listPosts,
+		arg.Status,
+		arg.AllStatuses,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.TagID,
+		arg.AfterID,
+		arg.AfterCreatedAt,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -719,6 +1855,13 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPos
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Likes,
+			&i.TagNames,
+			&i.SearchVector,
+			&i.PublishAt,
+			&i.Slug,
+			&i.Format,
+			&i.ContentHtml,
+			&i.Toc,
 			&i.Username,
 			&i.CommentCount,
 			&i.Tags,
@@ -739,7 +1882,7 @@ func (q *Queries) ListPosts(ctx context.Context, arg ListPostsParams) ([]ListPos
 
 const listPostsByUser = `-- name: ListPostsByUser :many
 SELECT 
-    p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes,
+    p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes, p.tag_names, p.search_vector, p.publish_at, p.slug, p.format, p.content_html, p.toc,
     u.username,
     COUNT(DISTINCT c.id) as comment_count,
     COALESCE(array_agg(DISTINCT t.name) FILTER (WHERE t.name IS NOT NULL), ARRAY[]::text[]) as tags,
@@ -750,41 +1893,56 @@ LEFT JOIN comments c ON p.id = c.post_id
 LEFT JOIN post_tags pt ON p.id = pt.post_id
 LEFT JOIN tags t ON pt.tag_id = t.id
 WHERE p.user_id = $1
-    AND ($2::text IS NULL OR p.status = $2)
+    AND p.status = $2::text
+    AND (p.status = 'published' OR $3::bool OR p.user_id = $4)
+    AND ($5::int IS NULL OR (p.created_at, p.id) < ($6::timestamp, $5::int))
 GROUP BY p.id, u.id
-ORDER BY p.created_at DESC
-LIMIT $3 OFFSET $4
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT $7
 `
 
 type ListPostsByUserParams struct {
-	UserID  sql.NullInt32 `json:"user_id"`
-	Column2 string        `json:"column_2"`
-	Limit   int32         `json:"limit"`
-	Offset  int32         `json:"offset"`
+	UserID         sql.NullInt32 `json:"user_id"`
+	Status         string        `json:"status"`
+	AllStatuses    bool          `json:"all_statuses"`
+	ViewerID       sql.NullInt32 `json:"viewer_id"`
+	AfterID        sql.NullInt32 `json:"after_id"`
+	AfterCreatedAt sql.NullTime  `json:"after_created_at"`
+	Limit          int32         `json:"limit"`
 }
 
 type ListPostsByUserRow struct {
-	ID           int32          `json:"id"`
-	UserID       sql.NullInt32  `json:"user_id"`
-	Title        string         `json:"title"`
-	Content      string         `json:"content"`
-	Type         string         `json:"type"`
-	Status       sql.NullString `json:"status"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
-	Likes        int32          `json:"likes"`
-	Username     sql.NullString `json:"username"`
-	CommentCount int64          `json:"comment_count"`
-	Tags         interface{}    `json:"tags"`
-	Likes_2      int32          `json:"likes_2"`
+	ID           int32           `json:"id"`
+	UserID       sql.NullInt32   `json:"user_id"`
+	Title        string          `json:"title"`
+	Content      string          `json:"content"`
+	Type         string          `json:"type"`
+	Status       sql.NullString  `json:"status"`
+	CreatedAt    sql.NullTime    `json:"created_at"`
+	UpdatedAt    sql.NullTime    `json:"updated_at"`
+	Likes        int32           `json:"likes"`
+	TagNames     string          `json:"tag_names"`
+	SearchVector interface{}     `json:"-"`
+	PublishAt    sql.NullTime    `json:"publish_at"`
+	Slug         string          `json:"slug"`
+	Format       string          `json:"format"`
+	ContentHtml  sql.NullString  `json:"content_html"`
+	Toc          json.RawMessage `json:"toc"`
+	Username     sql.NullString  `json:"username"`
+	CommentCount int64           `json:"comment_count"`
+	Tags         interface{}     `json:"tags"`
+	Likes_2      int32           `json:"likes_2"`
 }
 
 func (q *Queries) ListPostsByUser(ctx context.Context, arg ListPostsByUserParams) ([]ListPostsByUserRow, error) {
 	rows, err := q.db.QueryContext(ctx, listPostsByUser,
 		arg.UserID,
-		arg.Column2,
+		arg.Status,
+		arg.AllStatuses,
+		arg.ViewerID,
+		arg.AfterID,
+		arg.AfterCreatedAt,
 		arg.Limit,
-		arg.Offset,
 	)
 	if err != nil {
 		return nil, err
@@ -803,6 +1961,13 @@ func (q *Queries) ListPostsByUser(ctx context.Context, arg ListPostsByUserParams
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Likes,
+			&i.TagNames,
+			&i.SearchVector,
+			&i.PublishAt,
+			&i.Slug,
+			&i.Format,
+			&i.ContentHtml,
+			&i.Toc,
 			&i.Username,
 			&i.CommentCount,
 			&i.Tags,
@@ -823,7 +1988,7 @@ func (q *Queries) ListPostsByUser(ctx context.Context, arg ListPostsByUserParams
 
 const listPostsOrderByLikes = `-- name: ListPostsOrderByLikes :many
 SELECT 
-  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes,
+  p.id, p.user_id, p.title, p.content, p.type, p.status, p.created_at, p.updated_at, p.likes, p.publish_at, p.slug,
   u.username,
   COUNT(DISTINCT c.id) as comment_count,
   COALESCE(array_agg(DISTINCT t.name) FILTER (WHERE t.name IS NOT NULL), ARRAY[]::text[]) as tags
@@ -832,16 +1997,21 @@ LEFT JOIN users u ON p.user_id = u.id
 LEFT JOIN comments c ON p.id = c.post_id
 LEFT JOIN post_tags pt ON p.id = pt.post_id
 LEFT JOIN tags t ON pt.tag_id = t.id
-WHERE ($1::text IS NULL OR p.status = $1)
+WHERE p.status = $1::text
+  AND (p.status = 'published' OR $2::bool OR p.user_id = $3)
+  AND ($4::int IS NULL OR (p.likes, p.id) < ($5::int, $4::int))
 GROUP BY p.id, u.id
-ORDER BY p.likes DESC, p.created_at DESC
-LIMIT $2 OFFSET $3
+ORDER BY p.likes DESC, p.id DESC
+LIMIT $6
 `
 
 type ListPostsOrderByLikesParams struct {
-	Column1 string `json:"column_1"`
-	Limit   int32  `json:"limit"`
-	Offset  int32  `json:"offset"`
+	Status      string        `json:"status"`
+	AllStatuses bool          `json:"all_statuses"`
+	ViewerID    sql.NullInt32 `json:"viewer_id"`
+	AfterID     sql.NullInt32 `json:"after_id"`
+	AfterLikes  sql.NullInt32 `json:"after_likes"`
+	Limit       int32         `json:"limit"`
 }
 
 type ListPostsOrderByLikesRow struct {
@@ -854,13 +2024,22 @@ type ListPostsOrderByLikesRow struct {
 	CreatedAt    sql.NullTime   `json:"created_at"`
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
 	Likes        int32          `json:"likes"`
+	PublishAt    sql.NullTime   `json:"publish_at"`
+	Slug         string         `json:"slug"`
 	Username     sql.NullString `json:"username"`
 	CommentCount int64          `json:"comment_count"`
 	Tags         interface{}    `json:"tags"`
 }
 
 func (q *Queries) ListPostsOrderByLikes(ctx context.Context, arg ListPostsOrderByLikesParams) ([]ListPostsOrderByLikesRow, error) {
-	rows, err := q.db.QueryContext(ctx, listPostsOrderByLikes, arg.Column1, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, listPostsOrderByLikes,
+		arg.Status,
+		arg.AllStatuses,
+		arg.ViewerID,
+		arg.AfterID,
+		arg.AfterLikes,
+		arg.Limit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -878,6 +2057,8 @@ func (q *Queries) ListPostsOrderByLikes(ctx context.Context, arg ListPostsOrderB
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Likes,
+			&i.PublishAt,
+			&i.Slug,
 			&i.Username,
 			&i.CommentCount,
 			&i.Tags,
@@ -895,6 +2076,65 @@ func (q *Queries) ListPostsOrderByLikes(ctx context.Context, arg ListPostsOrderB
 	return items, nil
 }
 
+const listSitemapEntries = `-- name: ListSitemapEntries :many
+SELECT 'post'::text AS kind, p.id, p.slug, p.updated_at AS lastmod
+FROM posts p
+WHERE p.status = 'published'
+UNION ALL
+SELECT 'user'::text, u.id, '', GREATEST(u.updated_at, MAX(p.updated_at))
+FROM users u
+JOIN posts p ON p.user_id = u.id AND p.status = 'published'
+GROUP BY u.id
+UNION ALL
+SELECT 'tag'::text, t.id, '', MAX(p.updated_at)
+FROM tags t
+JOIN post_tags pt ON pt.tag_id = t.id
+JOIN posts p ON p.id = pt.post_id AND p.status = 'published'
+GROUP BY t.id
+ORDER BY kind, id
+LIMIT $1 OFFSET $2
+`
+
+type ListSitemapEntriesParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+type ListSitemapEntriesRow struct {
+	Kind    string       `json:"kind"`
+	ID      int32        `json:"id"`
+	Slug    string       `json:"slug"`
+	Lastmod sql.NullTime `json:"lastmod"`
+}
+
+func (q *Queries) ListSitemapEntries(ctx context.Context, arg ListSitemapEntriesParams) ([]ListSitemapEntriesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSitemapEntries, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSitemapEntriesRow{}
+	for rows.Next() {
+		var i ListSitemapEntriesRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.ID,
+			&i.Slug,
+			&i.Lastmod,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTags = `-- name: ListTags :many
 SELECT id, name FROM tags
 ORDER BY name
@@ -924,7 +2164,7 @@ func (q *Queries) ListTags(ctx context.Context) ([]Tag, error) {
 }
 
 const listUserImages = `-- name: ListUserImages :many
-SELECT id, user_id, file_path, alt_text, uploaded_at FROM images
+SELECT id, user_id, file_path, alt_text, uploaded_at, size_bytes, mime_type, checksum, width, height, sanitized_at, unlinked_at FROM images
 WHERE user_id = $1
 ORDER BY uploaded_at DESC
 LIMIT $2 OFFSET $3
@@ -951,6 +2191,13 @@ func (q *Queries) ListUserImages(ctx context.Context, arg ListUserImagesParams)
 			&i.FilePath,
 			&i.AltText,
 			&i.UploadedAt,
+			&i.SizeBytes,
+			&i.MimeType,
+			&i.Checksum,
+			&i.Width,
+			&i.Height,
+			&i.SanitizedAt,
+			&i.UnlinkedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1084,6 +2331,232 @@ func (q *Queries) ListUsersOrderByPostLikes(ctx context.Context, arg ListUsersOr
 	return items, nil
 }
 
+const lockImage = `-- name: LockImage :exec
+SELECT id FROM images
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockImage(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, lockImage, id)
+	return err
+}
+
+const lockUser = `-- name: LockUser :exec
+SELECT id FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUser(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, lockUser, id)
+	return err
+}
+
+const lockUserImageByChecksum = `-- name: LockUserImageByChecksum :one
+SELECT id, user_id, file_path, alt_text, uploaded_at, size_bytes, mime_type, checksum, width, height, sanitized_at, unlinked_at FROM images
+WHERE user_id = $1 AND checksum = $2
+ORDER BY id
+LIMIT 1
+FOR UPDATE
+`
+
+type LockUserImageByChecksumParams struct {
+	UserID   sql.NullInt32 `json:"user_id"`
+	Checksum string        `json:"checksum"`
+}
+
+func (q *Queries) LockUserImageByChecksum(ctx context.Context, arg LockUserImageByChecksumParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, lockUserImageByChecksum, arg.UserID, arg.Checksum)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FilePath,
+		&i.AltText,
+		&i.UploadedAt,
+		&i.SizeBytes,
+		&i.MimeType,
+		&i.Checksum,
+		&i.Width,
+		&i.Height,
+		&i.SanitizedAt,
+		&i.UnlinkedAt,
+	)
+	return i, err
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND email_verified_at IS NULL
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, markUserEmailVerified, id)
+	return err
+}
+
+const publishScheduledPosts = `-- name: PublishScheduledPosts :many
+UPDATE posts
+SET status = 'published',
+  updated_at = CURRENT_TIMESTAMP
+WHERE status = 'scheduled' AND publish_at <= $1::timestamp
+RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes, tag_names, search_vector, publish_at, slug, format, content_html, toc
+`
+
+func (q *Queries) PublishScheduledPosts(ctx context.Context, dueBefore time.Time) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, publishScheduledPosts, dueBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Post{}
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.Type,
+			&i.Status,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Likes,
+			&i.TagNames,
+			&i.SearchVector,
+			&i.PublishAt,
+			&i.Slug,
+			&i.Format,
+			&i.ContentHtml,
+			&i.Toc,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const removePostImage = `-- name: RemovePostImage :execrows
+DELETE FROM post_images
+WHERE post_id = $1 AND image_id = $2
+`
+
+type RemovePostImageParams struct {
+	PostID  int32 `json:"post_id"`
+	ImageID int32 `json:"image_id"`
+}
+
+func (q *Queries) RemovePostImage(ctx context.Context, arg RemovePostImageParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removePostImage, arg.PostID, arg.ImageID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeEmailVerificationTokens = `-- name: RevokeEmailVerificationTokens :exec
+UPDATE email_verification_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) RevokeEmailVerificationTokens(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, revokeEmailVerificationTokens, userID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeSession(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, revokeSession, id)
+	return err
+}
+
+const revokeUserSessions = `-- name: RevokeUserSessions :exec
+UPDATE sessions
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserSessions(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, revokeUserSessions, userID)
+	return err
+}
+
+const updateComment = `-- name: UpdateComment :one
+UPDATE comments
+SET 
+  content = $2,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, post_id, user_id, content, created_at, updated_at, parent_id
+`
+
+type UpdateCommentParams struct {
+	ID      int32  `json:"id"`
+	Content string `json:"content"`
+}
+
+func (q *Queries) UpdateComment(ctx context.Context, arg UpdateCommentParams) (Comment, error) {
+	row := q.db.QueryRowContext(ctx, updateComment, arg.ID, arg.Content)
+	var i Comment
+	err := row.Scan(
+		&i.ID,
+		&i.PostID,
+		&i.UserID,
+		&i.Content,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
+
+const updateImageAltText = `-- name: UpdateImageAltText :one
+UPDATE images
+SET alt_text = $2
+WHERE id = $1
+RETURNING id, user_id, file_path, alt_text, uploaded_at, size_bytes, mime_type, checksum, width, height, sanitized_at, unlinked_at
+`
+
+type UpdateImageAltTextParams struct {
+	ID      int32          `json:"id"`
+	AltText sql.NullString `json:"alt_text"`
+}
+
+func (q *Queries) UpdateImageAltText(ctx context.Context, arg UpdateImageAltTextParams) (Image, error) {
+	row := q.db.QueryRowContext(ctx, updateImageAltText, arg.ID, arg.AltText)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.FilePath,
+		&i.AltText,
+		&i.UploadedAt,
+		&i.SizeBytes,
+		&i.MimeType,
+		&i.Checksum,
+		&i.Width,
+		&i.Height,
+		&i.SanitizedAt,
+		&i.UnlinkedAt,
+	)
+	return i, err
+}
+
 const updatePost = `-- name: UpdatePost :one
 UPDATE posts
 SET 
@@ -1091,17 +2564,25 @@ SET
   content = COALESCE($3, content),
   type = COALESCE($4, type),
   status = COALESCE($5, status),
+  publish_at = COALESCE($6, publish_at),
+  format = COALESCE($7, format),
+  content_html = $8,
+  toc = $9,
   updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes
+RETURNING id, user_id, title, content, type, status, created_at, updated_at, likes, tag_names, search_vector, publish_at, slug, format, content_html, toc
 `
 
 type UpdatePostParams struct {
-	ID      int32          `json:"id"`
-	Title   string         `json:"title"`
-	Content string         `json:"content"`
-	Type    string         `json:"type"`
-	Status  sql.NullString `json:"status"`
+	ID          int32           `json:"id"`
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Type        string          `json:"type"`
+	Status      sql.NullString  `json:"status"`
+	PublishAt   sql.NullTime    `json:"publish_at"`
+	Format      string          `json:"format"`
+	ContentHtml sql.NullString  `json:"content_html"`
+	Toc         json.RawMessage `json:"toc"`
 }
 
 func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, error) {
@@ -1111,6 +2592,10 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		arg.Content,
 		arg.Type,
 		arg.Status,
+		arg.PublishAt,
+		arg.Format,
+		arg.ContentHtml,
+		arg.Toc,
 	)
 	var i Post
 	err := row.Scan(
@@ -1123,21 +2608,94 @@ func (q *Queries) UpdatePost(ctx context.Context, arg UpdatePostParams) (Post, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Likes,
+		&i.TagNames,
+		&i.SearchVector,
+		&i.PublishAt,
+		&i.Slug,
+		&i.Format,
+		&i.ContentHtml,
+		&i.Toc,
 	)
 	return i, err
 }
 
+const updatePostImageOrder = `-- name: UpdatePostImageOrder :execrows
+UPDATE post_images
+SET display_order = $3
+WHERE post_id = $1 AND image_id = $2
+`
+
+type UpdatePostImageOrderParams struct {
+	PostID       int32         `json:"post_id"`
+	ImageID      int32         `json:"image_id"`
+	DisplayOrder sql.NullInt32 `json:"display_order"`
+}
+
+func (q *Queries) UpdatePostImageOrder(ctx context.Context, arg UpdatePostImageOrderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updatePostImageOrder, arg.PostID, arg.ImageID, arg.DisplayOrder)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updatePostRendering = `-- name: UpdatePostRendering :exec
+UPDATE posts
+SET
+  content_html = $2,
+  toc = $3
+WHERE id = $1 AND content = $4 AND format = $5
+`
+
+type UpdatePostRenderingParams struct {
+	ID          int32           `json:"id"`
+	ContentHtml sql.NullString  `json:"content_html"`
+	Toc         json.RawMessage `json:"toc"`
+	Content     string          `json:"content"`
+	Format      string          `json:"format"`
+}
+
+func (q *Queries) UpdatePostRendering(ctx context.Context, arg UpdatePostRenderingParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostRendering,
+		arg.ID,
+		arg.ContentHtml,
+		arg.Toc,
+		arg.Content,
+		arg.Format,
+	)
+	return err
+}
+
+const updatePostSlug = `-- name: UpdatePostSlug :exec
+UPDATE posts
+SET slug = $2
+WHERE id = $1
+`
+
+type UpdatePostSlugParams struct {
+	ID   int32  `json:"id"`
+	Slug string `json:"slug"`
+}
+
+func (q *Queries) UpdatePostSlug(ctx context.Context, arg UpdatePostSlugParams) error {
+	_, err := q.db.ExecContext(ctx, updatePostSlug, arg.ID, arg.Slug)
+	return err
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET 
-  username = COALESCE($2, username),
-  email = COALESCE($3, email),
+  username = COALESCE(NULLIF($2, ''), username),
+  email = COALESCE(NULLIF($3, ''), email),
   first_name = COALESCE($4, first_name),
   last_name = COALESCE($5, last_name),
   bio = COALESCE($6, bio),
+  email_verified_at = CASE
+    WHEN COALESCE(NULLIF($3, ''), email) = email THEN email_verified_at
+  END,
   updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
-RETURNING id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at
+RETURNING id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at, role, email_verified_at
 `
 
 type UpdateUserParams struct {
@@ -1169,6 +2727,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Bio,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
@@ -1205,3 +2765,64 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 	)
 	return i, err
 }
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users
+SET
+  role = $2,
+  updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+RETURNING id, username, email, password_hash, first_name, last_name, bio, created_at, updated_at, role, email_verified_at
+`
+
+type UpdateUserRoleParams struct {
+	ID   int32  `json:"id"`
+	Role string `json:"role"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.FirstName,
+		&i.LastName,
+		&i.Bio,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const useEmailVerificationToken = `-- name: UseEmailVerificationToken :execrows
+UPDATE email_verification_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) UseEmailVerificationToken(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useEmailVerificationToken, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const usePasswordResetToken = `-- name: UsePasswordResetToken :execrows
+UPDATE password_reset_tokens
+SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL
+`
+
+func (q *Queries) UsePasswordResetToken(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, usePasswordResetToken, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T, user User) Session {
+	_, hash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+
+	arg := CreateSessionParams{
+		UserID:           user.ID,
+		RefreshTokenHash: hash,
+		UserAgent:        "test-agent",
+		ClientIp:         "127.0.0.1",
+		ExpiresAt:        time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, session.ID)
+	require.Equal(t, arg.UserID, session.UserID)
+	require.Equal(t, arg.RefreshTokenHash, session.RefreshTokenHash)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.False(t, session.RevokedAt.Valid)
+
+	return session
+}
+
+func TestGetSessionByRefreshTokenHash(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	got, err := testQueries.GetSessionByRefreshTokenHash(context.Background(), session.RefreshTokenHash)
+	require.NoError(t, err)
+	require.Equal(t, session.ID, got.ID)
+	require.Equal(t, user.ID, got.UserID)
+}
+
+func TestRevokeSession(t *testing.T) {
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	err := testQueries.RevokeSession(context.Background(), session.ID)
+	require.NoError(t, err)
+
+	got, err := testQueries.GetSessionByRefreshTokenHash(context.Background(), session.RefreshTokenHash)
+	require.NoError(t, err)
+	require.True(t, got.RevokedAt.Valid)
+}
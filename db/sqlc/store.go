@@ -5,18 +5,38 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
+	"unicode"
+
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/lib/pq"
 )
 
 type Store interface {
 	Querier
 	CreatePostTx(ctx context.Context, arg CreatePostTxParams) (Post, error)
-	UploadPostImageTx(ctx context.Context, arg UploadPostImageTxParams) error
+	CreatePostWithSlugTx(ctx context.Context, arg CreatePostParams) (Post, error)
+	UploadPostImageTx(ctx context.Context, arg UploadPostImageTxParams) (UploadPostImageTxResult, error)
+	ReorderPostImagesTx(ctx context.Context, arg ReorderPostImagesTxParams) ([]Image, error)
+	DeleteOrphanedImageTx(ctx context.Context, id int32) (int64, error)
 	UpdatePostTx(ctx context.Context, arg UpdatePostTxParams) (UpdatePostTxResult, error)
+	EditPostTx(ctx context.Context, arg EditPostTxParams) (EditPostTxResult, error)
+	RestorePostRevisionTx(ctx context.Context, arg RestorePostRevisionTxParams) (EditPostTxResult, error)
+	BackfillPostSlugs(ctx context.Context) (int, error)
 	AddPostTagTx(ctx context.Context, arg PostTagTxParams) (PostTag, error)
 	BatchAddPostTagsTx(ctx context.Context, arg BatchAddPostTagsParams) ([]PostTag, error)
 	FilterPosts(ctx context.Context, filter FilterParams) ([]FilteredPost, error)
+	CountFilteredPosts(ctx context.Context, filter FilterParams) (int64, error)
+	ListCommentThreads(ctx context.Context, arg ListCommentThreadsParams) ([]CommentThreadRow, error)
+	LikePostTx(ctx context.Context, arg PostLikeTxParams) (PostLikeTxResult, error)
+	UnlikePostTx(ctx context.Context, arg PostLikeTxParams) (PostLikeTxResult, error)
+	SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostRow, error)
+	CountSearchPosts(ctx context.Context, tsQuery string) (int64, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) error
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) error
 }
 
 type SQLStore struct {
@@ -50,49 +70,337 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 	return tx.Commit()
 }
 
+// maxSlugAttempts bounds how often a transaction picking a post slug runs
+// again after a concurrent one took the slug first
+const maxSlugAttempts = 5
+
+// execSlugTx executes a function that picks a post slug within a database
+// transaction. When a concurrent transaction committed the same slug first,
+// the unique constraint aborts this one and it runs again, so uniquePostSlug
+// sees that slug taken and moves on to the next suffix.
+func (store *SQLStore) execSlugTx(ctx context.Context, fn func(*Queries) error) error {
+	for attempt := 1; ; attempt++ {
+		err := store.execTx(ctx, fn)
+		if attempt < maxSlugAttempts && isSlugConflict(err) {
+			continue
+		}
+		return err
+	}
+}
+
+// isSlugConflict reports whether err is a unique violation on a post slug,
+// current or kept as a redirect
+func isSlugConflict(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code.Name() != "unique_violation" {
+		return false
+	}
+	return pqErr.Constraint == "posts_slug_key" || pqErr.Constraint == "post_slug_redirects_pkey"
+}
+
 // db/sqlc/store.go
 
 type UploadPostImageTxParams struct {
-	PostID   int32  `json:"post_id"`
-	UserID   int32  `json:"user_id"`
-	FilePath string `json:"file_path"`
-	AltText  string `json:"alt_text"`
-	Order    int32  `json:"order"`
+	PostID    int32  `json:"post_id"`
+	UserID    int32  `json:"user_id"`
+	FilePath  string `json:"file_path"`
+	AltText   string `json:"alt_text"`
+	Order     int32  `json:"order"` // zero appends the image after the others
+	SizeBytes int64  `json:"size_bytes"`
+	MimeType  string `json:"mime_type"`
+	Checksum  string `json:"checksum"`
+	Width     int32  `json:"width"`
+	Height    int32  `json:"height"`
+	// When metadata was stripped from the blob, zero if it was not
+	SanitizedAt time.Time `json:"sanitized_at"`
+	// Resized copies already written to the blob store
+	Variants []UploadImageVariant `json:"variants"`
+	// Limits of the user checked again while their row is locked, so
+	// concurrent uploads cannot exceed them together
+	Quota util.StorageQuota `json:"quota"`
 }
 
-func (store *SQLStore) UploadPostImageTx(ctx context.Context, arg UploadPostImageTxParams) error {
-	return store.execTx(ctx, func(q *Queries) error {
-		// 1. Create image record first
-		image, err := q.CreateImage(ctx, CreateImageParams{
-			UserID: sql.NullInt32{
-				Int32: arg.UserID,
-				Valid: true,
-			},
-			FilePath: arg.FilePath,
-			AltText: sql.NullString{
-				String: arg.AltText,
-				Valid:  true,
-			},
-		})
-		if err != nil {
-			return err
+// QuotaExceededError is returned by UploadPostImageTx when storing the upload
+// would take the user past their quota. Usage is what they used before it.
+type QuotaExceededError struct {
+	Usage GetUserStorageUsageRow
+	Size  int64
+}
+
+func (err *QuotaExceededError) Error() string {
+	return fmt.Sprintf("storage quota exceeded: %d images and %d bytes used, the upload needs %d",
+		err.Usage.ImageCount, err.Usage.ImageBytes+err.Usage.VariantBytes, err.Size)
+}
+
+type UploadImageVariant struct {
+	Name      string `json:"name"`
+	FilePath  string `json:"file_path"`
+	MimeType  string `json:"mime_type"`
+	Width     int32  `json:"width"`
+	Height    int32  `json:"height"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+type UploadPostImageTxResult struct {
+	Image    Image          `json:"image"`
+	Variants []ImageVariant `json:"variants"`
+	// Set when an identical image of the user was linked instead of the upload
+	Reused bool `json:"reused"`
+}
+
+// IsUploaded reports whether the file of the image is a blob it owns. Images
+// recorded before uploads existed only have the path the client sent. The
+// checksum of an upload was cleared when it duplicated an older one, but its
+// type is always recorded.
+func (image Image) IsUploaded() bool {
+	return image.Checksum != "" || image.MimeType != ""
+}
+
+// UploadPostImageTx records an uploaded image with its variants and links it
+// to the post. When the user already has an image with the same checksum that
+// image is linked instead and Reused is set, the caller should then delete
+// the blobs it stored for the upload. A reused image keeps its alt text, which
+// every post showing it shares, unless it has none yet. Without a FilePath
+// nothing was stored and sql.ErrNoRows is returned if there is no image to
+// reuse.
+func (store *SQLStore) UploadPostImageTx(ctx context.Context, arg UploadPostImageTxParams) (UploadPostImageTxResult, error) {
+	var result UploadPostImageTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		// 1. Look for the same content uploaded before by the user
+		if arg.Checksum != "" {
+			result.Image, result.Variants, err = findUserImage(ctx, q, arg.UserID, arg.Checksum)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			result.Reused = err == nil
+		}
+
+		// 2. Otherwise record the new image with its variants. The insert of
+		// an identical upload committed since the lookup is skipped by the
+		// unique checksum index, that image is linked instead.
+		if !result.Reused {
+			if arg.FilePath == "" {
+				return sql.ErrNoRows
+			}
+			if err := checkStorageQuota(ctx, q, arg); err != nil {
+				return err
+			}
+			result.Image, result.Variants, err = createUploadedImage(ctx, q, arg)
+			if err == sql.ErrNoRows && arg.Checksum != "" {
+				result.Image, result.Variants, err = findUserImage(ctx, q, arg.UserID, arg.Checksum)
+				result.Reused = err == nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.Reused && !result.Image.AltText.Valid && arg.AltText != "" {
+			result.Image, err = q.UpdateImageAltText(ctx, UpdateImageAltTextParams{
+				ID: result.Image.ID,
+				AltText: sql.NullString{
+					String: arg.AltText,
+					Valid:  true,
+				},
+			})
+			if err != nil {
+				return err
+			}
 		}
 
-		// 2. Then link it to the post
-		err = q.AddPostImage(ctx, AddPostImageParams{
+		// 3. Then link it to the post, an image already in the post stays where it is
+		order := arg.Order
+		if order == 0 {
+			order, err = q.GetNextPostImageOrder(ctx, arg.PostID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return q.AddPostImage(ctx, AddPostImageParams{
 			PostID:  arg.PostID,
-			ImageID: image.ID,
+			ImageID: result.Image.ID,
 			DisplayOrder: sql.NullInt32{
-				Int32: arg.Order,
+				Int32: order,
 				Valid: true,
 			},
 		})
+	})
+
+	return result, err
+}
+
+// checkStorageQuota locks the user row, so uploads of the user are recorded
+// one at a time, and verifies that the upload keeps them within their quota
+func checkStorageQuota(ctx context.Context, q *Queries, arg UploadPostImageTxParams) error {
+	if arg.Quota.MaxBytes == 0 && arg.Quota.MaxImages == 0 {
+		return nil
+	}
+
+	if err := q.LockUser(ctx, arg.UserID); err != nil {
+		return err
+	}
+
+	usage, err := q.GetUserStorageUsage(ctx, sql.NullInt32{Int32: arg.UserID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	size := arg.SizeBytes
+	for _, variant := range arg.Variants {
+		size += variant.SizeBytes
+	}
+
+	if (arg.Quota.MaxImages > 0 && usage.ImageCount >= arg.Quota.MaxImages) ||
+		(arg.Quota.MaxBytes > 0 && usage.ImageBytes+usage.VariantBytes+size > arg.Quota.MaxBytes) {
+		return &QuotaExceededError{Usage: usage, Size: size}
+	}
+	return nil
+}
+
+// findUserImage returns the image of the user with the checksum and its
+// variants, or sql.ErrNoRows. The image stays locked until the transaction
+// ends, so it cannot be collected as an orphan before it is linked.
+func findUserImage(ctx context.Context, q *Queries, userID int32, checksum string) (Image, []ImageVariant, error) {
+	image, err := q.LockUserImageByChecksum(ctx, LockUserImageByChecksumParams{
+		UserID: sql.NullInt32{
+			Int32: userID,
+			Valid: true,
+		},
+		Checksum: checksum,
+	})
+	if err != nil {
+		return image, nil, err
+	}
+
+	variants, err := q.ListImageVariants(ctx, image.ID)
+	return image, variants, err
+}
+
+// DeleteOrphanedImageTx deletes an image unless it is linked to a post and
+// returns how many rows were deleted. The image is locked first, so an upload
+// reusing it either links it before the check or no longer finds it.
+func (store *SQLStore) DeleteOrphanedImageTx(ctx context.Context, id int32) (int64, error) {
+	var deleted int64
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		if err := q.LockImage(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		deleted, err = q.DeleteOrphanedImage(ctx, id)
+		return err
+	})
+
+	return deleted, err
+}
+
+// createUploadedImage creates the image record of an upload and its variants
+func createUploadedImage(ctx context.Context, q *Queries, arg UploadPostImageTxParams) (Image, []ImageVariant, error) {
+	image, err := q.CreateImage(ctx, CreateImageParams{
+		UserID: sql.NullInt32{
+			Int32: arg.UserID,
+			Valid: true,
+		},
+		FilePath: arg.FilePath,
+		AltText: sql.NullString{
+			String: arg.AltText,
+			Valid:  arg.AltText != "",
+		},
+		SizeBytes: arg.SizeBytes,
+		MimeType:  arg.MimeType,
+		Checksum:  arg.Checksum,
+		Width:     arg.Width,
+		Height:    arg.Height,
+		SanitizedAt: sql.NullTime{
+			Time:  arg.SanitizedAt,
+			Valid: !arg.SanitizedAt.IsZero(),
+		},
+	})
+	if err != nil {
+		return image, nil, err
+	}
+
+	variants := make([]ImageVariant, 0, len(arg.Variants))
+	for _, variant := range arg.Variants {
+		imageVariant, err := q.CreateImageVariant(ctx, CreateImageVariantParams{
+			ImageID:   image.ID,
+			Name:      variant.Name,
+			FilePath:  variant.FilePath,
+			MimeType:  variant.MimeType,
+			Width:     variant.Width,
+			Height:    variant.Height,
+			SizeBytes: variant.SizeBytes,
+		})
+		if err != nil {
+			return image, nil, err
+		}
+		variants = append(variants, imageVariant)
+	}
+
+	return image, variants, nil
+}
+
+// ErrImageOrderMismatch is returned by ReorderPostImagesTx when the new order
+// does not list each of the post's images exactly once
+var ErrImageOrderMismatch = errors.New("image order must list every image of the post exactly once")
+
+type ReorderPostImagesTxParams struct {
+	PostID   int32   `json:"post_id"`
+	ImageIDs []int32 `json:"image_ids"`
+}
+
+// ReorderPostImagesTx sets the display order of all images of a post at once
+// and returns them in their new order
+func (store *SQLStore) ReorderPostImagesTx(ctx context.Context, arg ReorderPostImagesTxParams) ([]Image, error) {
+	var images []Image
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// 1. Check the new order against the images linked right now
+		current, err := q.ListPostImages(ctx, arg.PostID)
 		if err != nil {
 			return err
 		}
+		if len(current) != len(arg.ImageIDs) {
+			return ErrImageOrderMismatch
+		}
+
+		byID := make(map[int32]Image, len(current))
+		for _, image := range current {
+			byID[image.ID] = image
+		}
+
+		// 2. Number them from 1 in the requested order
+		images = make([]Image, 0, len(arg.ImageIDs))
+		for i, imageID := range arg.ImageIDs {
+			image, ok := byID[imageID]
+			if !ok {
+				return ErrImageOrderMismatch
+			}
+			delete(byID, imageID)
+
+			_, err := q.UpdatePostImageOrder(ctx, UpdatePostImageOrderParams{
+				PostID:  arg.PostID,
+				ImageID: imageID,
+				DisplayOrder: sql.NullInt32{
+					Int32: int32(i + 1),
+					Valid: true,
+				},
+			})
+			if err != nil {
+				return err
+			}
+			images = append(images, image)
+		}
 
 		return nil
 	})
+
+	return images, err
 }
 
 type CreatePostTxParams struct {
@@ -111,8 +419,11 @@ type CreatePostImage struct {
 func (store *SQLStore) CreatePostTx(ctx context.Context, arg CreatePostTxParams) (Post, error) {
 	var post Post
 
-	err := store.execTx(ctx, func(q *Queries) error {
-		var err error
+	err := store.execSlugTx(ctx, func(q *Queries) error {
+		slug, err := uniquePostSlug(ctx, q, arg.Title, 0)
+		if err != nil {
+			return err
+		}
 
 		// 1. Create post first
 		post, err = q.CreatePost(ctx, CreatePostParams{
@@ -127,6 +438,12 @@ func (store *SQLStore) CreatePostTx(ctx context.Context, arg CreatePostTxParams)
 				String: "published",
 				Valid:  true,
 			},
+			PublishAt: sql.NullTime{
+				Time:  time.Now().UTC(),
+				Valid: true,
+			},
+			Slug:   slug,
+			Format: "markdown",
 		})
 		if err != nil {
 			return err
@@ -181,36 +498,78 @@ func (store *SQLStore) CreatePostTx(ctx context.Context, arg CreatePostTxParams)
 	return post, err
 }
 
+// CreatePostWithSlugTx creates a post with a slug made from its title. The
+// slug in arg is replaced by one no other post uses, now or as an old slug.
+func (store *SQLStore) CreatePostWithSlugTx(ctx context.Context, arg CreatePostParams) (Post, error) {
+	var post Post
+
+	err := store.execSlugTx(ctx, func(q *Queries) error {
+		var err error
+		arg.Slug, err = uniquePostSlug(ctx, q, arg.Title, 0)
+		if err != nil {
+			return err
+		}
+
+		post, err = q.CreatePost(ctx, arg)
+		return err
+	})
+
+	return post, err
+}
+
+// uniquePostSlug makes a slug from title that no other post uses, adding -2,
+// -3 and so on while it is taken. postID is the post the slug is for, zero for
+// a new one, so a post may take back one of its own old slugs.
+func uniquePostSlug(ctx context.Context, q *Queries, title string, postID int32) (string, error) {
+	base := util.Slugify(title)
+	slug := base
+	for n := 2; ; n++ {
+		taken, err := q.IsSlugTaken(ctx, IsSlugTakenParams{
+			Slug:   slug,
+			PostID: postID,
+		})
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
 // db/sqlc/store.go
 
 type UpdatePostTxParams struct {
-	ID      int32   `json:"id"`
-	Title   string  `json:"title"`
-	Content string  `json:"content"`
-	Tags    []int32 `json:"tags"`
+	ID       int32   `json:"id"`
+	Title    string  `json:"title"`
+	Content  string  `json:"content"`
+	Tags     []int32 `json:"tags"`
+	EditedBy int32   `json:"edited_by"` // user making the change, zero if unknown
 }
 
 type UpdatePostTxResult struct {
-	Post Post      `json:"post"`
-	Tags []PostTag `json:"tags"`
+	Post     Post          `json:"post"`
+	Revision *PostRevision `json:"revision"`
+	Tags     []PostTag     `json:"tags"`
 }
 
 func (store *SQLStore) UpdatePostTx(ctx context.Context, arg UpdatePostTxParams) (UpdatePostTxResult, error) {
 	var result UpdatePostTxResult
 
-	err := store.execTx(ctx, func(q *Queries) error {
+	err := store.execSlugTx(ctx, func(q *Queries) error {
 		var err error
+		result.Tags = nil // from an attempt that lost its slug
 
-		// 1. Update post
-		post, err := q.UpdatePost(ctx, UpdatePostParams{
+		// 1. Update post, keeping the version it replaces
+		result.Post, result.Revision, err = editPost(ctx, q, UpdatePostParams{
 			ID:      arg.ID,
 			Title:   arg.Title,
 			Content: arg.Content,
-		})
+		}, arg.EditedBy)
 		if err != nil {
 			return err
 		}
-		result.Post = post
 
 		// 2. Delete existing tags - always access tables in the same order to prevent deadlocks
 		err = q.DeletePostTags(ctx, arg.ID)
@@ -241,6 +600,219 @@ func (store *SQLStore) UpdatePostTx(ctx context.Context, arg UpdatePostTxParams)
 	return result, err
 }
 
+type EditPostTxParams struct {
+	UpdatePostParams
+	EditedBy int32 `json:"edited_by"` // user making the change, zero if unknown
+}
+
+type EditPostTxResult struct {
+	Post Post `json:"post"`
+	// Revision keeps the replaced version, nil when title, content, type and
+	// format did not change
+	Revision *PostRevision `json:"revision"`
+}
+
+// EditPostTx updates a post and saves the version it replaces as a revision
+func (store *SQLStore) EditPostTx(ctx context.Context, arg EditPostTxParams) (EditPostTxResult, error) {
+	var result EditPostTxResult
+
+	err := store.execSlugTx(ctx, func(q *Queries) error {
+		var err error
+		result.Post, result.Revision, err = editPost(ctx, q, arg.UpdatePostParams, arg.EditedBy)
+		return err
+	})
+
+	return result, err
+}
+
+type RestorePostRevisionTxParams struct {
+	PostID   int32 `json:"post_id"`
+	Revision int32 `json:"revision"`
+	EditedBy int32 `json:"edited_by"`
+}
+
+// RestorePostRevisionTx brings back the title, content, type and format of a
+// revision.
+// The version it replaces is saved as a new revision, so a restore can be
+// undone like any other edit. It returns sql.ErrNoRows when the post has no
+// such revision.
+func (store *SQLStore) RestorePostRevisionTx(ctx context.Context, arg RestorePostRevisionTxParams) (EditPostTxResult, error) {
+	var result EditPostTxResult
+
+	err := store.execSlugTx(ctx, func(q *Queries) error {
+		revision, err := q.GetPostRevision(ctx, GetPostRevisionParams{
+			PostID:   arg.PostID,
+			Revision: arg.Revision,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Post, result.Revision, err = editPost(ctx, q, UpdatePostParams{
+			ID:      arg.PostID,
+			Title:   revision.Title,
+			Content: revision.Content,
+			Type:    revision.Type,
+			Format:  revision.Format,
+		}, arg.EditedBy)
+		return err
+	})
+
+	return result, err
+}
+
+// editPost updates a post and saves its current title, content, type and
+// format as a new revision first. Empty values in arg keep what is stored. Edits that
+// leave the text alone, like status changes, save no revision. A new title
+// gives the post a new slug and keeps the old one as a redirect. Without a
+// rendering in arg, the stored one is kept unless content or format change.
+func editPost(ctx context.Context, q *Queries, arg UpdatePostParams, editedBy int32) (Post, *PostRevision, error) {
+	// Locking the post keeps concurrent edits from numbering revisions alike
+	current, err := q.GetPostForUpdate(ctx, arg.ID)
+	if err != nil {
+		return Post{}, nil, err
+	}
+
+	if arg.Title == "" {
+		arg.Title = current.Title
+	}
+	if arg.Content == "" {
+		arg.Content = current.Content
+	}
+	if arg.Type == "" {
+		arg.Type = current.Type
+	}
+	if arg.Format == "" {
+		arg.Format = current.Format
+	}
+	// The rendered content stays valid as long as what it came from does
+	if !arg.ContentHtml.Valid && arg.Content == current.Content && arg.Format == current.Format {
+		arg.ContentHtml = current.ContentHtml
+		arg.Toc = current.Toc
+	}
+
+	var revision *PostRevision
+	if arg.Title != current.Title || arg.Content != current.Content || arg.Type != current.Type || arg.Format != current.Format {
+		saved, err := q.CreatePostRevision(ctx, CreatePostRevisionParams{
+			PostID:   current.ID,
+			Title:    current.Title,
+			Content:  current.Content,
+			Type:     current.Type,
+			EditedBy: sql.NullInt32{Int32: editedBy, Valid: editedBy != 0},
+			Format:   current.Format,
+		})
+		if err != nil {
+			return Post{}, nil, err
+		}
+		revision = &saved
+	}
+
+	if arg.Title != current.Title {
+		if err := renamePostSlug(ctx, q, current, arg.Title); err != nil {
+			return Post{}, nil, err
+		}
+	}
+
+	post, err := q.UpdatePost(ctx, arg)
+	if err != nil {
+		return Post{}, nil, err
+	}
+	return post, revision, nil
+}
+
+// renamePostSlug moves post to the slug of its new title and keeps the old
+// slug as a redirect, so links that were shared keep working
+func renamePostSlug(ctx context.Context, q *Queries, post Post, title string) error {
+	slug, err := uniquePostSlug(ctx, q, title, post.ID)
+	if err != nil || slug == post.Slug {
+		return err
+	}
+
+	err = q.CreatePostSlugRedirect(ctx, CreatePostSlugRedirectParams{
+		Slug:   post.Slug,
+		PostID: post.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The new slug may be one the post had before
+	if err := q.DeletePostSlugRedirect(ctx, slug); err != nil {
+		return err
+	}
+
+	return q.UpdatePostSlug(ctx, UpdatePostSlugParams{
+		ID:   post.ID,
+		Slug: slug,
+	})
+}
+
+// slugBatchSize is how many posts BackfillPostSlugs loads at a time
+const slugBatchSize = 100
+
+// BackfillPostSlugs gives every post whose slug was not made from its title
+// by util.Slugify the slug a new post with that title would get, keeping the
+// old one as a redirect. Migrating existing posts only approximates Slugify
+// in SQL. It returns how many posts got a new slug.
+func (store *SQLStore) BackfillPostSlugs(ctx context.Context) (int, error) {
+	renamed := 0
+	var afterID int32
+	for {
+		posts, err := store.ListPostSlugs(ctx, ListPostSlugsParams{
+			AfterID:   afterID,
+			BatchSize: slugBatchSize,
+		})
+		if err != nil {
+			return renamed, err
+		}
+
+		for _, post := range posts {
+			afterID = post.ID
+			if isSlugOf(post.Slug, post.Title) {
+				continue
+			}
+
+			err := store.execSlugTx(ctx, func(q *Queries) error {
+				current, err := q.GetPostForUpdate(ctx, post.ID)
+				if err != nil || isSlugOf(current.Slug, current.Title) {
+					return err
+				}
+				return renamePostSlug(ctx, q, current, current.Title)
+			})
+			if err == sql.ErrNoRows {
+				continue // deleted since it was listed
+			}
+			if err != nil {
+				return renamed, err
+			}
+			renamed++
+		}
+
+		if len(posts) < slugBatchSize {
+			return renamed, nil
+		}
+	}
+}
+
+// isSlugOf reports whether slug is the one uniquePostSlug would make from
+// title, with or without a -2, -3, ... suffix
+func isSlugOf(slug, title string) bool {
+	base := util.Slugify(title)
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok || suffix == "" || suffix[0] == '0' {
+		return false
+	}
+	for _, r := range suffix {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return suffix != "1"
+}
+
 type PostTagTxParams struct {
 	PostID int32 `json:"post_id"`
 	TagID  int32 `json:"tag_id"`
@@ -339,14 +911,172 @@ func (store *SQLStore) UpdatePostTagsTx(ctx context.Context, arg UpdatePostTagsP
 	})
 }
 
+type PostLikeTxParams struct {
+	UserID int32 `json:"user_id"`
+	PostID int32 `json:"post_id"`
+}
+
+type PostLikeTxResult struct {
+	Post      Post `json:"post"`
+	LikedByMe bool `json:"liked_by_me"`
+}
+
+// LikePostTx records that a user likes a post. Liking a post twice is a no-op,
+// the likes counter only moves when a new post_likes row is inserted.
+func (store *SQLStore) LikePostTx(ctx context.Context, arg PostLikeTxParams) (PostLikeTxResult, error) {
+	var result PostLikeTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// 1. Lock the post so concurrent likes update the counter one at a time
+		post, err := q.GetPostForUpdate(ctx, arg.PostID)
+		if err != nil {
+			return err
+		}
+
+		// 2. Insert the like, skipping it if it already exists
+		inserted, err := q.CreatePostLike(ctx, CreatePostLikeParams{
+			UserID: arg.UserID,
+			PostID: arg.PostID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 3. Keep the counter in sync
+		if inserted > 0 {
+			post, err = q.IncrementPostLikes(ctx, arg.PostID)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Post = post
+		result.LikedByMe = true
+		return nil
+	})
+
+	return result, err
+}
+
+// UnlikePostTx removes a user's like from a post. Unliking a post that was not
+// liked is a no-op.
+func (store *SQLStore) UnlikePostTx(ctx context.Context, arg PostLikeTxParams) (PostLikeTxResult, error) {
+	var result PostLikeTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		// 1. Lock the post so concurrent unlikes update the counter one at a time
+		post, err := q.GetPostForUpdate(ctx, arg.PostID)
+		if err != nil {
+			return err
+		}
+
+		// 2. Delete the like if there is one
+		deleted, err := q.DeletePostLike(ctx, DeletePostLikeParams{
+			UserID: arg.UserID,
+			PostID: arg.PostID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 3. Keep the counter in sync
+		if deleted > 0 {
+			post, err = q.DecrementPostLikes(ctx, arg.PostID)
+			if err != nil {
+				return err
+			}
+		}
+
+		result.Post = post
+		result.LikedByMe = false
+		return nil
+	})
+
+	return result, err
+}
+
+type ResetPasswordTxParams struct {
+	TokenID      int32  `json:"token_id"`
+	UserID       int32  `json:"user_id"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// ResetPasswordTx consumes a password reset token, sets the new password and
+// signs the user out everywhere. It returns sql.ErrNoRows if the token was
+// already used, so a token can never reset a password twice.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		// 1. Mark the token used, losing a race with another reset counts as used
+		used, err := q.UsePasswordResetToken(ctx, arg.TokenID)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return sql.ErrNoRows
+		}
+
+		// 2. Set the new password
+		_, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			ID:           arg.UserID,
+			PasswordHash: arg.PasswordHash,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 3. Revoke every session so stolen refresh tokens stop working
+		return q.RevokeUserSessions(ctx, arg.UserID)
+	})
+}
+
+type VerifyEmailTxParams struct {
+	TokenID int32 `json:"token_id"`
+	UserID  int32 `json:"user_id"`
+}
+
+// VerifyEmailTx consumes an email verification token and marks the user's
+// email verified. It returns sql.ErrNoRows if the token was already used.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		used, err := q.UseEmailVerificationToken(ctx, arg.TokenID)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return sql.ErrNoRows
+		}
+
+		return q.MarkUserEmailVerified(ctx, arg.UserID)
+	})
+}
+
 type FilterParams struct {
-	UserID    *int32
-	Status    *string
-	SortBy    string
-	SortOrder string
-	Limit     int32
-	Offset    int32
+	UserID        *int32
+	Status        *string
+	ViewerID      *int32 // posts that are not published are only listed to their author
+	AllStatuses   bool   // list posts that are not published regardless of their author
+	Type          *string
+	Username      *string    // author username
+	Tags          []string   // tag names, matched according to TagMatch
+	TagMatch      string     // "any" (default) or "all"
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	MinLikes      *int32
+	SortBy        string
+	SortOrder     string
+	After         *FilterCursor // list the posts sorted after this one
+	Limit         int32
 }
+
+// FilterCursor is the last post of the previous page of filtered posts. Only
+// its id and the field the posts are sorted by are used.
+type FilterCursor struct {
+	ID        int32
+	CreatedAt time.Time
+	Likes     int32
+	Title     string
+}
+
 type FilteredPost struct {
 	ID           int32          `json:"id"`
 	UserID       sql.NullInt32  `json:"user_id"`
@@ -357,6 +1087,8 @@ type FilteredPost struct {
 	CreatedAt    sql.NullTime   `json:"created_at"`
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
 	Likes        int32          `json:"likes"`
+	PublishAt    sql.NullTime   `json:"publish_at"`
+	Slug         string         `json:"slug"`
 	Username     sql.NullString `json:"username"`
 	CommentCount int64          `json:"comment_count"`
 	Tags         interface{}    `json:"tags"`
@@ -364,8 +1096,8 @@ type FilteredPost struct {
 
 // Implementation of FilterPosts for SQLStore
 func (store *SQLStore) FilterPosts(ctx context.Context, filter FilterParams) ([]FilteredPost, error) {
-	query := buildFilterQuery(filter)
-	rows, err := store.db.QueryContext(ctx, query)
+	query, args := buildFilterQuery(filter)
+	rows, err := store.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -384,6 +1116,8 @@ func (store *SQLStore) FilterPosts(ctx context.Context, filter FilterParams) ([]
 			&post.CreatedAt,
 			&post.UpdatedAt,
 			&post.Likes,
+			&post.PublishAt,
+			&post.Slug,
 			&post.Username,
 			&post.CommentCount,
 			&post.Tags,
@@ -397,11 +1131,118 @@ func (store *SQLStore) FilterPosts(ctx context.Context, filter FilterParams) ([]
 	return posts, rows.Err()
 }
 
-func buildFilterQuery(filter FilterParams) string {
-	baseQuery := `
+// CountFilteredPosts counts every post the filter matches, regardless of its
+// cursor and limit
+func (store *SQLStore) CountFilteredPosts(ctx context.Context, filter FilterParams) (int64, error) {
+	query, args := buildFilterCountQuery(filter)
+	var count int64
+	err := store.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	return count, err
+}
+
+// filterQueryBuilder collects WHERE conditions together with their arguments.
+// Values only ever reach the database as numbered placeholders.
+type filterQueryBuilder struct {
+	conditions []string
+	args       []interface{}
+}
+
+// arg registers a value and returns the placeholder that refers to it
+func (b *filterQueryBuilder) arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *filterQueryBuilder) where(condition string) {
+	b.conditions = append(b.conditions, condition)
+}
+
+// filterSortFields maps the accepted sort keys to columns. Anything else falls
+// back to created_at, so SortBy is never written into the query as is.
+var filterSortFields = map[string]string{
+	"created_at": "p.created_at",
+	"likes":      "p.likes",
+	"title":      "p.title",
+}
+
+// filterConditions returns the conditions selecting the posts the filter matches
+func filterConditions(filter FilterParams) *filterQueryBuilder {
+	b := &filterQueryBuilder{}
+
+	if filter.UserID != nil {
+		b.where("p.user_id = " + b.arg(*filter.UserID))
+	}
+	if filter.Status != nil && *filter.Status != "" {
+		b.where("p.status = " + b.arg(*filter.Status))
+	}
+	if !filter.AllStatuses {
+		if filter.ViewerID != nil {
+			b.where("(p.status = 'published' OR p.user_id = " + b.arg(*filter.ViewerID) + ")")
+		} else {
+			b.where("p.status = 'published'")
+		}
+	}
+	if filter.Type != nil && *filter.Type != "" {
+		b.where("p.type = " + b.arg(*filter.Type))
+	}
+	if filter.Username != nil && *filter.Username != "" {
+		b.where("u.username = " + b.arg(*filter.Username))
+	}
+	if filter.CreatedAfter != nil {
+		b.where("p.created_at >= " + b.arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		b.where("p.created_at < " + b.arg(*filter.CreatedBefore))
+	}
+	if filter.MinLikes != nil {
+		b.where("p.likes >= " + b.arg(*filter.MinLikes))
+	}
+
+	// Tags are matched in a subquery so the aggregated tag list stays complete
+	if tags := uniqueTags(filter.Tags); len(tags) > 0 {
+		tagQuery := `p.id IN (
+            SELECT pt.post_id
+            FROM post_tags pt
+            JOIN tags t ON pt.tag_id = t.id
+            WHERE t.name = ANY(` + b.arg(pq.Array(tags)) + `::text[])`
+		if filter.TagMatch == "all" {
+			tagQuery += `
+            GROUP BY pt.post_id
+            HAVING COUNT(DISTINCT t.name) = ` + b.arg(len(tags))
+		}
+		b.where(tagQuery + ")")
+	}
+
+	return b
+}
+
+// buildFilterQuery returns the filter query and the arguments for its placeholders
+func buildFilterQuery(filter FilterParams) (string, []interface{}) {
+	b := filterConditions(filter)
+
+	// Only whitelisted identifiers are spliced in
+	sortField, ok := filterSortFields[filter.SortBy]
+	if !ok {
+		sortField = "p.created_at"
+	}
+	sortOrder := "DESC"
+	if strings.EqualFold(filter.SortOrder, "asc") {
+		sortOrder = "ASC"
+	}
+
+	// Seek past the last post of the previous page, ties are ordered by id
+	if filter.After != nil {
+		op := "<"
+		if sortOrder == "ASC" {
+			op = ">"
+		}
+		b.where(fmt.Sprintf("(%s, p.id) %s (%s, %s)", sortField, op, b.arg(filterCursorValue(sortField, filter.After)), b.arg(filter.After.ID)))
+	}
+
+	query := `
         SELECT 
             p.id, p.user_id, p.title, p.content, p.type, p.status, 
-            p.created_at, p.updated_at, p.likes,
+            p.created_at, p.updated_at, p.likes, p.publish_at, p.slug,
             u.username,
             COUNT(DISTINCT c.id) as comment_count,
             COALESCE(array_agg(DISTINCT t.name) FILTER (WHERE t.name IS NOT NULL), ARRAY[]::text[]) as tags
@@ -412,30 +1253,314 @@ func buildFilterQuery(filter FilterParams) string {
         LEFT JOIN tags t ON pt.tag_id = t.id
         WHERE 1=1
     `
-
-	// Add filters
-	if filter.UserID != nil {
-		baseQuery += fmt.Sprintf(" AND p.user_id = %d", *filter.UserID)
-	}
-	if filter.Status != nil && *filter.Status != "" {
-		baseQuery += fmt.Sprintf(" AND p.status = '%s'", *filter.Status)
+	for _, condition := range b.conditions {
+		query += " AND " + condition
 	}
 
 	// Add group by
-	baseQuery += " GROUP BY p.id, u.id"
+	query += " GROUP BY p.id, u.id"
 
 	// Add sorting
-	sortField := "p.created_at"
-	switch filter.SortBy {
-	case "likes":
-		sortField = "p.likes"
-	case "title":
-		sortField = "p.title"
-	}
-	baseQuery += fmt.Sprintf(" ORDER BY %s %s", sortField, strings.ToUpper(filter.SortOrder))
+	query += fmt.Sprintf(" ORDER BY %s %s, p.id %s", sortField, sortOrder, sortOrder)
 
 	// Add pagination
-	baseQuery += fmt.Sprintf(" LIMIT %d OFFSET %d", filter.Limit, filter.Offset)
+	query += " LIMIT " + b.arg(filter.Limit)
+
+	return query, b.args
+}
+
+// filterCursorValue is the value of the cursor post for the sort column
+func filterCursorValue(sortField string, cursor *FilterCursor) interface{} {
+	switch sortField {
+	case "p.likes":
+		return cursor.Likes
+	case "p.title":
+		return cursor.Title
+	default:
+		return cursor.CreatedAt
+	}
+}
+
+// buildFilterCountQuery returns the query counting the posts the filter matches
+func buildFilterCountQuery(filter FilterParams) (string, []interface{}) {
+	b := filterConditions(filter)
+
+	query := `
+        SELECT COUNT(*)
+        FROM posts p
+        LEFT JOIN users u ON p.user_id = u.id
+        WHERE 1=1
+    `
+	for _, condition := range b.conditions {
+		query += " AND " + condition
+	}
+
+	return query, b.args
+}
+
+// uniqueTags drops blank and repeated tag names so "all" matching can compare counts
+func uniqueTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+type ListCommentThreadsParams struct {
+	PostID   int32  `json:"post_id"`
+	ParentID *int32 `json:"parent_id"` // nil lists the top-level comments of the post
+	MaxDepth int32  `json:"max_depth"` // deepest reply level returned, 0 returns only the thread roots
+	Limit    int32  `json:"limit"`     // applies to thread roots, not to their replies
+	Offset   int32  `json:"offset"`
+}
+
+type CommentThreadRow struct {
+	ID         int32          `json:"id"`
+	PostID     sql.NullInt32  `json:"post_id"`
+	UserID     sql.NullInt32  `json:"user_id"`
+	ParentID   sql.NullInt32  `json:"parent_id"`
+	Content    string         `json:"content"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
+	UpdatedAt  sql.NullTime   `json:"updated_at"`
+	Username   sql.NullString `json:"username"`
+	FirstName  sql.NullString `json:"first_name"`
+	LastName   sql.NullString `json:"last_name"`
+	Depth      int32          `json:"depth"`
+	ReplyCount int64          `json:"reply_count"`
+}
+
+// Thread roots are ordered newest first and replies oldest first. Every row
+// carries its sort path, so ordering by it yields each parent directly
+// followed by its replies.
+const listCommentThreads = `
+    WITH RECURSIVE roots AS (
+        SELECT c.id, ROW_NUMBER() OVER (ORDER BY c.created_at DESC, c.id DESC) AS ord
+        FROM comments c
+        WHERE c.post_id = $1 AND c.parent_id IS NOT DISTINCT FROM $2
+        ORDER BY c.created_at DESC, c.id DESC
+        LIMIT $3 OFFSET $4
+    ), thread AS (
+        SELECT r.id, 0 AS depth, ARRAY[r.ord] AS path
+        FROM roots r
+        UNION ALL
+        SELECT c.id, t.depth + 1, t.path || c.id::bigint
+        FROM comments c
+        JOIN thread t ON c.parent_id = t.id
+        WHERE t.depth < $5
+    )
+    SELECT
+        c.id, c.post_id, c.user_id, c.parent_id, c.content, c.created_at, c.updated_at,
+        u.username, u.first_name, u.last_name,
+        t.depth,
+        (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count
+    FROM thread t
+    JOIN comments c ON c.id = t.id
+    LEFT JOIN users u ON c.user_id = u.id
+    ORDER BY t.path
+`
+
+// ListCommentThreads returns a page of comment threads as a parent-ordered flat
+// list, each row annotated with its depth below the listed roots.
+func (store *SQLStore) ListCommentThreads(ctx context.Context, arg ListCommentThreadsParams) ([]CommentThreadRow, error) {
+	rows, err := store.db.QueryContext(ctx, listCommentThreads,
+		arg.PostID,
+		arg.ParentID,
+		arg.Limit,
+		arg.Offset,
+		arg.MaxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []CommentThreadRow{}
+	for rows.Next() {
+		var c CommentThreadRow
+		err := rows.Scan(
+			&c.ID,
+			&c.PostID,
+			&c.UserID,
+			&c.ParentID,
+			&c.Content,
+			&c.CreatedAt,
+			&c.UpdatedAt,
+			&c.Username,
+			&c.FirstName,
+			&c.LastName,
+			&c.Depth,
+			&c.ReplyCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
 
-	return baseQuery
+type SearchPostsParams struct {
+	TSQuery string        `json:"ts_query"` // to_tsquery syntax, see BuildTSQuery
+	After   *SearchCursor `json:"after"`    // list the matches ranked after this one
+	Limit   int32         `json:"limit"`
+}
+
+// SearchCursor is the last match of the previous page of search results
+type SearchCursor struct {
+	ID        int32
+	Rank      float32
+	CreatedAt time.Time
+}
+
+type SearchPostRow struct {
+	ID             int32          `json:"id"`
+	UserID         sql.NullInt32  `json:"user_id"`
+	Title          string         `json:"title"`
+	Type           string         `json:"type"`
+	Status         sql.NullString `json:"status"`
+	CreatedAt      sql.NullTime   `json:"created_at"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+	Likes          int32          `json:"likes"`
+	Slug           string         `json:"slug"`
+	Username       sql.NullString `json:"username"`
+	Rank           float32        `json:"rank"`
+	TitleHighlight string         `json:"title_highlight"`
+	Snippet        string         `json:"snippet"`
+}
+
+// Matches are ranked in the inner query so ts_headline, which re-parses the
+// whole document, only runs for the rows of the requested page. Title and
+// content are HTML-escaped before ts_headline marks the matches, so the
+// <mark> tags it adds are the only markup in the highlights clients render.
+const searchPosts = `
+    WITH query AS (
+        SELECT to_tsquery('english', $1) AS q
+    ),
+    matches AS (
+        SELECT p.id, ts_rank(p.search_vector, query.q) AS rank
+        FROM posts p, query
+        WHERE p.search_vector @@ query.q AND p.status = 'published'
+          AND ($3::int IS NULL OR (ts_rank(p.search_vector, query.q), p.created_at, p.id) < ($4::real, $5::timestamp, $3::int))
+        ORDER BY rank DESC, p.created_at DESC, p.id DESC
+        LIMIT $2
+    )
+    SELECT
+        p.id, p.user_id, p.title, p.type, p.status,
+        p.created_at, p.updated_at, p.likes, p.slug,
+        u.username,
+        m.rank,
+        ts_headline('english',
+            replace(replace(replace(replace(p.title, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+            query.q, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>') AS title_highlight,
+        ts_headline('english',
+            replace(replace(replace(replace(p.content, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'),
+            query.q, 'MaxFragments=2, MaxWords=30, MinWords=10, StartSel=<mark>, StopSel=</mark>') AS snippet
+    FROM matches m
+    JOIN posts p ON p.id = m.id
+    LEFT JOIN users u ON p.user_id = u.id
+    CROSS JOIN query
+    ORDER BY m.rank DESC, p.created_at DESC, p.id DESC
+`
+
+// SearchPosts runs a full-text search over published posts, best matches first
+func (store *SQLStore) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostRow, error) {
+	var afterID sql.NullInt32
+	var afterRank sql.NullFloat64
+	var afterCreatedAt sql.NullTime
+	if arg.After != nil {
+		afterID = sql.NullInt32{Int32: arg.After.ID, Valid: true}
+		afterRank = sql.NullFloat64{Float64: float64(arg.After.Rank), Valid: true}
+		afterCreatedAt = sql.NullTime{Time: arg.After.CreatedAt, Valid: true}
+	}
+
+	rows, err := store.db.QueryContext(ctx, searchPosts, arg.TSQuery, arg.Limit, afterID, afterRank, afterCreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []SearchPostRow{}
+	for rows.Next() {
+		var post SearchPostRow
+		err := rows.Scan(
+			&post.ID,
+			&post.UserID,
+			&post.Title,
+			&post.Type,
+			&post.Status,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+			&post.Likes,
+			&post.Slug,
+			&post.Username,
+			&post.Rank,
+			&post.TitleHighlight,
+			&post.Snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+const countSearchPosts = `
+    SELECT COUNT(*) FROM posts p
+    WHERE p.search_vector @@ to_tsquery('english', $1) AND p.status = 'published'
+`
+
+// CountSearchPosts counts every published post a search matches
+func (store *SQLStore) CountSearchPosts(ctx context.Context, tsQuery string) (int64, error) {
+	var count int64
+	err := store.db.QueryRowContext(ctx, countSearchPosts, tsQuery).Scan(&count)
+	return count, err
+}
+
+// BuildTSQuery turns a search box query into to_tsquery syntax. Words are
+// ANDed together, "quoted phrases" have to appear in order and a trailing *
+// turns a word into a prefix match. Other punctuation is dropped, so the
+// result is always valid tsquery input. It returns "" when nothing searchable
+// is left.
+func BuildTSQuery(input string) string {
+	var terms []string
+
+	// Every odd segment sits between a pair of double quotes
+	for i, segment := range strings.Split(input, `"`) {
+		if i%2 == 1 {
+			if words := searchWords(segment); len(words) > 0 {
+				terms = append(terms, "("+strings.Join(words, " <-> ")+")")
+			}
+			continue
+		}
+
+		for _, field := range strings.Fields(segment) {
+			words := searchWords(field)
+			if len(words) == 0 {
+				continue
+			}
+			if strings.HasSuffix(field, "*") {
+				words[len(words)-1] += ":*"
+			}
+			terms = append(terms, words...)
+		}
+	}
+
+	return strings.Join(terms, " & ")
+}
+
+// searchWords splits text into runs of letters and digits
+func searchWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
 }
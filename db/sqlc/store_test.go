@@ -5,7 +5,10 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/haotianxu2021/newPortfolio/util"
 	"github.com/stretchr/testify/require"
@@ -74,6 +77,313 @@ func TestCreatePostTx(t *testing.T) {
 	require.Len(t, postTags, 2)
 }
 
+func TestUploadPostImageTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	arg := UploadPostImageTxParams{
+		PostID:      post.ID,
+		UserID:      user.ID,
+		FilePath:    "images/" + util.RandomString(8) + ".png",
+		AltText:     "alt",
+		Order:       1,
+		SizeBytes:   2048,
+		MimeType:    "image/png",
+		Checksum:    util.RandomString(64),
+		Width:       1000,
+		Height:      500,
+		SanitizedAt: time.Now(),
+		Variants: []UploadImageVariant{
+			{Name: "thumbnail", FilePath: "images/t.png", MimeType: "image/png", Width: 320, Height: 160, SizeBytes: 100},
+			{Name: "medium", FilePath: "images/m.png", MimeType: "image/png", Width: 800, Height: 400, SizeBytes: 500},
+		},
+	}
+
+	result, err := store.UploadPostImageTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.FilePath, result.Image.FilePath)
+	require.Equal(t, arg.Checksum, result.Image.Checksum)
+	require.Equal(t, arg.Width, result.Image.Width)
+	require.True(t, result.Image.SanitizedAt.Valid)
+	require.Len(t, result.Variants, 2)
+
+	images, err := store.ListPostImages(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, result.Image.ID, images[0].ID)
+
+	variants, err := store.ListImageVariantsByPost(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+	require.Equal(t, "thumbnail", variants[0].Name)
+	require.Equal(t, "medium", variants[1].Name)
+
+	// Variants go away with their image
+	require.NoError(t, store.DeleteImage(context.Background(), result.Image.ID))
+	variants, err = store.ListImageVariants(context.Background(), result.Image.ID)
+	require.NoError(t, err)
+	require.Empty(t, variants)
+}
+
+func TestUploadPostImageTxReusesChecksum(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post1 := createRandomPost(t, user)
+	post2 := createRandomPost(t, user)
+
+	arg := UploadPostImageTxParams{
+		PostID:    post1.ID,
+		UserID:    user.ID,
+		FilePath:  "images/" + util.RandomString(8) + ".png",
+		SizeBytes: 1000,
+		Checksum:  util.RandomString(64),
+		Variants: []UploadImageVariant{
+			{Name: "thumbnail", FilePath: "images/t.png", MimeType: "image/png", Width: 320, Height: 160, SizeBytes: 100},
+		},
+	}
+	first, err := store.UploadPostImageTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.False(t, first.Reused)
+
+	// The same content in another post links the existing image, giving it
+	// the alt text it lacked
+	arg.PostID = post2.ID
+	arg.FilePath = "images/" + util.RandomString(8) + ".png"
+	arg.AltText = "a red square"
+	second, err := store.UploadPostImageTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, second.Reused)
+	require.Equal(t, first.Image.ID, second.Image.ID)
+	require.Len(t, second.Variants, 1)
+	require.Equal(t, "a red square", second.Image.AltText.String)
+
+	images, err := store.ListPostImages(context.Background(), post2.ID)
+	require.NoError(t, err)
+	require.Len(t, images, 1)
+	require.Equal(t, first.Image.ID, images[0].ID)
+
+	// Linking only, and twice into the same post, is fine. Alt text other
+	// posts show already is kept.
+	again, err := store.UploadPostImageTx(context.Background(), UploadPostImageTxParams{
+		PostID:   post2.ID,
+		UserID:   user.ID,
+		AltText:  "something else",
+		Checksum: arg.Checksum,
+	})
+	require.NoError(t, err)
+	require.True(t, again.Reused)
+	require.Equal(t, "a red square", again.Image.AltText.String)
+
+	// Linking only fails when there is nothing to link
+	_, err = store.UploadPostImageTx(context.Background(), UploadPostImageTxParams{
+		PostID:   post2.ID,
+		UserID:   user.ID,
+		Checksum: util.RandomString(64),
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Usage counts the shared image once
+	usage, err := store.GetUserStorageUsage(context.Background(), sql.NullInt32{Int32: user.ID, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), usage.ImageCount)
+	require.Equal(t, int64(1000), usage.ImageBytes)
+	require.Equal(t, int64(100), usage.VariantBytes)
+}
+
+func TestUploadPostImageTxConcurrentDuplicates(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	checksum := util.RandomString(64)
+
+	// Identical uploads that all missed each other in the handler's lookup
+	n := 5
+	errs := make(chan error)
+	results := make(chan UploadPostImageTxResult)
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.UploadPostImageTx(context.Background(), UploadPostImageTxParams{
+				PostID:    post.ID,
+				UserID:    user.ID,
+				FilePath:  "images/" + util.RandomString(8) + ".png",
+				SizeBytes: 1000,
+				Checksum:  checksum,
+			})
+			errs <- err
+			results <- result
+		}()
+	}
+
+	// Exactly one of them created the image, the others linked it
+	imageIDs := make(map[int32]bool)
+	created := 0
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		result := <-results
+		imageIDs[result.Image.ID] = true
+		if !result.Reused {
+			created++
+		}
+	}
+	require.Len(t, imageIDs, 1)
+	require.Equal(t, 1, created)
+
+	usage, err := store.GetUserStorageUsage(context.Background(), sql.NullInt32{Int32: user.ID, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), usage.ImageCount)
+}
+
+func TestUploadPostImageTxQuota(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+	quota := util.StorageQuota{MaxBytes: 2500, MaxImages: 5}
+
+	// Concurrent uploads that each fit the quota on their own
+	n := 3
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.UploadPostImageTx(context.Background(), UploadPostImageTxParams{
+				PostID:    post.ID,
+				UserID:    user.ID,
+				FilePath:  "images/" + util.RandomString(8) + ".png",
+				SizeBytes: 1000,
+				Checksum:  util.RandomString(64),
+				Quota:     quota,
+			})
+			errs <- err
+		}()
+	}
+
+	// Only as many as fit together are recorded
+	exceeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		var quotaErr *QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			require.Equal(t, int64(2000), quotaErr.Usage.ImageBytes)
+			exceeded++
+			continue
+		}
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, exceeded)
+
+	usage, err := store.GetUserStorageUsage(context.Background(), sql.NullInt32{Int32: user.ID, Valid: true})
+	require.NoError(t, err)
+	require.Equal(t, int32(2), usage.ImageCount)
+}
+
+func TestReorderPostImagesTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	// Uploads without an order are appended
+	ids := make([]int32, 3)
+	for i := range ids {
+		result, err := store.UploadPostImageTx(context.Background(), UploadPostImageTxParams{
+			PostID:   post.ID,
+			UserID:   user.ID,
+			FilePath: "images/" + util.RandomString(8) + ".png",
+		})
+		require.NoError(t, err)
+		ids[i] = result.Image.ID
+	}
+
+	images, err := store.ListPostImages(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Equal(t, ids, []int32{images[0].ID, images[1].ID, images[2].ID})
+
+	newOrder := []int32{ids[2], ids[0], ids[1]}
+	reordered, err := store.ReorderPostImagesTx(context.Background(), ReorderPostImagesTxParams{
+		PostID:   post.ID,
+		ImageIDs: newOrder,
+	})
+	require.NoError(t, err)
+	require.Len(t, reordered, 3)
+
+	images, err = store.ListPostImages(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Equal(t, newOrder, []int32{images[0].ID, images[1].ID, images[2].ID})
+
+	// Leaving an image out or listing one twice changes nothing
+	for _, imageIDs := range [][]int32{{ids[0], ids[1]}, {ids[0], ids[0], ids[1]}} {
+		_, err = store.ReorderPostImagesTx(context.Background(), ReorderPostImagesTxParams{
+			PostID:   post.ID,
+			ImageIDs: imageIDs,
+		})
+		require.ErrorIs(t, err, ErrImageOrderMismatch)
+	}
+
+	images, err = store.ListPostImages(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Equal(t, newOrder, []int32{images[0].ID, images[1].ID, images[2].ID})
+
+	// Detaching keeps the image itself
+	removed, err := store.RemovePostImage(context.Background(), RemovePostImageParams{
+		PostID:  post.ID,
+		ImageID: ids[0],
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), removed)
+
+	_, err = store.GetImage(context.Background(), ids[0])
+	require.NoError(t, err)
+}
+
+func TestListOrphanedImages(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	result, err := store.UploadPostImageTx(context.Background(), UploadPostImageTxParams{
+		PostID:   post.ID,
+		UserID:   user.ID,
+		FilePath: "images/" + util.RandomString(8) + ".png",
+	})
+	require.NoError(t, err)
+	image := result.Image
+
+	// Only look at this test's image
+	orphans := func(cutoff time.Time) []Image {
+		images, err := store.ListOrphanedImages(context.Background(), ListOrphanedImagesParams{
+			Cutoff:    cutoff,
+			AfterID:   image.ID - 1,
+			BatchSize: 1,
+		})
+		require.NoError(t, err)
+		if len(images) == 1 && images[0].ID != image.ID {
+			return nil
+		}
+		return images
+	}
+
+	// Linked images are never orphaned
+	require.Empty(t, orphans(time.Now().UTC().Add(time.Hour)))
+	deleted, err := store.DeleteOrphanedImageTx(context.Background(), image.ID)
+	require.NoError(t, err)
+	require.Zero(t, deleted)
+
+	// Deleting the post unlinks the image and starts its grace period
+	require.NoError(t, store.DeletePost(context.Background(), post.ID))
+	unlinked, err := store.GetImage(context.Background(), image.ID)
+	require.NoError(t, err)
+	require.True(t, unlinked.UnlinkedAt.Valid)
+
+	require.Empty(t, orphans(time.Now().UTC().Add(-time.Hour)))
+	require.Len(t, orphans(time.Now().UTC().Add(time.Hour)), 1)
+
+	deleted, err = store.DeleteOrphanedImageTx(context.Background(), image.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	_, err = store.GetImage(context.Background(), image.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
 func TestUpdatePostTx(t *testing.T) {
 	store := NewStore(testDB)
 
@@ -117,6 +427,292 @@ func TestUpdatePostTx(t *testing.T) {
 	require.True(t, tagIDs[tag2.ID])
 	require.True(t, tagIDs[tag3.ID])
 	require.False(t, tagIDs[tag1.ID])
+
+	// The version before the update is kept as the first revision
+	require.NotNil(t, result.Revision)
+	require.Equal(t, int32(1), result.Revision.Revision)
+	require.Equal(t, "initial title", result.Revision.Title)
+	require.Equal(t, "initial content", result.Revision.Content)
+}
+
+func TestEditPostTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	// Changing the text saves the previous version
+	result, err := store.EditPostTx(context.Background(), EditPostTxParams{
+		UpdatePostParams: UpdatePostParams{
+			ID:      post.ID,
+			Content: "edited content",
+		},
+		EditedBy: user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "edited content", result.Post.Content)
+	require.Equal(t, post.Title, result.Post.Title)
+	require.Equal(t, post.Type, result.Post.Type)
+	require.NotNil(t, result.Revision)
+	require.Equal(t, int32(1), result.Revision.Revision)
+	require.Equal(t, post.Content, result.Revision.Content)
+	require.Equal(t, user.ID, result.Revision.EditedBy.Int32)
+
+	// Changing only the status saves no revision
+	result, err = store.EditPostTx(context.Background(), EditPostTxParams{
+		UpdatePostParams: UpdatePostParams{
+			ID:     post.ID,
+			Status: sql.NullString{String: util.PostStatusUnlisted, Valid: true},
+		},
+		EditedBy: user.ID,
+	})
+	require.NoError(t, err)
+	require.Nil(t, result.Revision)
+	require.Equal(t, util.PostStatusUnlisted, result.Post.Status.String)
+
+	revisions, err := store.ListPostRevisions(context.Background(), ListPostRevisionsParams{
+		PostID: post.ID,
+		Limit:  10,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, user.Username, revisions[0].EditedByUsername.String)
+}
+
+func TestRestorePostRevisionTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	post := createRandomPost(t, user)
+
+	_, err := store.EditPostTx(context.Background(), EditPostTxParams{
+		UpdatePostParams: UpdatePostParams{
+			ID:      post.ID,
+			Title:   "second title",
+			Content: "<p>second content</p>",
+			Format:  "html",
+		},
+		EditedBy: user.ID,
+	})
+	require.NoError(t, err)
+
+	result, err := store.RestorePostRevisionTx(context.Background(), RestorePostRevisionTxParams{
+		PostID:   post.ID,
+		Revision: 1,
+		EditedBy: user.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, post.Title, result.Post.Title)
+	require.Equal(t, post.Content, result.Post.Content)
+	require.Equal(t, post.Format, result.Post.Format)
+
+	// Restoring keeps the replaced version, so it can be undone
+	require.NotNil(t, result.Revision)
+	require.Equal(t, int32(2), result.Revision.Revision)
+	require.Equal(t, "second title", result.Revision.Title)
+	require.Equal(t, "html", result.Revision.Format)
+
+	_, err = store.RestorePostRevisionTx(context.Background(), RestorePostRevisionTxParams{
+		PostID:   post.ID,
+		Revision: 9,
+		EditedBy: user.ID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestEditPostTxKeepsRendering(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	post, err := store.CreatePostWithSlugTx(context.Background(), CreatePostParams{
+		UserID:      sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:       "rendered " + util.RandomString(8),
+		Content:     "hello",
+		Type:        "blog",
+		Status:      sql.NullString{String: util.PostStatusPublished, Valid: true},
+		Format:      "markdown",
+		ContentHtml: sql.NullString{String: "<p>hello</p>\n", Valid: true},
+		Toc:         json.RawMessage(`[]`),
+	})
+	require.NoError(t, err)
+
+	// Changing the status keeps the rendering
+	result, err := store.EditPostTx(context.Background(), EditPostTxParams{
+		UpdatePostParams: UpdatePostParams{
+			ID:     post.ID,
+			Status: sql.NullString{String: util.PostStatusUnlisted, Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, post.ContentHtml, result.Post.ContentHtml)
+	require.JSONEq(t, `[]`, string(result.Post.Toc))
+
+	// New content without a rendering leaves the post to be rendered again
+	result, err = store.EditPostTx(context.Background(), EditPostTxParams{
+		UpdatePostParams: UpdatePostParams{ID: post.ID, Content: "changed"},
+	})
+	require.NoError(t, err)
+	require.False(t, result.Post.ContentHtml.Valid)
+
+	// A rendering of content that changed since is not saved
+	err = store.UpdatePostRendering(context.Background(), UpdatePostRenderingParams{
+		ID:          post.ID,
+		ContentHtml: sql.NullString{String: "<p>hello</p>\n", Valid: true},
+		Toc:         json.RawMessage(`[]`),
+		Content:     "hello",
+		Format:      "markdown",
+	})
+	require.NoError(t, err)
+	stored, err := store.GetPostForUpdate(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.False(t, stored.ContentHtml.Valid)
+}
+
+func TestCreatePostWithSlugTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	title := "Crème Brûlée " + util.RandomString(8)
+	arg := CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   title,
+		Content: "same title twice",
+		Type:    "blog",
+		Format:  "markdown",
+		Status:  sql.NullString{String: util.PostStatusPublished, Valid: true},
+	}
+
+	first, err := store.CreatePostWithSlugTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, util.Slugify(title), first.Slug)
+
+	// A post with the same title gets a numbered slug
+	second, err := store.CreatePostWithSlugTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, first.Slug+"-2", second.Slug)
+
+	id, err := store.GetPostIDBySlug(context.Background(), second.Slug)
+	require.NoError(t, err)
+	require.Equal(t, second.ID, id)
+}
+
+func TestBackfillPostSlugs(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	title := "Привет " + util.RandomString(8)
+
+	// What migrating a post with a Cyrillic title gave it
+	oldSlug := "post-" + util.RandomString(8)
+	post, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   title,
+		Content: "migrated",
+		Type:    "blog",
+		Format:  "markdown",
+		Slug:    oldSlug,
+		Status:  sql.NullString{String: util.PostStatusPublished, Valid: true},
+	})
+	require.NoError(t, err)
+
+	renamed, err := store.BackfillPostSlugs(context.Background())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, renamed, 1)
+
+	backfilled, err := store.GetPostForUpdate(context.Background(), post.ID)
+	require.NoError(t, err)
+	require.Equal(t, util.Slugify(title), backfilled.Slug)
+
+	// Links to the migrated slug keep working
+	redirect, err := store.GetPostSlugRedirect(context.Background(), oldSlug)
+	require.NoError(t, err)
+	require.Equal(t, post.ID, redirect.PostID)
+
+	// Slugs made from the title are left alone
+	renamed, err = store.BackfillPostSlugs(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, renamed)
+}
+
+func TestCreatePostWithSlugTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	title := "Concurrent " + util.RandomString(8)
+
+	// Posts with one title, created before any of them committed its slug
+	n := 5
+	errs := make(chan error)
+	posts := make(chan Post)
+	for i := 0; i < n; i++ {
+		go func() {
+			post, err := store.CreatePostWithSlugTx(context.Background(), CreatePostParams{
+				UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+				Title:   title,
+				Content: "same title at once",
+				Type:    "blog",
+				Format:  "markdown",
+				Status:  sql.NullString{String: util.PostStatusPublished, Valid: true},
+			})
+			errs <- err
+			posts <- post
+		}()
+	}
+
+	// Every one of them got its own slug
+	slugs := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		slugs[(<-posts).Slug] = true
+	}
+	require.Len(t, slugs, n)
+	require.True(t, slugs[util.Slugify(title)])
+}
+
+func TestEditPostTxRenamesSlug(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	post, err := store.CreatePostWithSlugTx(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   "first title " + util.RandomString(8),
+		Content: "content",
+		Type:    "blog",
+		Format:  "markdown",
+		Status:  sql.NullString{String: util.PostStatusPublished, Valid: true},
+	})
+	require.NoError(t, err)
+
+	rename := func(title string) Post {
+		result, err := store.EditPostTx(context.Background(), EditPostTxParams{
+			UpdatePostParams: UpdatePostParams{ID: post.ID, Title: title},
+			EditedBy:         user.ID,
+		})
+		require.NoError(t, err)
+		return result.Post
+	}
+
+	// The old slug redirects to the renamed post
+	renamed := rename("second title " + util.RandomString(8))
+	require.NotEqual(t, post.Slug, renamed.Slug)
+	redirect, err := store.GetPostSlugRedirect(context.Background(), post.Slug)
+	require.NoError(t, err)
+	require.Equal(t, post.ID, redirect.PostID)
+
+	// Nobody else may take the old slug
+	taken, err := store.IsSlugTaken(context.Background(), IsSlugTakenParams{Slug: post.Slug})
+	require.NoError(t, err)
+	require.True(t, taken)
+
+	// Going back to the first title takes back the first slug
+	restored := rename(post.Title)
+	require.Equal(t, post.Slug, restored.Slug)
+	_, err = store.GetPostSlugRedirect(context.Background(), post.Slug)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	redirect, err = store.GetPostSlugRedirect(context.Background(), renamed.Slug)
+	require.NoError(t, err)
+	require.Equal(t, post.ID, redirect.PostID)
 }
 
 func TestAddPostTagTx(t *testing.T) {
@@ -222,3 +818,194 @@ func TestUpdatePostTagsTx(t *testing.T) {
 	require.True(t, tagIDs[tag2.ID])
 	require.True(t, tagIDs[tag3.ID])
 }
+
+func TestLikePostTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	author := createRandomUser(t)
+	reader := createRandomUser(t)
+	post := createRandomPost(t, author)
+
+	arg := PostLikeTxParams{
+		UserID: reader.ID,
+		PostID: post.ID,
+	}
+
+	// Liking twice only counts once
+	for i := 0; i < 2; i++ {
+		result, err := store.LikePostTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.True(t, result.LikedByMe)
+		require.Equal(t, post.Likes+1, result.Post.Likes)
+	}
+
+	likers, err := store.ListPostLikers(context.Background(), ListPostLikersParams{
+		PostID: post.ID,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, likers, 1)
+	require.Equal(t, reader.ID, likers[0].ID)
+
+	// Unliking twice only removes the one like
+	for i := 0; i < 2; i++ {
+		result, err := store.UnlikePostTx(context.Background(), arg)
+		require.NoError(t, err)
+		require.False(t, result.LikedByMe)
+		require.Equal(t, post.Likes, result.Post.Likes)
+	}
+
+	// Liking a missing post fails without leaving a row behind
+	_, err = store.LikePostTx(context.Background(), PostLikeTxParams{UserID: reader.ID, PostID: -1})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestBuildFilterQueryUsesPlaceholders(t *testing.T) {
+	status := "published' OR '1'='1"
+	author := "bob'; DROP TABLE posts; --"
+
+	query, args := buildFilterQuery(FilterParams{
+		Status:    &status,
+		Username:  &author,
+		Tags:      []string{"go", "go", " "},
+		TagMatch:  "all",
+		SortBy:    "likes; DROP TABLE posts",
+		SortOrder: "asc; --",
+		After:     &FilterCursor{ID: 7, CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Limit:     5,
+	})
+
+	require.NotContains(t, query, status)
+	require.NotContains(t, query, author)
+	require.NotContains(t, query, "DROP")
+	require.Contains(t, query, "(p.created_at, p.id) < ($5, $6)")
+	require.Contains(t, query, "ORDER BY p.created_at DESC")
+	require.Contains(t, query, "LIMIT $7")
+
+	require.Len(t, args, 7)
+	require.Equal(t, status, args[0])
+	require.Equal(t, author, args[1])
+	require.Equal(t, 1, args[3]) // duplicate and blank tags are dropped
+}
+
+func TestFilterPosts(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	tagA := createRandomTag(t)
+	tagB := createRandomTag(t)
+
+	both := createRandomPost(t, user)
+	onlyA := createRandomPost(t, user)
+	for _, pt := range []PostTagTxParams{
+		{PostID: both.ID, TagID: tagA.ID},
+		{PostID: both.ID, TagID: tagB.ID},
+		{PostID: onlyA.ID, TagID: tagA.ID},
+	} {
+		_, err := store.AddPostTagTx(context.Background(), pt)
+		require.NoError(t, err)
+	}
+	_, err := testQueries.IncrementPostLikes(context.Background(), both.ID)
+	require.NoError(t, err)
+
+	filterIDs := func(filter FilterParams) []int32 {
+		filter.Username = &user.Username
+		filter.Limit = 10
+		posts, err := store.FilterPosts(context.Background(), filter)
+		require.NoError(t, err)
+
+		ids := make([]int32, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+		return ids
+	}
+
+	tags := []string{tagA.Name, tagB.Name}
+	require.ElementsMatch(t, []int32{both.ID, onlyA.ID}, filterIDs(FilterParams{Tags: tags, TagMatch: "any"}))
+	require.Equal(t, []int32{both.ID}, filterIDs(FilterParams{Tags: tags, TagMatch: "all"}))
+
+	minLikes := int32(1)
+	require.Equal(t, []int32{both.ID}, filterIDs(FilterParams{MinLikes: &minLikes}))
+
+	postType := "project"
+	require.Empty(t, filterIDs(FilterParams{Type: &postType}))
+
+	future := time.Now().Add(time.Hour)
+	require.Empty(t, filterIDs(FilterParams{CreatedAfter: &future}))
+	require.Len(t, filterIDs(FilterParams{CreatedBefore: &future}), 2)
+
+	// Hostile input is only ever compared as a value
+	status := "published' OR '1'='1"
+	require.Empty(t, filterIDs(FilterParams{Status: &status}))
+
+	// The cursor seeks past the last post of the previous page
+	byLikes := FilterParams{SortBy: "likes", SortOrder: "desc"}
+	require.Equal(t, []int32{both.ID, onlyA.ID}, filterIDs(byLikes))
+	byLikes.After = &FilterCursor{ID: both.ID, Likes: 1}
+	require.Equal(t, []int32{onlyA.ID}, filterIDs(byLikes))
+
+	count, err := store.CountFilteredPosts(context.Background(), FilterParams{
+		Username: &user.Username,
+		Tags:     tags,
+		After:    &FilterCursor{ID: both.ID, CreatedAt: time.Now()},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), count)
+
+	// Drafts are only listed to their author
+	draft, err := testQueries.CreatePost(context.Background(), CreatePostParams{
+		UserID:  sql.NullInt32{Int32: user.ID, Valid: true},
+		Title:   "draft " + util.RandomString(6),
+		Content: "not ready yet",
+		Type:    "blog",
+		Format:  "markdown",
+		Slug:    util.RandomString(12),
+		Status:  sql.NullString{String: "draft", Valid: true},
+	})
+	require.NoError(t, err)
+	require.NotContains(t, filterIDs(FilterParams{}), draft.ID)
+	require.Contains(t, filterIDs(FilterParams{ViewerID: &user.ID}), draft.ID)
+	require.Contains(t, filterIDs(FilterParams{AllStatuses: true}), draft.ID)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	session := createRandomSession(t, user)
+
+	_, tokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+	resetToken, err := store.CreatePasswordResetToken(context.Background(), CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	arg := ResetPasswordTxParams{
+		TokenID:      resetToken.ID,
+		UserID:       user.ID,
+		PasswordHash: "new-hash-" + util.RandomString(6),
+	}
+	err = store.ResetPasswordTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	updatedUser, err := store.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, arg.PasswordHash, updatedUser.PasswordHash)
+
+	// Existing sessions are revoked
+	revoked, err := store.GetSessionByRefreshTokenHash(context.Background(), session.RefreshTokenHash)
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	// The token cannot be used a second time
+	arg.PasswordHash = "other-hash"
+	err = store.ResetPasswordTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	updatedUser, err = store.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, arg.PasswordHash, updatedUser.PasswordHash)
+}
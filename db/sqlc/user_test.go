@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/haotianxu2021/newPortfolio/util"
 	"github.com/stretchr/testify/require"
@@ -43,6 +44,7 @@ func createRandomUser(t *testing.T) User {
 
 	require.NotZero(t, user.ID)
 	require.NotZero(t, user.CreatedAt)
+	require.Equal(t, util.RoleAuthor, user.Role)
 
 	return user
 }
@@ -80,6 +82,47 @@ func TestUpdateUser(t *testing.T) {
 	require.Equal(t, arg.Bio, user2.Bio)
 }
 
+func TestUpdateUserEmailClearsVerification(t *testing.T) {
+	user := createRandomUser(t)
+	require.NoError(t, testQueries.MarkUserEmailVerified(context.Background(), user.ID))
+
+	// Fields left empty keep their value and the verification
+	updated, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		ID:  user.ID,
+		Bio: sql.NullString{String: "New bio", Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, updated.Username)
+	require.Equal(t, user.Email, updated.Email)
+	require.True(t, updated.EmailVerifiedAt.Valid)
+
+	updated, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		ID:    user.ID,
+		Email: util.RandomString(8) + "@example.com",
+	})
+	require.NoError(t, err)
+	require.False(t, updated.EmailVerifiedAt.Valid)
+}
+
+func TestRevokeEmailVerificationTokens(t *testing.T) {
+	user := createRandomUser(t)
+
+	_, tokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+	_, err = testQueries.CreateEmailVerificationToken(context.Background(), CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, testQueries.RevokeEmailVerificationTokens(context.Background(), user.ID))
+
+	token, err := testQueries.GetEmailVerificationTokenByHash(context.Background(), tokenHash)
+	require.NoError(t, err)
+	require.True(t, token.UsedAt.Valid)
+}
+
 func TestUpdateUserPassword(t *testing.T) {
 	user1 := createRandomUser(t)
 	newPassword := util.RandomString(10)
@@ -95,3 +138,53 @@ func TestUpdateUserPassword(t *testing.T) {
 	require.Equal(t, user1.Email, result.Email)
 	require.Equal(t, user1.Username, result.Username)
 }
+
+func TestUpdateUserRole(t *testing.T) {
+	user := createRandomUser(t)
+
+	updated, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		ID:   user.ID,
+		Role: util.RoleEditor,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, updated.ID)
+	require.Equal(t, util.RoleEditor, updated.Role)
+
+	// Unknown roles are rejected by the check constraint
+	_, err = testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		ID:   user.ID,
+		Role: "superuser",
+	})
+	require.Error(t, err)
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	require.False(t, user.EmailVerifiedAt.Valid)
+
+	_, tokenHash, err := util.NewOpaqueToken()
+	require.NoError(t, err)
+	verificationToken, err := store.CreateEmailVerificationToken(context.Background(), CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	fetched, err := store.GetEmailVerificationTokenByHash(context.Background(), tokenHash)
+	require.NoError(t, err)
+	require.Equal(t, verificationToken.ID, fetched.ID)
+
+	arg := VerifyEmailTxParams{TokenID: verificationToken.ID, UserID: user.ID}
+	err = store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	verifiedUser, err := store.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.True(t, verifiedUser.EmailVerifiedAt.Valid)
+
+	// The token cannot be used a second time
+	err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
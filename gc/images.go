@@ -0,0 +1,150 @@
+// Package gc removes stored data nothing refers to anymore.
+package gc
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/storage"
+)
+
+// imageBatchSize is how many orphaned images are loaded at a time
+const imageBatchSize = 100
+
+// ImageCollector deletes images that are not linked to any post, along with
+// their variants and stored blobs. An image is only collected once it has
+// been unreferenced for the grace period, so uploads still being attached
+// and images detached by mistake survive a while.
+type ImageCollector struct {
+	store       db.Store
+	blobStore   storage.BlobStore
+	gracePeriod time.Duration
+	now         func() time.Time // UTC, like the timestamps it is compared with
+}
+
+// NewImageCollector creates an ImageCollector
+func NewImageCollector(store db.Store, blobStore storage.BlobStore, gracePeriod time.Duration) *ImageCollector {
+	return &ImageCollector{
+		store:       store,
+		blobStore:   blobStore,
+		gracePeriod: gracePeriod,
+		now:         func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// ImageReport describes what a collection reclaimed, or would have in a dry run
+type ImageReport struct {
+	DryRun bool `json:"dry_run"`
+	// Images and variant files removed from the database
+	Images   int `json:"images"`
+	Variants int `json:"variants"`
+	// Blobs deleted from storage and their size according to the database
+	Blobs int   `json:"blobs"`
+	Bytes int64 `json:"bytes"`
+	// Blobs that could not be deleted, their rows are gone all the same
+	FailedBlobs int `json:"failed_blobs"`
+}
+
+// Collect deletes the images that have been unreferenced for longer than the
+// grace period. In a dry run nothing is deleted, the report tells what would
+// have been.
+func (collector *ImageCollector) Collect(ctx context.Context, dryRun bool) (ImageReport, error) {
+	report := ImageReport{DryRun: dryRun}
+	cutoff := collector.now().Add(-collector.gracePeriod)
+
+	var afterID int32
+	for {
+		images, err := collector.store.ListOrphanedImages(ctx, db.ListOrphanedImagesParams{
+			Cutoff:    cutoff,
+			AfterID:   afterID,
+			BatchSize: imageBatchSize,
+		})
+		if err != nil {
+			return report, err
+		}
+
+		for _, image := range images {
+			afterID = image.ID
+			if err := collector.collectImage(ctx, image, dryRun, &report); err != nil {
+				return report, err
+			}
+		}
+
+		if len(images) < imageBatchSize {
+			return report, nil
+		}
+	}
+}
+
+func (collector *ImageCollector) collectImage(ctx context.Context, image db.Image, dryRun bool, report *ImageReport) error {
+	// The variant rows go with the image, so look up their blobs first
+	variants, err := collector.store.ListImageVariants(ctx, image.ID)
+	if err != nil {
+		return err
+	}
+
+	if !dryRun {
+		// Attaching the image again since it was listed keeps it
+		deleted, err := collector.store.DeleteOrphanedImageTx(ctx, image.ID)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return nil
+		}
+	}
+
+	report.Images++
+	report.Variants += len(variants)
+
+	// Only uploaded images have blobs, older rows recorded a caller-supplied path
+	type blob struct {
+		key  string
+		size int64
+	}
+	blobs := make([]blob, 0, len(variants)+1)
+	if image.IsUploaded() {
+		blobs = append(blobs, blob{image.FilePath, image.SizeBytes})
+	}
+	for _, variant := range variants {
+		blobs = append(blobs, blob{variant.FilePath, variant.SizeBytes})
+	}
+
+	for _, b := range blobs {
+		if !dryRun {
+			if err := collector.blobStore.Delete(ctx, b.key); err != nil {
+				log.Printf("cannot delete blob %s of image %d: %v", b.key, image.ID, err)
+				report.FailedBlobs++
+				continue
+			}
+		}
+		report.Blobs++
+		report.Bytes += b.size
+	}
+
+	return nil
+}
+
+// Run collects orphaned images every interval until ctx is done, logging what
+// each collection reclaimed
+func (collector *ImageCollector) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := collector.Collect(ctx, false)
+			if err != nil {
+				log.Printf("image garbage collection failed: %v", err)
+			}
+			if report.Images > 0 {
+				log.Printf("image garbage collection: %+v", report)
+			}
+		}
+	}
+}
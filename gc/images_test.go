@@ -0,0 +1,154 @@
+package gc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func putBlob(t *testing.T, blobStore storage.BlobStore, key string) {
+	err := blobStore.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), "image/png")
+	require.NoError(t, err)
+}
+
+func blobExists(t *testing.T, blobStore storage.BlobStore, key string) bool {
+	reader, err := blobStore.Get(context.Background(), key)
+	if err == storage.ErrNotFound {
+		return false
+	}
+	require.NoError(t, err)
+	reader.Close()
+	return true
+}
+
+func TestImageCollectorCollect(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	uploaded := db.Image{ID: 1, FilePath: "images/1/a.png", Checksum: "abc", SizeBytes: 1000}
+	legacy := db.Image{ID: 2, FilePath: "https://example.com/old.png"}
+	// A duplicate upload whose checksum was cleared when images were deduplicated
+	duplicate := db.Image{ID: 4, FilePath: "images/1/d.png", MimeType: "image/png", SizeBytes: 200}
+	relinked := db.Image{ID: 3, FilePath: "images/1/c.png", Checksum: "def", SizeBytes: 500}
+	variant := db.ImageVariant{ImageID: 1, Name: "thumbnail", FilePath: "images/1/a-thumbnail.png", SizeBytes: 100}
+
+	testCases := []struct {
+		name        string
+		dryRun      bool
+		buildStubs  func(store *mockdb.MockStore)
+		checkResult func(t *testing.T, report ImageReport, blobStore storage.BlobStore)
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListOrphanedImages(gomock.Any(), gomock.Eq(db.ListOrphanedImagesParams{
+						Cutoff:    now.Add(-24 * time.Hour),
+						BatchSize: imageBatchSize,
+					})).
+					Times(1).
+					Return([]db.Image{uploaded, legacy, relinked, duplicate}, nil)
+
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return([]db.ImageVariant{variant}, nil)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Eq(int32(2))).Times(1).Return([]db.ImageVariant{}, nil)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Eq(int32(3))).Times(1).Return([]db.ImageVariant{}, nil)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Eq(int32(4))).Times(1).Return([]db.ImageVariant{}, nil)
+
+				store.EXPECT().DeleteOrphanedImageTx(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return(int64(1), nil)
+				store.EXPECT().DeleteOrphanedImageTx(gomock.Any(), gomock.Eq(int32(2))).Times(1).Return(int64(1), nil)
+				// Attached to a post again since it was listed
+				store.EXPECT().DeleteOrphanedImageTx(gomock.Any(), gomock.Eq(int32(3))).Times(1).Return(int64(0), nil)
+				store.EXPECT().DeleteOrphanedImageTx(gomock.Any(), gomock.Eq(int32(4))).Times(1).Return(int64(1), nil)
+			},
+			checkResult: func(t *testing.T, report ImageReport, blobStore storage.BlobStore) {
+				require.Equal(t, ImageReport{Images: 3, Variants: 1, Blobs: 3, Bytes: 1300}, report)
+				require.False(t, blobExists(t, blobStore, uploaded.FilePath))
+				require.False(t, blobExists(t, blobStore, duplicate.FilePath))
+				require.False(t, blobExists(t, blobStore, variant.FilePath))
+				require.True(t, blobExists(t, blobStore, relinked.FilePath))
+			},
+		},
+		{
+			name:   "DryRun",
+			dryRun: true,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListOrphanedImages(gomock.Any(), gomock.Any()).Times(1).Return([]db.Image{uploaded}, nil)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Eq(int32(1))).Times(1).Return([]db.ImageVariant{variant}, nil)
+				store.EXPECT().DeleteOrphanedImageTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResult: func(t *testing.T, report ImageReport, blobStore storage.BlobStore) {
+				require.Equal(t, ImageReport{DryRun: true, Images: 1, Variants: 1, Blobs: 2, Bytes: 1100}, report)
+				require.True(t, blobExists(t, blobStore, uploaded.FilePath))
+				require.True(t, blobExists(t, blobStore, variant.FilePath))
+			},
+		},
+		{
+			name: "Batches",
+			buildStubs: func(store *mockdb.MockStore) {
+				batch := make([]db.Image, imageBatchSize)
+				for i := range batch {
+					batch[i] = db.Image{ID: int32(i + 1)}
+				}
+
+				gomock.InOrder(
+					store.EXPECT().
+						ListOrphanedImages(gomock.Any(), gomock.Eq(db.ListOrphanedImagesParams{
+							Cutoff:    now.Add(-24 * time.Hour),
+							BatchSize: imageBatchSize,
+						})).
+						Return(batch, nil),
+					store.EXPECT().
+						ListOrphanedImages(gomock.Any(), gomock.Eq(db.ListOrphanedImagesParams{
+							Cutoff:    now.Add(-24 * time.Hour),
+							AfterID:   imageBatchSize,
+							BatchSize: imageBatchSize,
+						})).
+						Return([]db.Image{}, nil),
+				)
+				store.EXPECT().ListImageVariants(gomock.Any(), gomock.Any()).Times(imageBatchSize).Return([]db.ImageVariant{}, nil)
+				store.EXPECT().DeleteOrphanedImageTx(gomock.Any(), gomock.Any()).Times(imageBatchSize).Return(int64(1), nil)
+			},
+			checkResult: func(t *testing.T, report ImageReport, blobStore storage.BlobStore) {
+				require.Equal(t, imageBatchSize, report.Images)
+				require.Zero(t, report.Blobs)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			blobStore := storage.NewLocalBlobStore(t.TempDir(), "/uploads")
+			putBlob(t, blobStore, uploaded.FilePath)
+			putBlob(t, blobStore, variant.FilePath)
+			putBlob(t, blobStore, relinked.FilePath)
+			putBlob(t, blobStore, duplicate.FilePath)
+
+			collector := NewImageCollector(store, blobStore, 24*time.Hour)
+			collector.now = func() time.Time { return now }
+
+			report, err := collector.Collect(context.Background(), tc.dryRun)
+			require.NoError(t, err)
+			tc.checkResult(t, report, blobStore)
+		})
+	}
+}
+
+func TestImageCollectorNowIsUTC(t *testing.T) {
+	// Image timestamps are stored without a time zone, in UTC
+	collector := NewImageCollector(nil, nil, time.Hour)
+	require.Equal(t, time.UTC, collector.now().Location())
+}
@@ -0,0 +1,163 @@
+// Package imaging decodes uploaded images and renders the resized variants
+// served to browsers.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+// jpegQuality is used for every JPEG variant
+const jpegQuality = 82
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrMalformed         = errors.New("malformed image data")
+	ErrTooManyPixels     = errors.New("image has too many pixels")
+)
+
+// VariantSpec describes one size an uploaded image is rendered in
+type VariantSpec struct {
+	Name     string
+	MaxWidth int
+}
+
+// DefaultVariants are the sizes generated for every upload, smallest first
+var DefaultVariants = []VariantSpec{
+	{Name: "thumbnail", MaxWidth: 320},
+	{Name: "medium", MaxWidth: 800},
+	{Name: "large", MaxWidth: 1600},
+}
+
+// Variant is an encoded, resized copy of an image
+type Variant struct {
+	Name     string
+	Width    int
+	Height   int
+	MimeType string
+	Data     []byte
+}
+
+// CheckDimensions reads the size an image declares in its header and
+// rejects images with more than maxPixels pixels. A few bytes can declare
+// dimensions whose pixels would not fit in memory, so this must pass before
+// an upload is decoded.
+func CheckDimensions(data []byte, maxPixels int64) error {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return ErrUnsupportedFormat
+		}
+		return err
+	}
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return ErrTooManyPixels
+	}
+	return nil
+}
+
+// Decode decodes a JPEG, PNG, GIF or WebP image of at most maxPixels pixels
+// and reports its format
+func Decode(data []byte, maxPixels int64) (image.Image, string, error) {
+	if err := CheckDimensions(data, maxPixels); err != nil {
+		return nil, "", err
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, "", ErrUnsupportedFormat
+		}
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// GenerateVariants renders img at every spec narrower than the image itself,
+// images are never scaled up. Lossless sources and images with transparency
+// are encoded as PNG, everything else as JPEG. There is no pure Go WebP
+// encoder, so WebP uploads get JPEG or PNG variants.
+func GenerateVariants(img image.Image, format string, specs []VariantSpec) ([]Variant, error) {
+	bounds := img.Bounds()
+	variants := make([]Variant, 0, len(specs))
+
+	for _, spec := range specs {
+		if spec.MaxWidth >= bounds.Dx() {
+			continue
+		}
+
+		resized := Resize(img, spec.MaxWidth)
+		data, mimeType, err := Encode(resized, outputFormat(img, format))
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode %s variant: %w", spec.Name, err)
+		}
+
+		variants = append(variants, Variant{
+			Name:     spec.Name,
+			Width:    resized.Bounds().Dx(),
+			Height:   resized.Bounds().Dy(),
+			MimeType: mimeType,
+			Data:     data,
+		})
+	}
+
+	return variants, nil
+}
+
+// Resize scales img to the given width, keeping the aspect ratio
+func Resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Over, nil)
+	return dst
+}
+
+// Encode encodes img as "jpeg" or "png" and returns the bytes with their
+// content type
+func Encode(img image.Image, format string) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case "jpeg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	case "png":
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	default:
+		return nil, "", ErrUnsupportedFormat
+	}
+}
+
+// outputFormat picks the encoding of the variants of an image
+func outputFormat(img image.Image, format string) string {
+	switch format {
+	case "jpeg":
+		return "jpeg"
+	case "png", "gif":
+		return "png"
+	}
+
+	if opaque, ok := img.(interface{ Opaque() bool }); ok && opaque.Opaque() {
+		return "jpeg"
+	}
+	return "png"
+}
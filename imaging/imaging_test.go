@@ -0,0 +1,114 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestImage(width, height int, alpha uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 100, A: alpha})
+		}
+	}
+	return img
+}
+
+func TestGenerateVariantsFromPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, newTestImage(2000, 1000, 255)))
+
+	img, format, err := Decode(buf.Bytes(), 1<<22)
+	require.NoError(t, err)
+	require.Equal(t, "png", format)
+
+	variants, err := GenerateVariants(img, format, DefaultVariants)
+	require.NoError(t, err)
+	require.Len(t, variants, 3)
+
+	expected := []struct {
+		name          string
+		width, height int
+	}{
+		{"thumbnail", 320, 160},
+		{"medium", 800, 400},
+		{"large", 1600, 800},
+	}
+	for i, variant := range variants {
+		require.Equal(t, expected[i].name, variant.Name)
+		require.Equal(t, expected[i].width, variant.Width)
+		require.Equal(t, expected[i].height, variant.Height)
+		require.Equal(t, "image/png", variant.MimeType)
+
+		decoded, err := png.Decode(bytes.NewReader(variant.Data))
+		require.NoError(t, err)
+		require.Equal(t, expected[i].width, decoded.Bounds().Dx())
+	}
+}
+
+func TestGenerateVariantsNeverUpscales(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, newTestImage(500, 250, 255), nil))
+
+	img, format, err := Decode(buf.Bytes(), 1<<22)
+	require.NoError(t, err)
+	require.Equal(t, "jpeg", format)
+
+	variants, err := GenerateVariants(img, format, DefaultVariants)
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	require.Equal(t, "thumbnail", variants[0].Name)
+	require.Equal(t, "image/jpeg", variants[0].MimeType)
+
+	_, err = jpeg.Decode(bytes.NewReader(variants[0].Data))
+	require.NoError(t, err)
+}
+
+func TestOutputFormat(t *testing.T) {
+	require.Equal(t, "jpeg", outputFormat(newTestImage(1, 1, 255), "webp"))
+	require.Equal(t, "png", outputFormat(newTestImage(1, 1, 128), "webp"))
+	require.Equal(t, "png", outputFormat(newTestImage(1, 1, 255), "gif"))
+}
+
+func TestDecodeRejectsUnknownFormat(t *testing.T) {
+	_, _, err := Decode([]byte("not an image"), 1<<20)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+// withPNGSize rewrites the dimensions a PNG declares in its header without
+// adding the pixels to match
+func withPNGSize(t *testing.T, img image.Image, width, height uint32) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	// The IHDR chunk follows the 8 byte signature: length, type, width,
+	// height, 5 more bytes of header and the CRC of type and data
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestDecodeRejectsTooManyPixels(t *testing.T) {
+	// A few hundred bytes declaring an image of 10 billion pixels
+	data := withPNGSize(t, newTestImage(1, 1, 255), 100000, 100000)
+
+	require.ErrorIs(t, CheckDimensions(data, 1<<20), ErrTooManyPixels)
+	_, _, err := Decode(data, 1<<20)
+	require.ErrorIs(t, err, ErrTooManyPixels)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, newTestImage(1024, 1024, 255)))
+	require.NoError(t, CheckDimensions(buf.Bytes(), 1<<20))
+	require.ErrorIs(t, CheckDimensions(buf.Bytes(), 1<<20-1), ErrTooManyPixels)
+}
@@ -0,0 +1,331 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// sanitizedJPEGQuality is used when a JPEG has to be re-encoded to apply its
+// orientation, high enough that the extra generation is hard to notice
+const sanitizedJPEGQuality = 92
+
+// exifOrientationTag is the EXIF tag holding the orientation of the camera
+const exifOrientationTag = 0x0112
+
+// Sanitize removes EXIF, GPS, XMP, comments and other metadata from an
+// encoded JPEG, PNG, GIF or WebP image. When the metadata asks for the image
+// to be rotated or mirrored, the pixels are transformed and re-encoded so the
+// image still displays the right way up without it.
+func Sanitize(data []byte) ([]byte, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	switch format {
+	case "jpeg":
+		return sanitizeJPEG(data)
+	case "png":
+		return sanitizePNG(data)
+	case "gif":
+		return sanitizeGIF(data)
+	case "webp":
+		return stripWebPMetadata(data)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func sanitizeJPEG(data []byte) ([]byte, error) {
+	stripped, exif, err := stripJPEGMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := exifOrientation(exif)
+	if orientation <= 1 {
+		return stripped, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = jpeg.Encode(&buf, applyOrientation(img, orientation), &jpeg.Options{Quality: sanitizedJPEGQuality})
+	return buf.Bytes(), err
+}
+
+// stripJPEGMetadata drops every application segment except JFIF and ICC
+// colour profiles, and all comments. It also returns the TIFF data of the
+// EXIF segment, if there was one.
+func stripJPEGMetadata(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, nil, ErrMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	var exif []byte
+	pos := 2
+	for {
+		if pos+4 > len(data) || data[pos] != 0xFF {
+			return nil, nil, ErrMalformed
+		}
+		marker := data[pos+1]
+
+		// Padding before a marker
+		if marker == 0xFF {
+			pos++
+			continue
+		}
+
+		// Start of scan, the compressed image data follows without metadata
+		if marker == 0xDA {
+			out.Write(data[pos:])
+			return out.Bytes(), exif, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, nil, ErrMalformed
+		}
+		payload := data[pos+4 : end]
+
+		keep := true
+		switch {
+		case marker == 0xE0:
+			keep = bytes.HasPrefix(payload, []byte("JFIF\x00"))
+		case marker == 0xE1:
+			keep = false
+			if exif == nil && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				exif = payload[6:]
+			}
+		case marker == 0xE2:
+			keep = bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00"))
+		case marker >= 0xE3 && marker <= 0xEF, marker == 0xFE:
+			keep = false
+		}
+
+		if keep {
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+}
+
+func sanitizePNG(data []byte) ([]byte, error) {
+	stripped, exif, err := stripPNGMetadata(data)
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := exifOrientation(exif)
+	if orientation <= 1 {
+		return stripped, nil
+	}
+
+	img, err := png.Decode(bytes.NewReader(stripped))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, applyOrientation(img, orientation))
+	return buf.Bytes(), err
+}
+
+// pngMetadataChunks are the ancillary chunks that describe the image rather
+// than how to display it
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// stripPNGMetadata drops text, time and EXIF chunks. It also returns the
+// content of the eXIf chunk, if there was one.
+func stripPNGMetadata(data []byte) ([]byte, []byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, nil, ErrMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.WriteString(signature)
+
+	var exif []byte
+	pos := len(signature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, nil, ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, nil, ErrMalformed
+		}
+
+		if chunkType == "eXIf" && exif == nil {
+			exif = data[pos+8 : pos+8+length]
+		}
+		if !pngMetadataChunks[chunkType] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	return out.Bytes(), exif, nil
+}
+
+// sanitizeGIF re-encodes the animation, which leaves out comment and
+// application extensions such as XMP
+func sanitizeGIF(data []byte) ([]byte, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = gif.EncodeAll(&buf, g)
+	return buf.Bytes(), err
+}
+
+// stripWebPMetadata drops the EXIF and XMP chunks of an extended WebP file
+// and clears their flags in the VP8X header
+func stripWebPMetadata(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:12])
+
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		end := pos + 8 + size + size%2 // chunks are padded to an even size
+		if size < 0 || end > len(data) {
+			return nil, ErrMalformed
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+			// dropped
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= 0x08 | 0x04 // EXIF and XMP present flags
+			}
+			out.Write(chunk)
+		default:
+			out.Write(data[pos:end])
+		}
+		pos = end
+	}
+
+	result := out.Bytes()
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+	return result, nil
+}
+
+// exifOrientation reads the orientation from EXIF TIFF data. It returns 1,
+// the normal orientation, when the tag is missing or the data is malformed.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == exifOrientationTag {
+			orientation := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation rotates and mirrors img as described by an EXIF
+// orientation value, so that it displays upright without the tag
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the top-left diagonal
+				dx, dy = y, x
+			case 6: // needs a 90° clockwise turn
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the top-right diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // needs a 90° counter-clockwise turn
+				dx, dy = y, w-1-x
+			}
+			si := src.PixOffset(x, y)
+			di := dst.PixOffset(dx, dy)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,178 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// exifSegment builds a little endian EXIF APP1 segment holding an
+// orientation and a fake GPS pointer
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte("II*\x00")
+	tiff = binary.LittleEndian.AppendUint32(tiff, 8)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+
+	// Orientation, SHORT
+	tiff = binary.LittleEndian.AppendUint16(tiff, exifOrientationTag)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint16(tiff, orientation)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0)
+
+	// GPSInfo pointer, LONG
+	tiff = binary.LittleEndian.AppendUint16(tiff, 0x8825)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 0)
+	tiff = append(tiff, "GPS 51.5007N 0.1246W"...)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// testJPEG encodes a width x height JPEG whose top left 8x8 block is red, with
+// the given segments inserted after the start of image marker
+func testJPEG(t *testing.T, width, height int, segments ...[]byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{A: 255})
+		}
+	}
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+	encoded := buf.Bytes()
+
+	data := append([]byte(nil), encoded[:2]...)
+	for _, segment := range segments {
+		data = append(data, segment...)
+	}
+	return append(data, encoded[2:]...)
+}
+
+func TestSanitizeJPEGStripsMetadata(t *testing.T) {
+	comment := []byte{0xFF, 0xFE, 0x00, 0x0A, 'a', 'u', 't', 'h', 'o', 'r', ':', 'x'}
+	data := testJPEG(t, 32, 16, exifSegment(1), comment)
+
+	sanitized, err := Sanitize(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(sanitized), "Exif")
+	require.NotContains(t, string(sanitized), "GPS")
+	require.NotContains(t, string(sanitized), "author")
+
+	// Nothing to rotate, so the image data is kept as it was
+	require.Equal(t, testJPEG(t, 32, 16), sanitized)
+}
+
+func TestSanitizeJPEGAppliesOrientation(t *testing.T) {
+	// Orientation 6 means the camera was turned, the image needs a clockwise turn
+	data := testJPEG(t, 32, 16, exifSegment(6))
+
+	sanitized, err := Sanitize(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(sanitized), "Exif")
+
+	img, err := jpeg.Decode(bytes.NewReader(sanitized))
+	require.NoError(t, err)
+	require.Equal(t, 16, img.Bounds().Dx())
+	require.Equal(t, 32, img.Bounds().Dy())
+
+	// The red top left corner ends up top right
+	r, _, _, _ := img.At(12, 4).RGBA()
+	require.Greater(t, r>>8, uint32(200))
+}
+
+func TestSanitizePNGStripsTextChunks(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 2, 2))))
+	encoded := buf.Bytes()
+
+	// Insert a tEXt chunk right after IHDR
+	text := []byte("tEXtLocation\x00home")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+
+	ihdrEnd := 8 + 12 + 13
+	data := append(append(append([]byte(nil), encoded[:ihdrEnd]...), chunk...), encoded[ihdrEnd:]...)
+
+	sanitized, err := Sanitize(data)
+	require.NoError(t, err)
+	require.Equal(t, encoded, sanitized)
+}
+
+func TestStripWebPMetadata(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		c := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+		c = append(c, payload...)
+		if len(payload)%2 == 1 {
+			c = append(c, 0)
+		}
+		return c
+	}
+
+	vp8x := chunk("VP8X", []byte{0x08 | 0x04 | 0x10, 0, 0, 0, 1, 0, 0, 1, 0, 0})
+	image := chunk("VP8L", []byte{0x2F, 0, 0, 0, 0})
+	exif := chunk("EXIF", []byte("II*\x00GPS"))
+	xmp := chunk("XMP ", []byte("<x:xmpmeta/>"))
+
+	body := append(append(append(append([]byte("WEBP"), vp8x...), image...), exif...), xmp...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	stripped, err := stripWebPMetadata(data)
+	require.NoError(t, err)
+	require.NotContains(t, string(stripped), "EXIF")
+	require.NotContains(t, string(stripped), "xmpmeta")
+	require.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:8]))
+
+	// Only the alpha flag is left in the VP8X header
+	require.Equal(t, byte(0x10), stripped[20])
+	require.Equal(t, image, stripped[12+len(vp8x):])
+}
+
+func TestApplyOrientation(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+
+	testCases := []struct {
+		orientation   int
+		width, height int
+		x, y          int
+	}{
+		{1, 3, 2, 0, 0},
+		{2, 3, 2, 2, 0},
+		{3, 3, 2, 2, 1},
+		{4, 3, 2, 0, 1},
+		{5, 2, 3, 0, 0},
+		{6, 2, 3, 1, 0},
+		{7, 2, 3, 1, 2},
+		{8, 2, 3, 0, 2},
+	}
+
+	for _, tc := range testCases {
+		oriented := applyOrientation(img, tc.orientation)
+		require.Equal(t, tc.width, oriented.Bounds().Dx(), "orientation %d", tc.orientation)
+		require.Equal(t, tc.height, oriented.Bounds().Dy(), "orientation %d", tc.orientation)
+
+		r, _, _, _ := oriented.At(tc.x, tc.y).RGBA()
+		require.Equal(t, uint32(0xFFFF), r, "orientation %d", tc.orientation)
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -12,6 +14,9 @@ import (
 
 	"github.com/haotianxu2021/newPortfolio/api"
 	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/haotianxu2021/newPortfolio/gc"
+	"github.com/haotianxu2021/newPortfolio/scheduler"
+	"github.com/haotianxu2021/newPortfolio/storage"
 	"github.com/haotianxu2021/newPortfolio/util"
 	_ "github.com/lib/pq"
 )
@@ -32,6 +37,18 @@ func main() {
 	// Create store
 	store := db.NewStore(conn)
 
+	blobStore, err := storage.NewBlobStore(config)
+	if err != nil {
+		log.Fatal("cannot create blob store:", err)
+	}
+	collector := gc.NewImageCollector(store, blobStore, config.ImageGCGracePeriod)
+
+	// "gc-images" runs one collection and exits instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "gc-images" {
+		runImageGC(collector, os.Args[2:])
+		return
+	}
+
 	// Create and start server
 	server, err := api.NewServer(store, config)
 	if err != nil {
@@ -45,6 +62,23 @@ func main() {
 		serverErr <- server.Start(config.ServerAddress)
 	}()
 
+	// Background jobs stop when the server does
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	// Collect orphaned images in the background
+	if config.ImageGCInterval > 0 {
+		go collector.Run(jobsCtx, config.ImageGCInterval)
+	}
+
+	// Posts migrated before slugs existed get the slugs their titles give
+	go backfillPostSlugs(jobsCtx, store)
+
+	// Publish scheduled posts once they are due
+	if config.PostSchedulerInterval > 0 {
+		go scheduler.NewPostPublisher(store).Run(jobsCtx, config.PostSchedulerInterval)
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -70,3 +104,37 @@ func main() {
 		log.Println("server exited properly")
 	}
 }
+
+// backfillPostSlugs renames the posts whose slug util.Slugify would not have
+// made from their title, logging how many there were
+func backfillPostSlugs(ctx context.Context, store db.Store) {
+	renamed, err := store.BackfillPostSlugs(ctx)
+	if err != nil {
+		log.Printf("post slug backfill failed: %v", err)
+	}
+	if renamed > 0 {
+		log.Printf("post slug backfill: renamed %d posts", renamed)
+	}
+}
+
+// runImageGC collects orphaned images once and prints what was reclaimed.
+// With -dry-run it only prints what would be.
+func runImageGC(collector *gc.ImageCollector, args []string) {
+	flags := flag.NewFlagSet("gc-images", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "report what would be deleted without deleting anything")
+	flags.Parse(args)
+
+	report, err := collector.Collect(context.Background(), *dryRun)
+	if err != nil {
+		log.Fatal("image garbage collection failed:", err)
+	}
+
+	verb := "deleted"
+	if report.DryRun {
+		verb = "would delete"
+	}
+	fmt.Printf("%s %d images with %d variants, %d blobs, %d bytes\n", verb, report.Images, report.Variants, report.Blobs, report.Bytes)
+	if report.FailedBlobs > 0 {
+		fmt.Printf("could not delete %d blobs, see the log\n", report.FailedBlobs)
+	}
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/haotianxu2021/newPortfolio/util"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	gmutil "github.com/yuin/goldmark/util"
+)
+
+// markdown renders GitHub flavoured Markdown. Raw HTML is passed through
+// because Render sanitizes the output afterwards.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(
+		html.WithUnsafe(),
+		renderer.WithNodeRenderers(gmutil.Prioritized(codeBlockRenderer{}, 100)),
+	),
+)
+
+// codeFormatter marks tokens with classes rather than inline styles, so the
+// colours come from a stylesheet and survive sanitizing
+var codeFormatter = chromahtml.New(chromahtml.WithClasses(true))
+
+func renderMarkdown(content string) (string, []TOCEntry, error) {
+	source := []byte(content)
+	ctx := parser.NewContext(parser.WithIDs(&headingIDs{used: map[string]bool{}}))
+	doc := markdown.Parser().Parse(text.NewReader(source), parser.WithContext(ctx))
+
+	var buf bytes.Buffer
+	if err := markdown.Renderer().Render(&buf, source, doc); err != nil {
+		return "", nil, err
+	}
+	return buf.String(), tableOfContents(doc, source), nil
+}
+
+// headingIDs makes heading anchors the same way post slugs are made, so
+// accented headings keep their letters, and numbers repeated ones
+type headingIDs struct {
+	used map[string]bool
+}
+
+func (ids *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	base := util.Slugify(string(value))
+	id := base
+	for n := 2; ids.used[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	ids.used[id] = true
+	return []byte(id)
+}
+
+func (ids *headingIDs) Put(value []byte) {
+	ids.used[string(value)] = true
+}
+
+// tableOfContents lists the headings of doc in order
+func tableOfContents(doc ast.Node, source []byte) []TOCEntry {
+	var toc []TOCEntry
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		entry := TOCEntry{Level: heading.Level, Text: string(nodeText(heading, source))}
+		if id, ok := heading.AttributeString("id"); ok {
+			if id, ok := id.([]byte); ok {
+				entry.ID = string(id)
+			}
+		}
+		toc = append(toc, entry)
+		return ast.WalkSkipChildren, nil
+	})
+	return toc
+}
+
+// nodeText returns the text inside node without any markup
+func nodeText(node ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch child := child.(type) {
+		case *ast.Text:
+			buf.Write(child.Segment.Value(source))
+			if child.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(child.Value)
+		default:
+			buf.Write(nodeText(child, source))
+		}
+	}
+	return buf.Bytes()
+}
+
+// codeBlockRenderer highlights fenced code blocks in the language named after
+// the opening fence
+type codeBlockRenderer struct{}
+
+func (r codeBlockRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r codeBlockRenderer) renderFencedCodeBlock(w gmutil.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	block := node.(*ast.FencedCodeBlock)
+
+	var code bytes.Buffer
+	lines := block.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	lexer := lexers.Get(string(block.Language(source)))
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code.String())
+	if err != nil {
+		return ast.WalkStop, err
+	}
+	if err := codeFormatter.Format(w, styles.Fallback, tokens); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkSkipChildren, nil
+}
@@ -0,0 +1,98 @@
+// Package render turns post content into the sanitized HTML served to
+// browsers, together with a table of contents built from its headings.
+package render
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// Formats post content can be written in
+const (
+	FormatMarkdown = "markdown" // CommonMark with GitHub extensions
+	FormatHTML     = "html"     // HTML, sanitized before it is served
+	FormatPlain    = "plain"    // text, blank lines separate paragraphs
+)
+
+var ErrUnknownFormat = errors.New("unknown content format")
+
+// TOCEntry is one heading in the table of contents. ID is the anchor of the
+// heading in the rendered HTML.
+type TOCEntry struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// Result is rendered content. TOC is empty rather than nil for content
+// without headings.
+type Result struct {
+	HTML string
+	TOC  []TOCEntry
+}
+
+// policy allows what users may write in posts, plus the heading ids and the
+// classes the code highlighter marks tokens with
+var policy = newPolicy()
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^[a-zA-Z0-9 _-]+$`)).OnElements("pre", "code", "span")
+	return p
+}
+
+// IsValidFormat reports whether format is one of the known content formats
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatMarkdown, FormatHTML, FormatPlain:
+		return true
+	}
+	return false
+}
+
+// Render renders content written in format. Whatever the format, the HTML is
+// sanitized, so it is safe to serve as is.
+func Render(format, content string) (Result, error) {
+	var result Result
+	switch format {
+	case FormatMarkdown:
+		rendered, toc, err := renderMarkdown(content)
+		if err != nil {
+			return result, err
+		}
+		result.HTML, result.TOC = rendered, toc
+	case FormatHTML:
+		result.HTML = content
+	case FormatPlain:
+		result.HTML = renderPlain(content)
+	default:
+		return result, ErrUnknownFormat
+	}
+
+	result.HTML = policy.Sanitize(result.HTML)
+	if result.TOC == nil {
+		result.TOC = []TOCEntry{}
+	}
+	return result, nil
+}
+
+// renderPlain escapes text and keeps its paragraphs and line breaks
+func renderPlain(content string) string {
+	var sb strings.Builder
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	for _, paragraph := range strings.Split(content, "\n\n") {
+		paragraph = strings.Trim(paragraph, "\n")
+		if strings.TrimSpace(paragraph) == "" {
+			continue
+		}
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		sb.WriteString("</p>\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	content := "# Getting *started*\n\n" +
+		"Some text.\n\n" +
+		"## Über uns\n\n" +
+		"## Über uns\n\n" +
+		"```go\nfunc main() {}\n```\n"
+
+	result, err := Render(FormatMarkdown, content)
+	require.NoError(t, err)
+
+	require.Contains(t, result.HTML, `<h1 id="getting-started">Getting <em>started</em></h1>`)
+	require.Contains(t, result.HTML, `<h2 id="uber-uns">`)
+	require.Contains(t, result.HTML, `<h2 id="uber-uns-2">`)
+	require.Contains(t, result.HTML, `<pre class="chroma">`)
+	require.Contains(t, result.HTML, `<span class="kd">func</span>`)
+
+	require.Equal(t, []TOCEntry{
+		{Level: 1, ID: "getting-started", Text: "Getting started"},
+		{Level: 2, ID: "uber-uns", Text: "Über uns"},
+		{Level: 2, ID: "uber-uns-2", Text: "Über uns"},
+	}, result.TOC)
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		content string
+	}{
+		{
+			name:    "Markdown",
+			format:  FormatMarkdown,
+			content: "Hi <script>alert(1)</script> <b onclick=\"steal()\">there</b> [link](javascript:alert(1))",
+		},
+		{
+			name:    "HTML",
+			format:  FormatHTML,
+			content: `<p>Hi <script>alert(1)</script> <b onclick="steal()">there</b> <a href="javascript:alert(1)">link</a></p>`,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := Render(tc.format, tc.content)
+			require.NoError(t, err)
+
+			require.Contains(t, result.HTML, "<b>there</b>")
+			require.NotContains(t, result.HTML, "<script")
+			require.NotContains(t, result.HTML, "onclick")
+			require.NotContains(t, result.HTML, "javascript:")
+			require.Empty(t, result.TOC)
+			require.NotNil(t, result.TOC)
+		})
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	result, err := Render(FormatPlain, "a <b> & c\nnext line\n\n\nsecond paragraph")
+	require.NoError(t, err)
+	require.Equal(t, "<p>a &lt;b&gt; &amp; c<br>\nnext line</p>\n<p>second paragraph</p>\n", result.HTML)
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	_, err := Render("rtf", "content")
+	require.ErrorIs(t, err, ErrUnknownFormat)
+	require.False(t, IsValidFormat("rtf"))
+	require.True(t, IsValidFormat(FormatMarkdown))
+}
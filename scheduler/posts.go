@@ -0,0 +1,52 @@
+// Package scheduler runs the jobs that change content at a given time.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+)
+
+// PostPublisher publishes scheduled posts once their publish_at has passed
+type PostPublisher struct {
+	store db.Store
+	now   func() time.Time
+}
+
+// NewPostPublisher creates a PostPublisher
+func NewPostPublisher(store db.Store) *PostPublisher {
+	return &PostPublisher{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// PublishDue publishes every scheduled post that is due and returns them.
+// A post is only ever published once, even when runs overlap.
+func (publisher *PostPublisher) PublishDue(ctx context.Context) ([]db.Post, error) {
+	return publisher.store.PublishScheduledPosts(ctx, publisher.now().UTC())
+}
+
+// Run publishes due posts every interval until ctx is done, logging each
+// post it published
+func (publisher *PostPublisher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			posts, err := publisher.PublishDue(ctx)
+			if err != nil {
+				log.Printf("cannot publish scheduled posts: %v", err)
+			}
+			for _, post := range posts {
+				log.Printf("published scheduled post %d due at %s", post.ID, post.PublishAt.Time.Format(time.RFC3339))
+			}
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	mockdb "github.com/haotianxu2021/newPortfolio/db/mock"
+	db "github.com/haotianxu2021/newPortfolio/db/sqlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostPublisherPublishDue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Local times are compared to publish_at in UTC
+	now := time.Date(2024, 6, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	due := db.Post{
+		ID:        1,
+		Status:    sql.NullString{String: "published", Valid: true},
+		PublishAt: sql.NullTime{Time: now.UTC().Add(-time.Minute), Valid: true},
+	}
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		PublishScheduledPosts(gomock.Any(), gomock.Eq(now.UTC())).
+		Times(1).
+		Return([]db.Post{due}, nil)
+
+	publisher := NewPostPublisher(store)
+	publisher.now = func() time.Time { return now }
+
+	posts, err := publisher.PublishDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []db.Post{due}, posts)
+}
+
+func TestPostPublisherRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		PublishScheduledPosts(gomock.Any(), gomock.Any()).
+		MinTimes(1).
+		DoAndReturn(func(context.Context, time.Time) ([]db.Post, error) {
+			cancel()
+			return []db.Post{}, nil
+		})
+
+	done := make(chan struct{})
+	go func() {
+		NewPostPublisher(store).Run(ctx, time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publisher did not stop after its context was cancelled")
+	}
+}
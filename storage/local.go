@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalBlobStore keeps blobs as files below a root directory
+type LocalBlobStore struct {
+	root      string
+	publicURL string
+}
+
+// NewLocalBlobStore creates a blob store writing to root. Blob URLs are built
+// by joining publicURL and the key.
+func NewLocalBlobStore(root, publicURL string) *LocalBlobStore {
+	return &LocalBlobStore{
+		root:      root,
+		publicURL: strings.TrimRight(publicURL, "/"),
+	}
+}
+
+func (store *LocalBlobStore) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(store.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob to a temporary file first, so readers never see a
+// partly written file
+func (store *LocalBlobStore) Put(ctx context.Context, key string, data io.Reader, size int64, contentType string) error {
+	name, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+func (store *LocalBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := store.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Delete removes the blob, deleting a missing blob is not an error
+func (store *LocalBlobStore) Delete(ctx context.Context, key string) error {
+	name, err := store.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (store *LocalBlobStore) URL(key string) string {
+	return store.publicURL + "/" + key
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	root := t.TempDir()
+	store := NewLocalBlobStore(root, "/uploads/")
+	ctx := context.Background()
+
+	data := "fake image bytes"
+	err := store.Put(ctx, "images/1/photo.png", strings.NewReader(data), int64(len(data)), "image/png")
+	require.NoError(t, err)
+
+	onDisk, err := os.ReadFile(filepath.Join(root, "images", "1", "photo.png"))
+	require.NoError(t, err)
+	require.Equal(t, data, string(onDisk))
+
+	reader, err := store.Get(ctx, "images/1/photo.png")
+	require.NoError(t, err)
+	got, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, data, string(got))
+
+	require.Equal(t, "/uploads/images/1/photo.png", store.URL("images/1/photo.png"))
+
+	require.NoError(t, store.Delete(ctx, "images/1/photo.png"))
+	_, err = store.Get(ctx, "images/1/photo.png")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Deleting twice is fine
+	require.NoError(t, store.Delete(ctx, "images/1/photo.png"))
+}
+
+func TestLocalBlobStoreRejectsInvalidKeys(t *testing.T) {
+	store := NewLocalBlobStore(t.TempDir(), "/uploads")
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "images/../../secret", "images//photo.png", `images\photo.png`} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain")
+		require.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/haotianxu2021/newPortfolio/util"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobStore keeps uploaded files. Keys are slash separated relative paths
+// such as "images/1/photo.png".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns the address clients can download the blob from
+	URL(key string) string
+}
+
+// NewBlobStore returns the blob store selected by config.StorageDriver: "s3"
+// for an S3 compatible service, anything else keeps files on local disk.
+func NewBlobStore(config util.Config) (BlobStore, error) {
+	switch config.StorageDriver {
+	case "s3":
+		if config.S3Endpoint == "" || config.S3Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for the s3 storage driver")
+		}
+		return NewS3BlobStore(S3Config{
+			Endpoint:        config.S3Endpoint,
+			Region:          config.S3Region,
+			Bucket:          config.S3Bucket,
+			AccessKeyID:     config.S3AccessKeyID,
+			SecretAccessKey: config.S3SecretAccessKey,
+			PublicURL:       config.StoragePublicURL,
+		}), nil
+	default:
+		return NewLocalBlobStore(config.StorageDir, config.StoragePublicURL), nil
+	}
+}
+
+// cleanKey rejects keys that are empty or could escape the store's root
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+
+	return cleaned, nil
+}
@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -14,11 +16,61 @@ import (
 // Config stores all configuration of the application.
 // The values are read from environment variables.
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                       string                  `mapstructure:"DB_DRIVER"`
+	DBSource                       string                  `mapstructure:"DB_SOURCE"`
+	ServerAddress                  string                  `mapstructure:"SERVER_ADDRESS"`
+	TokenSymmetricKey              string                  `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration            time.Duration           `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration           time.Duration           `mapstructure:"REFRESH_TOKEN_DURATION"`
+	PasswordResetTokenDuration     time.Duration           `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	EmailVerificationTokenDuration time.Duration           `mapstructure:"EMAIL_VERIFICATION_TOKEN_DURATION"`
+	RequireEmailVerification       bool                    `mapstructure:"REQUIRE_EMAIL_VERIFICATION"` // block post creation until the email is verified
+	CommentMaxDepth                int32                   `mapstructure:"COMMENT_MAX_DEPTH"`
+	AppBaseURL                     string                  `mapstructure:"APP_BASE_URL"` // frontend URL used in links sent by email
+	MailDriver                     string                  `mapstructure:"MAIL_DRIVER"`  // "smtp", "file" or "log"
+	MailFrom                       string                  `mapstructure:"MAIL_FROM"`
+	MailDir                        string                  `mapstructure:"MAIL_DIR"`
+	SMTPAddress                    string                  `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername                   string                  `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                   string                  `mapstructure:"SMTP_PASSWORD"`
+	StorageDriver                  string                  `mapstructure:"STORAGE_DRIVER"`     // "s3" or "local"
+	StorageDir                     string                  `mapstructure:"STORAGE_DIR"`        // root of the local storage driver
+	StoragePublicURL               string                  `mapstructure:"STORAGE_PUBLIC_URL"` // base URL uploaded files are served from
+	S3Endpoint                     string                  `mapstructure:"S3_ENDPOINT"`
+	S3Region                       string                  `mapstructure:"S3_REGION"`
+	S3Bucket                       string                  `mapstructure:"S3_BUCKET"`
+	S3AccessKeyID                  string                  `mapstructure:"S3_ACCESS_KEY_ID"`
+	S3SecretAccessKey              string                  `mapstructure:"S3_SECRET_ACCESS_KEY"`
+	MaxUploadSize                  int64                   `mapstructure:"MAX_UPLOAD_SIZE"`         // in bytes
+	MaxImagePixels                 int64                   `mapstructure:"MAX_IMAGE_PIXELS"`        // width times height of the largest image accepted
+	ImageGCInterval                time.Duration           `mapstructure:"IMAGE_GC_INTERVAL"`       // zero disables the background collector
+	ImageGCGracePeriod             time.Duration           `mapstructure:"IMAGE_GC_GRACE_PERIOD"`   // how long an image stays unreferenced before it is collected
+	StorageQuotas                  map[string]StorageQuota `mapstructure:"STORAGE_QUOTAS"`          // per role, see ParseStorageQuotas
+	PostSchedulerInterval          time.Duration           `mapstructure:"POST_SCHEDULER_INTERVAL"` // how often scheduled posts are published, zero disables it
+	SiteTitle                      string                  `mapstructure:"SITE_TITLE"`              // title of the feeds
+	SiteURL                        string                  `mapstructure:"SITE_URL"`                // public URL of the site, feeds link posts and themselves under it
+	RobotsDisallow                 []string                `mapstructure:"ROBOTS_DISALLOW"`         // comma separated paths robots.txt asks crawlers to skip
+}
+
+// String formats the configuration for logs with its secrets masked
+func (config Config) String() string {
+	// Formatting a type without the String method avoids calling it again
+	type plainConfig Config
+	redacted := plainConfig(config)
+
+	for _, secret := range []*string{&redacted.TokenSymmetricKey, &redacted.SMTPPassword, &redacted.S3SecretAccessKey} {
+		if *secret != "" {
+			*secret = "[redacted]"
+		}
+	}
+	// The connection string holds the database password
+	if u, err := url.Parse(redacted.DBSource); err == nil && u.User != nil {
+		redacted.DBSource = u.Redacted()
+	} else if redacted.DBSource != "" && !strings.Contains(redacted.DBSource, "://") {
+		redacted.DBSource = "[redacted]"
+	}
+
+	return fmt.Sprintf("%+v", redacted)
 }
 
 // loadEnvFile reads and parses the .env file if it exists.
@@ -129,6 +181,23 @@ func LoadConfig() (config Config, err error) {
 	config.DBSource = os.Getenv("DB_SOURCE")
 	config.ServerAddress = os.Getenv("SERVER_ADDRESS")
 	config.TokenSymmetricKey = os.Getenv("TOKEN_SYMMETRIC_KEY")
+	config.AppBaseURL = os.Getenv("APP_BASE_URL")
+	config.MailDriver = os.Getenv("MAIL_DRIVER")
+	config.MailFrom = os.Getenv("MAIL_FROM")
+	config.MailDir = os.Getenv("MAIL_DIR")
+	config.SMTPAddress = os.Getenv("SMTP_ADDRESS")
+	config.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	config.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	config.StorageDriver = os.Getenv("STORAGE_DRIVER")
+	config.StorageDir = os.Getenv("STORAGE_DIR")
+	config.StoragePublicURL = os.Getenv("STORAGE_PUBLIC_URL")
+	config.S3Endpoint = os.Getenv("S3_ENDPOINT")
+	config.S3Region = os.Getenv("S3_REGION")
+	config.S3Bucket = os.Getenv("S3_BUCKET")
+	config.S3AccessKeyID = os.Getenv("S3_ACCESS_KEY_ID")
+	config.S3SecretAccessKey = os.Getenv("S3_SECRET_ACCESS_KEY")
+	config.SiteTitle = os.Getenv("SITE_TITLE")
+	config.SiteURL = strings.TrimSuffix(os.Getenv("SITE_URL"), "/")
 
 	// Parse duration if set
 	if durationStr := os.Getenv("ACCESS_TOKEN_DURATION"); durationStr != "" {
@@ -138,6 +207,106 @@ func LoadConfig() (config Config, err error) {
 		}
 		config.AccessTokenDuration = duration
 	}
+
+	if durationStr := os.Getenv("REFRESH_TOKEN_DURATION"); durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return config, fmt.Errorf("invalid REFRESH_TOKEN_DURATION format: %w", err)
+		}
+		config.RefreshTokenDuration = duration
+	}
+
+	if durationStr := os.Getenv("PASSWORD_RESET_TOKEN_DURATION"); durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return config, fmt.Errorf("invalid PASSWORD_RESET_TOKEN_DURATION format: %w", err)
+		}
+		config.PasswordResetTokenDuration = duration
+	}
+
+	if durationStr := os.Getenv("EMAIL_VERIFICATION_TOKEN_DURATION"); durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil {
+			return config, fmt.Errorf("invalid EMAIL_VERIFICATION_TOKEN_DURATION format: %w", err)
+		}
+		config.EmailVerificationTokenDuration = duration
+	}
+
+	if durationStr := os.Getenv("IMAGE_GC_INTERVAL"); durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil || duration < 0 {
+			return config, fmt.Errorf("invalid IMAGE_GC_INTERVAL: %s", durationStr)
+		}
+		config.ImageGCInterval = duration
+	}
+
+	if durationStr := os.Getenv("IMAGE_GC_GRACE_PERIOD"); durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil || duration < 0 {
+			return config, fmt.Errorf("invalid IMAGE_GC_GRACE_PERIOD: %s", durationStr)
+		}
+		config.ImageGCGracePeriod = duration
+	}
+
+	if durationStr := os.Getenv("POST_SCHEDULER_INTERVAL"); durationStr != "" {
+		duration, err := time.ParseDuration(durationStr)
+		if err != nil || duration < 0 {
+			return config, fmt.Errorf("invalid POST_SCHEDULER_INTERVAL: %s", durationStr)
+		}
+		config.PostSchedulerInterval = duration
+	}
+
+	if disallowStr := os.Getenv("ROBOTS_DISALLOW"); disallowStr != "" {
+		for _, path := range strings.Split(disallowStr, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				config.RobotsDisallow = append(config.RobotsDisallow, path)
+			}
+		}
+	}
+
+	config.StorageQuotas = make(map[string]StorageQuota, len(defaultStorageQuotas))
+	for role, quota := range defaultStorageQuotas {
+		config.StorageQuotas[role] = quota
+	}
+	if quotasStr := os.Getenv("STORAGE_QUOTAS"); quotasStr != "" {
+		quotas, err := ParseStorageQuotas(quotasStr)
+		if err != nil {
+			return config, fmt.Errorf("invalid STORAGE_QUOTAS: %w", err)
+		}
+		for role, quota := range quotas {
+			config.StorageQuotas[role] = quota
+		}
+	}
+
+	if requireStr := os.Getenv("REQUIRE_EMAIL_VERIFICATION"); requireStr != "" {
+		require, err := strconv.ParseBool(requireStr)
+		if err != nil {
+			return config, fmt.Errorf("invalid REQUIRE_EMAIL_VERIFICATION: %s", requireStr)
+		}
+		config.RequireEmailVerification = require
+	}
+
+	if depthStr := os.Getenv("COMMENT_MAX_DEPTH"); depthStr != "" {
+		depth, err := strconv.ParseInt(depthStr, 10, 32)
+		if err != nil || depth < 0 {
+			return config, fmt.Errorf("invalid COMMENT_MAX_DEPTH: %s", depthStr)
+		}
+		config.CommentMaxDepth = int32(depth)
+	}
+	if sizeStr := os.Getenv("MAX_UPLOAD_SIZE"); sizeStr != "" {
+		size, err := strconv.ParseInt(sizeStr, 10, 64)
+		if err != nil || size <= 0 {
+			return config, fmt.Errorf("invalid MAX_UPLOAD_SIZE: %s", sizeStr)
+		}
+		config.MaxUploadSize = size
+	}
+	if pixelsStr := os.Getenv("MAX_IMAGE_PIXELS"); pixelsStr != "" {
+		pixels, err := strconv.ParseInt(pixelsStr, 10, 64)
+		if err != nil || pixels <= 0 {
+			return config, fmt.Errorf("invalid MAX_IMAGE_PIXELS: %s", pixelsStr)
+		}
+		config.MaxImagePixels = pixels
+	}
 	// log.Printf("%s, %s, %s, %s, %s", config.DBDriver, config.DBSource, config.ServerAddress, config.TokenSymmetricKey, config.AccessTokenDuration)
 
 	// Apply defaults and validate
@@ -149,6 +318,78 @@ func LoadConfig() (config Config, err error) {
 		config.AccessTokenDuration = 15 * time.Minute // default value
 	}
 
+	if config.RefreshTokenDuration == 0 {
+		config.RefreshTokenDuration = 7 * 24 * time.Hour // default value
+	}
+
+	if config.PasswordResetTokenDuration == 0 {
+		config.PasswordResetTokenDuration = time.Hour // default value
+	}
+
+	if config.EmailVerificationTokenDuration == 0 {
+		config.EmailVerificationTokenDuration = 24 * time.Hour // default value
+	}
+
+	if config.AppBaseURL == "" {
+		config.AppBaseURL = "http://localhost:3000" // default value
+	}
+
+	if config.SiteTitle == "" {
+		config.SiteTitle = "Portfolio" // default value
+	}
+
+	if config.SiteURL == "" {
+		config.SiteURL = config.AppBaseURL // default value
+	}
+
+	if config.MailDriver == "" {
+		config.MailDriver = "log" // default value
+	}
+
+	if config.MailFrom == "" {
+		config.MailFrom = "no-reply@localhost" // default value
+	}
+
+	if config.MailDir == "" {
+		config.MailDir = "tmp/mail" // default value
+	}
+
+	if config.StorageDriver == "" {
+		config.StorageDriver = "local" // default value
+	}
+
+	if config.StorageDir == "" {
+		config.StorageDir = "uploads" // default value
+	}
+
+	if config.StoragePublicURL == "" && config.StorageDriver == "local" {
+		config.StoragePublicURL = "/uploads" // default value, served by the API itself
+	}
+
+	if config.MaxUploadSize == 0 {
+		config.MaxUploadSize = 10 << 20 // default value, 10 MiB
+	}
+
+	if config.MaxImagePixels == 0 {
+		config.MaxImagePixels = 50_000_000 // default value, 50 megapixels
+	}
+
+	if os.Getenv("COMMENT_MAX_DEPTH") == "" {
+		config.CommentMaxDepth = 5 // default value
+	}
+
+	if os.Getenv("IMAGE_GC_INTERVAL") == "" {
+		config.ImageGCInterval = time.Hour // default value
+	}
+
+	if os.Getenv("IMAGE_GC_GRACE_PERIOD") == "" {
+		config.ImageGCGracePeriod = 24 * time.Hour // default value
+	}
+
+	if os.Getenv("POST_SCHEDULER_INTERVAL") == "" {
+		config.PostSchedulerInterval = time.Minute // default value
+	}
+
 	if config.DBSource == "" {
 		return config, fmt.Errorf("DB_SOURCE environment variable is required")
 	}
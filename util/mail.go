@@ -0,0 +1,108 @@
+package util
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Email is a plain text message to a single recipient
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users
+type Mailer interface {
+	SendEmail(email Email) error
+}
+
+// NewMailer returns the mailer selected by config.MailDriver: "smtp" for real
+// delivery, "file" to write messages to MailDir, anything else logs them.
+func NewMailer(config Config) (Mailer, error) {
+	switch config.MailDriver {
+	case "smtp":
+		if config.SMTPAddress == "" {
+			return nil, fmt.Errorf("SMTP_ADDRESS is required for the smtp mail driver")
+		}
+		return &SMTPMailer{
+			address:  config.SMTPAddress,
+			username: config.SMTPUsername,
+			password: config.SMTPPassword,
+			from:     config.MailFrom,
+		}, nil
+	case "file":
+		if err := os.MkdirAll(config.MailDir, 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create mail directory: %w", err)
+		}
+		return &FileMailer{dir: config.MailDir, from: config.MailFrom}, nil
+	default:
+		return &LogMailer{from: config.MailFrom}, nil
+	}
+}
+
+// LogMailer writes emails to the application log, for local development
+type LogMailer struct {
+	from string
+}
+
+func (mailer *LogMailer) SendEmail(email Email) error {
+	log.Printf("email from %s to %s: %s\n%s", mailer.from, email.To, email.Subject, email.Body)
+	return nil
+}
+
+// FileMailer writes every email to its own file, for local development and
+// for inspecting what would have been sent
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func (mailer *FileMailer) SendEmail(email Email) error {
+	name := fmt.Sprintf("%d.eml", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(mailer.dir, name), formatEmail(mailer.from, email), 0o600)
+}
+
+// SMTPMailer delivers emails through an SMTP server
+type SMTPMailer struct {
+	address  string
+	username string
+	password string
+	from     string
+}
+
+func (mailer *SMTPMailer) SendEmail(email Email) error {
+	var auth smtp.Auth
+	if mailer.username != "" {
+		host, _, err := net.SplitHostPort(mailer.address)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", mailer.username, mailer.password, host)
+	}
+
+	return smtp.SendMail(mailer.address, auth, mailer.from, []string{email.To}, formatEmail(mailer.from, email))
+}
+
+// formatEmail renders an email as an RFC 5322 message
+func formatEmail(from string, email Email) []byte {
+	// Header values must not contain line breaks
+	clean := strings.NewReplacer("\r", "", "\n", "")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(email.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", clean.Replace(email.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(email.Body)
+	return []byte(b.String())
+}
@@ -0,0 +1,49 @@
+package util
+
+// Statuses a post moves through during its lifecycle
+const (
+	PostStatusDraft     = "draft"     // only visible to its author
+	PostStatusScheduled = "scheduled" // published automatically at publish_at
+	PostStatusPublished = "published" // listed and readable by everyone
+	PostStatusUnlisted  = "unlisted"  // readable by everyone with the link, but not listed
+	PostStatusArchived  = "archived"  // retired, only visible to its author
+)
+
+// postStatusTransitions holds the statuses a post may move to from each status.
+// Keeping a post in its current status is always allowed.
+var postStatusTransitions = map[string][]string{
+	PostStatusDraft:     {PostStatusScheduled, PostStatusPublished, PostStatusUnlisted, PostStatusArchived},
+	PostStatusScheduled: {PostStatusDraft, PostStatusPublished, PostStatusArchived},
+	PostStatusPublished: {PostStatusDraft, PostStatusUnlisted, PostStatusArchived},
+	PostStatusUnlisted:  {PostStatusDraft, PostStatusPublished, PostStatusArchived},
+	PostStatusArchived:  {PostStatusDraft},
+}
+
+// IsValidPostStatus reports whether status is one of the known post statuses
+func IsValidPostStatus(status string) bool {
+	_, ok := postStatusTransitions[status]
+	return ok
+}
+
+// CanTransitionPostStatus reports whether a post in status from may be moved
+// to status to
+func CanTransitionPostStatus(from, to string) bool {
+	if !IsValidPostStatus(to) {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	for _, status := range postStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReadablePostStatus reports whether anyone may read a post in status,
+// not only its author and editors
+func IsReadablePostStatus(status string) bool {
+	return status == PostStatusPublished || status == PostStatusUnlisted
+}
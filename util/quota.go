@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StorageQuota limits the images a user may keep. Zero means no limit.
+type StorageQuota struct {
+	MaxBytes  int64 `json:"max_bytes"`
+	MaxImages int32 `json:"max_images"`
+}
+
+// defaultStorageQuotas apply to the roles STORAGE_QUOTAS does not mention.
+// Admins are not limited and readers cannot upload.
+var defaultStorageQuotas = map[string]StorageQuota{
+	RoleAuthor: {MaxBytes: 100 << 20, MaxImages: 500},
+	RoleEditor: {MaxBytes: 1 << 30, MaxImages: 5000},
+}
+
+// ParseStorageQuotas parses comma separated role=bytes:images entries, for
+// example "author=52428800:200,editor=0:0" where 0 lifts the limit
+func ParseStorageQuotas(s string) (map[string]StorageQuota, error) {
+	quotas := make(map[string]StorageQuota)
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		role, limits, ok := strings.Cut(entry, "=")
+		if !ok || !IsValidRole(role) {
+			return nil, fmt.Errorf("invalid storage quota %q", entry)
+		}
+
+		bytesStr, imagesStr, ok := strings.Cut(limits, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid storage quota %q", entry)
+		}
+		maxBytes, err := strconv.ParseInt(bytesStr, 10, 64)
+		if err != nil || maxBytes < 0 {
+			return nil, fmt.Errorf("invalid storage quota %q", entry)
+		}
+		maxImages, err := strconv.ParseInt(imagesStr, 10, 32)
+		if err != nil || maxImages < 0 {
+			return nil, fmt.Errorf("invalid storage quota %q", entry)
+		}
+
+		quotas[role] = StorageQuota{MaxBytes: maxBytes, MaxImages: int32(maxImages)}
+	}
+
+	return quotas, nil
+}
+
+// StorageQuotaFor returns the storage quota of users with role
+func (config Config) StorageQuotaFor(role string) StorageQuota {
+	return config.StorageQuotas[role]
+}
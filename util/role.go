@@ -0,0 +1,29 @@
+package util
+
+// Roles a user can hold, from most to least privileged
+const (
+	RoleAdmin  = "admin"  // manages users and can moderate any content
+	RoleEditor = "editor" // can edit any post
+	RoleAuthor = "author" // can publish and manage their own posts
+	RoleReader = "reader" // can only comment and like
+)
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleEditor, RoleAuthor, RoleReader:
+		return true
+	}
+	return false
+}
+
+// CanEditAnyPost reports whether role may edit posts written by other users
+func CanEditAnyPost(role string) bool {
+	return role == RoleAdmin || role == RoleEditor
+}
+
+// CanDeleteAnyContent reports whether role may delete posts, tags and images
+// owned by other users
+func CanDeleteAnyContent(role string) bool {
+	return role == RoleAdmin
+}
@@ -0,0 +1,84 @@
+package util
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxSlugLength is the longest slug Slugify returns, leaving room in the
+// column for the suffix that tells apart posts with the same title
+const MaxSlugLength = 100
+
+// slugTransliterations spells out letters that do not decompose into an ASCII
+// letter and a mark
+var slugTransliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'đ': "d", 'ð': "d", 'þ': "th", 'ł': "l", 'ı': "i",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th", 'ι': "i",
+	'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s",
+	'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// Slugify turns a title into a lowercase slug of ASCII letters, digits and
+// dashes for use in URLs. Accented letters lose their marks and Cyrillic and
+// Greek letters are transliterated, so "Crème Brûlée" becomes "creme-brulee".
+// Titles with nothing left to keep give "post".
+func Slugify(title string) string {
+	var sb strings.Builder
+	dash := false
+	write := func(s string) {
+		if dash && sb.Len() > 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteString(s)
+		dash = false
+	}
+
+	for _, r := range strings.ToLower(title) {
+		if r == '\'' || r == '’' {
+			// Apostrophes are dropped without splitting the word
+			continue
+		}
+
+		// Letters with a spelling of their own, like й, are looked up before
+		// decomposing, the rest lose the marks decomposing splits off
+		letters := string(r)
+		if _, ok := slugTransliterations[r]; !ok {
+			letters = norm.NFKD.String(letters)
+		}
+		for _, l := range letters {
+			if s, ok := slugTransliterations[l]; ok {
+				if s != "" {
+					write(s)
+				}
+				continue
+			}
+			switch {
+			case l < unicode.MaxASCII && (unicode.IsLetter(l) || unicode.IsDigit(l)):
+				write(string(l))
+			case unicode.Is(unicode.Mn, l):
+			default:
+				dash = true
+			}
+		}
+	}
+
+	slug := sb.String()
+	if len(slug) > MaxSlugLength {
+		slug = slug[:MaxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	if slug == "" {
+		return "post"
+	}
+	return slug
+}
@@ -1,6 +1,10 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"time"
 
 	"github.com/o1egl/paseto"
@@ -8,7 +12,7 @@ import (
 )
 
 type TokenMaker interface {
-	CreateToken(username string, duration time.Duration) (string, error)
+	CreateToken(username string, role string, duration time.Duration) (string, error)
 	VerifyToken(token string) (*Payload, error)
 }
 
@@ -19,6 +23,7 @@ type PasetoMaker struct {
 
 type Payload struct {
 	Username  string    `json:"username"`
+	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
 }
@@ -36,9 +41,10 @@ func NewPasetoMaker(symmetricKey string) (TokenMaker, error) {
 	return maker, nil
 }
 
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, error) {
 	payload := &Payload{
 		Username:  username,
+		Role:      role,
 		IssuedAt:  time.Now(),
 		ExpiredAt: time.Now().Add(duration),
 	}
@@ -60,3 +66,22 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 
 	return payload, nil
 }
+
+// NewOpaqueToken returns a random opaque token, used for refresh and password
+// reset tokens, and the hash to store for it. Only the hash is persisted, so a
+// leaked table cannot be used to renew sessions or reset passwords.
+func NewOpaqueToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashOpaqueToken(token), nil
+}
+
+// HashOpaqueToken returns the hex encoded SHA-256 of an opaque token
+func HashOpaqueToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}